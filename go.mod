@@ -1,10 +1,22 @@
 module github.com/ELares/crypto
 
-go 1.16
+go 1.23
 
 require (
-	github.com/google/go-cmp v0.5.5 // indirect
+	filippo.io/bigmod v0.1.0
+	filippo.io/edwards25519 v1.1.0
+	github.com/cloudflare/circl v1.6.1
 	github.com/stretchr/testify v1.7.0
-	golang.org/x/crypto v0.0.0-20210513164829-c07d793c2f9a // indirect
+	golang.org/x/crypto v0.12.0
+	golang.org/x/sys v0.11.0
+	golang.org/x/text v0.22.0
 	gopkg.in/square/go-jose.v2 v2.5.1
+	software.sslmate.com/src/go-pkcs12 v0.7.3
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/google/go-cmp v0.5.5 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
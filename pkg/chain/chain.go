@@ -0,0 +1,361 @@
+package chain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	c "github.com/ELares/crypto/pkg"
+	p "github.com/ELares/crypto/pkg/pem"
+)
+
+const (
+	// FailureNoIssuer no issuer certificate was found among the intermediates and roots
+	FailureNoIssuer Failure = "no-issuer"
+
+	// FailureUntrustedRoot the path ends in a self-signed certificate which is not a trusted root
+	FailureUntrustedRoot Failure = "untrusted-root"
+
+	// FailureExpired the certificate is expired at the verification time
+	FailureExpired Failure = "expired"
+
+	// FailureNotYetValid the certificate is not yet valid at the verification time
+	FailureNotYetValid Failure = "not-yet-valid"
+
+	// FailureNotCA the issuer certificate is not a CA
+	FailureNotCA Failure = "not-ca"
+
+	// FailureKeyUsage the issuer certificate is not allowed to sign certificates
+	FailureKeyUsage Failure = "key-usage"
+
+	// FailureExtKeyUsage a certificate of the path does not allow the requested extended key usage
+	FailureExtKeyUsage Failure = "ext-key-usage"
+
+	// FailurePathLength the issuer path length constraint is exceeded
+	FailurePathLength Failure = "path-length"
+
+	// FailureSignature the certificate signature does not verify with the issuer public key
+	FailureSignature Failure = "signature"
+
+	// FailureNameConstraints a name of the certificate violates the name constraints of a CA above it
+	FailureNameConstraints Failure = "name-constraints"
+
+	// FailureCriticalExtension the certificate has a critical extension which is not handled
+	FailureCriticalExtension Failure = "critical-extension"
+
+	// maxDepth upper bound of the certificates in a path
+	maxDepth = 16
+)
+
+type (
+	// Failure reason of a failed link in a certificate path
+	Failure string
+
+	// Diagnostic describes why a link of a candidate path failed
+	Diagnostic struct {
+		// Depth position of the certificate in the path, the leaf is 0
+		Depth   int
+		Subject string
+		Issuer  string
+		Failure Failure
+		Err     error
+	}
+
+	// Link a certificate of a verified path with its public key as rsa, ecdsa or ed25519 type,
+	// only the field matching the certificate key algorithm is set
+	Link struct {
+		Certificate      *x509.Certificate
+		RSAPublicKey     *rsa.PublicKey
+		ECDSAPublicKey   *ecdsa.PublicKey
+		Ed25519PublicKey ed25519.PublicKey
+	}
+
+	// Result outcome of a verification, every valid path from the leaf to a trusted root and the
+	// diagnostics of the discarded candidates
+	Result struct {
+		Chains      [][]Link
+		Diagnostics []Diagnostic
+	}
+
+	// IChain interface for methods to build and verify certificate chains
+	IChain interface {
+		FromPEMCertificates(p.CertificatePEM) ([]*x509.Certificate, error)
+		Verify(bundle p.CertificatePEM, roots, intermediates []*x509.Certificate, at time.Time, usages ...x509.ExtKeyUsage) (*Result, error)
+	}
+
+	// Chain struct to implement the IChain methods
+	Chain struct{}
+
+	// builder state of a single Verify call
+	builder struct {
+		roots       []*x509.Certificate
+		candidates  []*x509.Certificate
+		at          time.Time
+		usages      []x509.ExtKeyUsage
+		result      *Result
+		diagnostics map[string]bool
+	}
+)
+
+// NewChain gets a new Chain pointer
+func NewChain() IChain {
+	return &Chain{}
+}
+
+// Error formats the diagnostic
+func (d Diagnostic) Error() string {
+	if d.Err != nil {
+		return fmt.Sprintf("depth %d %q issued by %q: %s: %v", d.Depth, d.Subject, d.Issuer, d.Failure, d.Err)
+	}
+
+	return fmt.Sprintf("depth %d %q issued by %q: %s", d.Depth, d.Subject, d.Issuer, d.Failure)
+}
+
+// Valid reports whether at least one path to a trusted root was found
+func (r *Result) Valid() bool {
+	return len(r.Chains) > 0
+}
+
+// FromPEMCertificates takes a pem bundle and converts every certificate block into a x509 certificate
+func (ch *Chain) FromPEMCertificates(bundle p.CertificatePEM) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != c.CERTIFICATE {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, c.ErrDecodePEMCertificate
+	}
+
+	return certs, nil
+}
+
+// Verify builds every path from the first certificate of the bundle (the leaf) to one of the roots,
+// using the rest of the bundle and the intermediates, and checks validity at the given time, the
+// name constraints, unhandled critical extensions and the requested extended key usages (none
+// requested accepts any usage)
+func (ch *Chain) Verify(bundle p.CertificatePEM, roots, intermediates []*x509.Certificate, at time.Time, usages ...x509.ExtKeyUsage) (*Result, error) {
+	certs, err := ch.FromPEMCertificates(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &builder{
+		roots:       roots,
+		candidates:  append(append(append([]*x509.Certificate{}, certs[1:]...), intermediates...), roots...),
+		at:          at,
+		usages:      usages,
+		result:      &Result{},
+		diagnostics: map[string]bool{},
+	}
+
+	if b.checkValidity(certs[0], 0) && b.checkExtensions(certs[0], 0) {
+		b.build([]*x509.Certificate{certs[0]})
+	}
+
+	return b.result, nil
+}
+
+// build extends the path with every issuer candidate of its last certificate
+func (b *builder) build(path []*x509.Certificate) {
+	current := path[len(path)-1]
+	depth := len(path) - 1
+
+	if b.isRoot(current) {
+		b.complete(path)
+		return
+	}
+
+	if depth >= maxDepth {
+		b.diagnose(current, depth, FailurePathLength, nil)
+		return
+	}
+
+	found := false
+	for _, candidate := range b.candidates {
+		if !bytes.Equal(candidate.RawSubject, current.RawIssuer) || contains(path, candidate) {
+			continue
+		}
+
+		if len(current.AuthorityKeyId) > 0 && len(candidate.SubjectKeyId) > 0 &&
+			!bytes.Equal(current.AuthorityKeyId, candidate.SubjectKeyId) {
+			continue
+		}
+
+		found = true
+
+		if !b.checkIssuer(current, candidate, depth) || !b.checkValidity(candidate, depth+1) || !b.checkExtensions(candidate, depth+1) {
+			continue
+		}
+
+		b.build(append(append([]*x509.Certificate{}, path...), candidate))
+	}
+
+	if found {
+		return
+	}
+
+	if bytes.Equal(current.RawIssuer, current.RawSubject) && current.CheckSignatureFrom(current) == nil {
+		b.diagnose(current, depth, FailureUntrustedRoot, nil)
+		return
+	}
+
+	b.diagnose(current, depth, FailureNoIssuer, nil)
+}
+
+// complete checks the path wide constraints of a path ending in a trusted root
+func (b *builder) complete(path []*x509.Certificate) {
+	if !b.checkNameConstraints(path) {
+		return
+	}
+
+	for i, cert := range path {
+		if !b.allowsUsages(cert) {
+			b.diagnose(cert, i, FailureExtKeyUsage, fmt.Errorf("requested %v, allowed %v", b.usages, cert.ExtKeyUsage))
+			return
+		}
+	}
+
+	links := make([]Link, 0, len(path))
+	for _, cert := range path {
+		links = append(links, newLink(cert))
+	}
+
+	b.result.Chains = append(b.result.Chains, links)
+}
+
+// checkIssuer checks that the candidate is allowed to issue the certificate and its signature
+func (b *builder) checkIssuer(cert, issuer *x509.Certificate, depth int) bool {
+	if !issuer.BasicConstraintsValid || !issuer.IsCA {
+		b.diagnose(cert, depth, FailureNotCA, fmt.Errorf("issuer %q is not a CA", issuer.Subject.String()))
+		return false
+	}
+
+	if issuer.KeyUsage != 0 && issuer.KeyUsage&x509.KeyUsageCertSign == 0 {
+		b.diagnose(cert, depth, FailureKeyUsage, fmt.Errorf("issuer %q lacks keyCertSign", issuer.Subject.String()))
+		return false
+	}
+
+	// depth intermediates are below the issuer once the leaf is excluded
+	if (issuer.MaxPathLen > 0 || issuer.MaxPathLenZero) && depth > issuer.MaxPathLen {
+		b.diagnose(cert, depth, FailurePathLength, fmt.Errorf("issuer %q allows %d intermediates, found %d", issuer.Subject.String(), issuer.MaxPathLen, depth))
+		return false
+	}
+
+	if err := issuer.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+		b.diagnose(cert, depth, FailureSignature, err)
+		return false
+	}
+
+	return true
+}
+
+// checkValidity checks the certificate validity period at the verification time
+func (b *builder) checkValidity(cert *x509.Certificate, depth int) bool {
+	if b.at.Before(cert.NotBefore) {
+		b.diagnose(cert, depth, FailureNotYetValid, fmt.Errorf("valid from %s", cert.NotBefore.Format(time.RFC3339)))
+		return false
+	}
+
+	if b.at.After(cert.NotAfter) {
+		b.diagnose(cert, depth, FailureExpired, fmt.Errorf("expired at %s", cert.NotAfter.Format(time.RFC3339)))
+		return false
+	}
+
+	return true
+}
+
+// allowsUsages checks the requested extended key usages against the certificate, a certificate
+// without extended key usages or with the any usage allows every usage
+func (b *builder) allowsUsages(cert *x509.Certificate) bool {
+	if len(b.usages) == 0 || len(cert.ExtKeyUsage) == 0 {
+		return true
+	}
+
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+
+	for _, requested := range b.usages {
+		for _, usage := range cert.ExtKeyUsage {
+			if usage == requested {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isRoot reports whether the certificate is one of the trusted roots
+func (b *builder) isRoot(cert *x509.Certificate) bool {
+	return contains(b.roots, cert)
+}
+
+// diagnose records a failure once per certificate, depth and reason
+func (b *builder) diagnose(cert *x509.Certificate, depth int, failure Failure, err error) {
+	key := fmt.Sprintf("%d|%x|%s", depth, cert.Raw, failure)
+	if b.diagnostics[key] {
+		return
+	}
+
+	b.diagnostics[key] = true
+	b.result.Diagnostics = append(b.result.Diagnostics, Diagnostic{
+		Depth:   depth,
+		Subject: cert.Subject.String(),
+		Issuer:  cert.Issuer.String(),
+		Failure: failure,
+		Err:     err,
+	})
+}
+
+// newLink surfaces the certificate public key as rsa, ecdsa or ed25519 type
+func newLink(cert *x509.Certificate) Link {
+	link := Link{Certificate: cert}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		link.RSAPublicKey = key
+	case *ecdsa.PublicKey:
+		link.ECDSAPublicKey = key
+	case ed25519.PublicKey:
+		link.Ed25519PublicKey = key
+	}
+
+	return link
+}
+
+// contains reports whether the certificate is in the list
+func contains(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, candidate := range certs {
+		if candidate.Equal(cert) {
+			return true
+		}
+	}
+
+	return false
+}
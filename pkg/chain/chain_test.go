@@ -0,0 +1,445 @@
+package chain
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ELares/crypto/pkg/ecdsa"
+	"github.com/ELares/crypto/pkg/ed25519"
+	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/rsa"
+	"github.com/stretchr/testify/assert"
+)
+
+var serial int64
+
+func newCert(t *testing.T, template, parent *x509.Certificate, publicKey crypto.PublicKey, signer crypto.Signer) *x509.Certificate {
+	serial++
+	template.SerialNumber = big.NewInt(serial)
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+	}
+
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Now().Add(24 * time.Hour)
+	}
+
+	if parent == nil {
+		parent = template
+	}
+
+	der, err := x509.CreateCertificate(nil, template, parent, publicKey, signer)
+	assert.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	return cert
+}
+
+func caTemplate(name string) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+}
+
+func toBundle(certs ...*x509.Certificate) p.CertificatePEM {
+	var bundle []byte
+	for _, cert := range certs {
+		bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+
+	return bundle
+}
+
+func TestVerify(t *testing.T) {
+	ch := NewChain()
+
+	rootKey, rootPub, _ := ecdsa.NewECDSA().P384()
+	interKey, interPub, _ := rsa.NewRSA().R2048()
+	_, leafPub, _ := ed25519.NewED25519().Ed25519()
+	otherKey, otherPub, _ := ecdsa.NewECDSA().P256()
+
+	root := newCert(t, caTemplate("Root"), nil, rootPub, rootKey)
+	otherRoot := newCert(t, caTemplate("Other Root"), nil, otherPub, otherKey)
+	inter := newCert(t, caTemplate("Intermediate"), root, interPub, rootKey)
+
+	leaf := newCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "leaf"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, inter, leafPub, interKey)
+
+	expiredInterTemplate := caTemplate("Intermediate")
+	expiredInterTemplate.NotBefore = time.Now().Add(-48 * time.Hour)
+	expiredInterTemplate.NotAfter = time.Now().Add(-24 * time.Hour)
+	expiredInter := newCert(t, expiredInterTemplate, root, interPub, rootKey)
+
+	notCATemplate := caTemplate("Intermediate")
+	notCATemplate.IsCA = false
+	notCATemplate.KeyUsage = x509.KeyUsageDigitalSignature
+	notCA := newCert(t, notCATemplate, root, interPub, rootKey)
+
+	impostorRootTemplate := caTemplate("Root")
+	impostorRootTemplate.SubjectKeyId = root.SubjectKeyId
+	impostorRoot := newCert(t, impostorRootTemplate, nil, otherPub, otherKey)
+
+	pathLenRootTemplate := caTemplate("Root")
+	pathLenRootTemplate.MaxPathLenZero = true
+	pathLenRoot := newCert(t, pathLenRootTemplate, nil, rootPub, rootKey)
+
+	testcases := []struct {
+		name          string
+		bundle        p.CertificatePEM
+		roots         []*x509.Certificate
+		intermediates []*x509.Certificate
+		at            time.Time
+		usages        []x509.ExtKeyUsage
+
+		isValid bool
+		failure Failure
+		depth   int
+	}{
+		{
+			name:   "Valid leaf + intermediate bundle",
+			bundle: toBundle(leaf, inter),
+			roots:  []*x509.Certificate{root},
+			at:     time.Now(),
+			usages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+
+			isValid: true,
+		},
+		{
+			name:          "Valid intermediate from the options",
+			bundle:        toBundle(leaf),
+			roots:         []*x509.Certificate{root},
+			intermediates: []*x509.Certificate{inter},
+			at:            time.Now(),
+
+			isValid: true,
+		},
+		{
+			name:   "Invalid missing intermediate",
+			bundle: toBundle(leaf),
+			roots:  []*x509.Certificate{root},
+			at:     time.Now(),
+
+			failure: FailureNoIssuer,
+			depth:   0,
+		},
+		{
+			name:   "Invalid untrusted root",
+			bundle: toBundle(leaf, inter, root),
+			roots:  []*x509.Certificate{otherRoot},
+			at:     time.Now(),
+
+			failure: FailureUntrustedRoot,
+			depth:   2,
+		},
+		{
+			name:   "Invalid leaf expired",
+			bundle: toBundle(leaf, inter),
+			roots:  []*x509.Certificate{root},
+			at:     time.Now().Add(48 * time.Hour),
+
+			failure: FailureExpired,
+			depth:   0,
+		},
+		{
+			name:   "Invalid leaf not yet valid",
+			bundle: toBundle(leaf, inter),
+			roots:  []*x509.Certificate{root},
+			at:     time.Now().Add(-48 * time.Hour),
+
+			failure: FailureNotYetValid,
+			depth:   0,
+		},
+		{
+			name:   "Invalid intermediate expired",
+			bundle: toBundle(leaf, expiredInter),
+			roots:  []*x509.Certificate{root},
+			at:     time.Now(),
+
+			failure: FailureExpired,
+			depth:   1,
+		},
+		{
+			name:   "Invalid intermediate not a CA",
+			bundle: toBundle(leaf, notCA),
+			roots:  []*x509.Certificate{root},
+			at:     time.Now(),
+
+			failure: FailureNotCA,
+			depth:   0,
+		},
+		{
+			name:   "Invalid ext key usage",
+			bundle: toBundle(leaf, inter),
+			roots:  []*x509.Certificate{root},
+			at:     time.Now(),
+			usages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+
+			failure: FailureExtKeyUsage,
+			depth:   0,
+		},
+		{
+			name:   "Invalid path length",
+			bundle: toBundle(leaf, inter),
+			roots:  []*x509.Certificate{pathLenRoot},
+			at:     time.Now(),
+
+			failure: FailurePathLength,
+			depth:   1,
+		},
+		{
+			name:   "Invalid signature",
+			bundle: toBundle(leaf, inter),
+			roots:  []*x509.Certificate{impostorRoot},
+			at:     time.Now(),
+
+			failure: FailureSignature,
+			depth:   1,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ch.Verify(tc.bundle, tc.roots, tc.intermediates, tc.at, tc.usages...)
+			assert.Nil(t, err)
+
+			if tc.isValid {
+				assert.True(t, result.Valid())
+				assert.Len(t, result.Chains, 1)
+				assert.Len(t, result.Chains[0], 3)
+				assert.Equal(t, leafPub, result.Chains[0][0].Ed25519PublicKey)
+				assert.Equal(t, interPub, result.Chains[0][1].RSAPublicKey)
+				assert.Equal(t, rootPub, result.Chains[0][2].ECDSAPublicKey)
+			} else {
+				assert.False(t, result.Valid())
+				assert.NotEmpty(t, result.Diagnostics)
+
+				found := false
+				for _, diagnostic := range result.Diagnostics {
+					if diagnostic.Failure == tc.failure && diagnostic.Depth == tc.depth {
+						found = true
+						assert.NotEmpty(t, diagnostic.Error())
+					}
+				}
+
+				assert.True(t, found, "%v", result.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestFromPEMCertificates(t *testing.T) {
+	ch := NewChain()
+
+	key, pub, _ := ecdsa.NewECDSA().P256()
+	root := newCert(t, caTemplate("Root"), nil, pub, key)
+
+	certs, err := ch.FromPEMCertificates(toBundle(root, root))
+	assert.Nil(t, err)
+	assert.Len(t, certs, 2)
+
+	_, err = ch.FromPEMCertificates([]byte("-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n"))
+	assert.NotNil(t, err)
+
+	_, err = ch.FromPEMCertificates(nil)
+	assert.NotNil(t, err)
+
+	_, err = ch.Verify(nil, []*x509.Certificate{root}, nil, time.Now())
+	assert.NotNil(t, err)
+}
+
+func TestNameConstraints(t *testing.T) {
+	ch := NewChain()
+
+	rootKey, rootPub, _ := ecdsa.NewECDSA().P256()
+	interKey, interPub, _ := ecdsa.NewECDSA().P256()
+	_, leafPub, _ := ed25519.NewED25519().Ed25519()
+
+	root := newCert(t, caTemplate("Root"), nil, rootPub, rootKey)
+
+	_, network, _ := net.ParseCIDR("10.0.0.0/8")
+	uri, _ := url.Parse("https://api.example.com/v1")
+	ipURI, _ := url.Parse("https://10.0.0.1/v1")
+
+	testcases := []struct {
+		name       string
+		constraint func(*x509.Certificate)
+		leaf       *x509.Certificate
+
+		isValid bool
+		failure Failure
+	}{
+		{
+			name:       "Valid permitted DNS subdomain",
+			constraint: func(ca *x509.Certificate) { ca.PermittedDNSDomains = []string{"example.com"} },
+			leaf:       &x509.Certificate{DNSNames: []string{"www.example.com", "example.com"}},
+
+			isValid: true,
+		},
+		{
+			name:       "Invalid DNS outside permitted",
+			constraint: func(ca *x509.Certificate) { ca.PermittedDNSDomains = []string{"example.com"} },
+			leaf:       &x509.Certificate{DNSNames: []string{"www.example.com", "evil.com"}},
+
+			failure: FailureNameConstraints,
+		},
+		{
+			name:       "Invalid DNS suffix without label boundary",
+			constraint: func(ca *x509.Certificate) { ca.PermittedDNSDomains = []string{"example.com"} },
+			leaf:       &x509.Certificate{DNSNames: []string{"badexample.com"}},
+
+			failure: FailureNameConstraints,
+		},
+		{
+			name:       "Invalid DNS excluded",
+			constraint: func(ca *x509.Certificate) { ca.ExcludedDNSDomains = []string{"internal.example.com"} },
+			leaf:       &x509.Certificate{DNSNames: []string{"db.internal.example.com"}},
+
+			failure: FailureNameConstraints,
+		},
+		{
+			name:       "Valid IP permitted",
+			constraint: func(ca *x509.Certificate) { ca.PermittedIPRanges = []*net.IPNet{network} },
+			leaf:       &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("10.1.2.3")}},
+
+			isValid: true,
+		},
+		{
+			name:       "Invalid IP outside permitted",
+			constraint: func(ca *x509.Certificate) { ca.PermittedIPRanges = []*net.IPNet{network} },
+			leaf:       &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("192.168.1.1")}},
+
+			failure: FailureNameConstraints,
+		},
+		{
+			name:       "Invalid IP excluded",
+			constraint: func(ca *x509.Certificate) { ca.ExcludedIPRanges = []*net.IPNet{network} },
+			leaf:       &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("10.1.2.3")}},
+
+			failure: FailureNameConstraints,
+		},
+		{
+			name:       "Valid email host",
+			constraint: func(ca *x509.Certificate) { ca.PermittedEmailAddresses = []string{"example.com"} },
+			leaf:       &x509.Certificate{EmailAddresses: []string{"alice@example.com"}},
+
+			isValid: true,
+		},
+		{
+			name:       "Valid email subdomain host",
+			constraint: func(ca *x509.Certificate) { ca.PermittedEmailAddresses = []string{"example.com"} },
+			leaf:       &x509.Certificate{EmailAddresses: []string{"alice@mail.example.com"}},
+
+			isValid: true,
+		},
+		{
+			name:       "Invalid email outside permitted",
+			constraint: func(ca *x509.Certificate) { ca.PermittedEmailAddresses = []string{"example.com"} },
+			leaf:       &x509.Certificate{EmailAddresses: []string{"alice@example.org"}},
+
+			failure: FailureNameConstraints,
+		},
+		{
+			name:       "Invalid email mailbox excluded",
+			constraint: func(ca *x509.Certificate) { ca.ExcludedEmailAddresses = []string{"mallory@example.com"} },
+			leaf:       &x509.Certificate{EmailAddresses: []string{"mallory@example.com"}},
+
+			failure: FailureNameConstraints,
+		},
+		{
+			name:       "Valid URI subdomain",
+			constraint: func(ca *x509.Certificate) { ca.PermittedURIDomains = []string{".example.com"} },
+			leaf:       &x509.Certificate{URIs: []*url.URL{uri}},
+
+			isValid: true,
+		},
+		{
+			name:       "Invalid URI outside permitted",
+			constraint: func(ca *x509.Certificate) { ca.PermittedURIDomains = []string{"example.org"} },
+			leaf:       &x509.Certificate{URIs: []*url.URL{uri}},
+
+			failure: FailureNameConstraints,
+		},
+		{
+			name:       "Invalid URI IP host",
+			constraint: func(ca *x509.Certificate) { ca.ExcludedURIDomains = []string{"example.org"} },
+			leaf:       &x509.Certificate{URIs: []*url.URL{ipURI}},
+
+			failure: FailureNameConstraints,
+		},
+		{
+			name:       "Valid unconstrained name type",
+			constraint: func(ca *x509.Certificate) { ca.PermittedDNSDomains = []string{"example.com"} },
+			leaf:       &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("192.168.1.1")}},
+
+			isValid: true,
+		},
+		{
+			name:       "Invalid unhandled critical extension",
+			constraint: func(ca *x509.Certificate) {},
+			leaf: &x509.Certificate{
+				DNSNames:        []string{"www.example.com"},
+				ExtraExtensions: []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}, Critical: true, Value: []byte{5, 0}}},
+			},
+
+			failure: FailureCriticalExtension,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			interTemplate := caTemplate("Constrained Intermediate")
+			tc.constraint(interTemplate)
+			inter := newCert(t, interTemplate, root, interPub, rootKey)
+
+			tc.leaf.Subject = pkix.Name{CommonName: "leaf"}
+			leaf := newCert(t, tc.leaf, inter, leafPub, interKey)
+
+			result, err := ch.Verify(toBundle(leaf, inter), []*x509.Certificate{root}, nil, time.Now())
+			assert.Nil(t, err)
+			assert.Equal(t, tc.isValid, result.Valid(), "%v", result.Diagnostics)
+
+			// the x509 verifier is the ground truth
+			roots := x509.NewCertPool()
+			roots.AddCert(root)
+			intermediates := x509.NewCertPool()
+			intermediates.AddCert(inter)
+
+			_, err = leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+			assert.Equal(t, tc.isValid, err == nil, "%v", err)
+
+			if !tc.isValid {
+				assert.Len(t, result.Diagnostics, 1)
+				assert.Equal(t, tc.failure, result.Diagnostics[0].Failure)
+				assert.Equal(t, 0, result.Diagnostics[0].Depth)
+				assert.NotEmpty(t, result.Diagnostics[0].Error())
+			}
+		})
+	}
+
+	// an intermediate with an unhandled critical extension is discarded as well
+	interTemplate := caTemplate("Critical Intermediate")
+	interTemplate.ExtraExtensions = []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 2}, Critical: true, Value: []byte{5, 0}}}
+	inter := newCert(t, interTemplate, root, interPub, rootKey)
+	leaf := newCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "leaf"}}, inter, leafPub, interKey)
+
+	result, err := ch.Verify(toBundle(leaf, inter), []*x509.Certificate{root}, nil, time.Now())
+	assert.Nil(t, err)
+	assert.False(t, result.Valid())
+	assert.Equal(t, FailureCriticalExtension, result.Diagnostics[0].Failure)
+	assert.Equal(t, 1, result.Diagnostics[0].Depth)
+}
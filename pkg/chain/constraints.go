@@ -0,0 +1,156 @@
+package chain
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// checkExtensions checks the certificate has no critical extension the x509 package does not handle
+func (b *builder) checkExtensions(cert *x509.Certificate, depth int) bool {
+	if len(cert.UnhandledCriticalExtensions) > 0 {
+		b.diagnose(cert, depth, FailureCriticalExtension, fmt.Errorf("unhandled critical extensions %v", cert.UnhandledCriticalExtensions))
+		return false
+	}
+
+	return true
+}
+
+// checkNameConstraints checks the subject alternative names of every certificate below a CA of the
+// path against the name constraints of that CA
+func (b *builder) checkNameConstraints(path []*x509.Certificate) bool {
+	for i, ca := range path {
+		if !hasNameConstraints(ca) {
+			continue
+		}
+
+		for depth, cert := range path[:i] {
+			if err := checkNames(ca, cert); err != nil {
+				b.diagnose(cert, depth, FailureNameConstraints, err)
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// hasNameConstraints reports whether the CA constrains any name type
+func hasNameConstraints(ca *x509.Certificate) bool {
+	return len(ca.PermittedDNSDomains) > 0 || len(ca.ExcludedDNSDomains) > 0 ||
+		len(ca.PermittedIPRanges) > 0 || len(ca.ExcludedIPRanges) > 0 ||
+		len(ca.PermittedEmailAddresses) > 0 || len(ca.ExcludedEmailAddresses) > 0 ||
+		len(ca.PermittedURIDomains) > 0 || len(ca.ExcludedURIDomains) > 0
+}
+
+// checkNames checks the DNS, IP, email and URI names of the certificate against the CA constraints
+func checkNames(ca, cert *x509.Certificate) error {
+	for _, name := range cert.DNSNames {
+		match := func(constraint string) bool { return matchDomain(name, constraint) }
+		if err := checkName("DNS name", name, ca.PermittedDNSDomains, ca.ExcludedDNSDomains, match); err != nil {
+			return err
+		}
+	}
+
+	for _, ip := range cert.IPAddresses {
+		match := func(constraint *net.IPNet) bool { return constraint.Contains(ip) }
+		if err := checkName("IP address", ip.String(), ca.PermittedIPRanges, ca.ExcludedIPRanges, match); err != nil {
+			return err
+		}
+	}
+
+	for _, email := range cert.EmailAddresses {
+		match := func(constraint string) bool { return matchEmail(email, constraint) }
+		if err := checkName("email address", email, ca.PermittedEmailAddresses, ca.ExcludedEmailAddresses, match); err != nil {
+			return err
+		}
+	}
+
+	for _, uri := range cert.URIs {
+		if len(ca.PermittedURIDomains) == 0 && len(ca.ExcludedURIDomains) == 0 {
+			break
+		}
+
+		// a URI without a domain host cannot be checked against domain constraints
+		host := uri.Hostname()
+		if host == "" || net.ParseIP(host) != nil {
+			return fmt.Errorf("URI %q has no domain to check against the constraints of %q", uri.String(), ca.Subject.String())
+		}
+
+		match := func(constraint string) bool { return matchURI(uri, constraint) }
+		if err := checkName("URI", uri.String(), ca.PermittedURIDomains, ca.ExcludedURIDomains, match); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkName fails when the name matches an excluded constraint, or when there are permitted
+// constraints and it matches none of them
+func checkName[T any](kind, name string, permitted, excluded []T, match func(T) bool) error {
+	for _, constraint := range excluded {
+		if match(constraint) {
+			return fmt.Errorf("%s %q is excluded by %v", kind, name, constraint)
+		}
+	}
+
+	if len(permitted) == 0 {
+		return nil
+	}
+
+	for _, constraint := range permitted {
+		if match(constraint) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s %q is not within the permitted %v", kind, name, permitted)
+}
+
+// matchDomain reports whether the domain is within the constraint, "example.com" matching the
+// domain and its subdomains and ".example.com" only its subdomains
+func matchDomain(domain, constraint string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	constraint = strings.ToLower(constraint)
+
+	if constraint == "" {
+		return true
+	}
+
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(domain, constraint)
+	}
+
+	return domain == constraint || strings.HasSuffix(domain, "."+constraint)
+}
+
+// matchEmail reports whether the mailbox is within the constraint, either a mailbox or a domain
+// matched as a DNS constraint against the mailbox host, as the x509 verifier does
+func matchEmail(email, constraint string) bool {
+	if strings.Contains(constraint, "@") {
+		return strings.EqualFold(email, constraint)
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	return matchDomain(email[at+1:], constraint)
+}
+
+// matchURI reports whether the URI host is within the constraint, a host or ".example.com" for
+// any subdomain host
+func matchURI(uri *url.URL, constraint string) bool {
+	host := strings.ToLower(uri.Hostname())
+	constraint = strings.ToLower(constraint)
+
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(host, constraint)
+	}
+
+	return host == constraint
+}
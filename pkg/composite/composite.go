@@ -0,0 +1,650 @@
+package composite
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+
+	c "github.com/ELares/crypto/pkg"
+	ec "github.com/ELares/crypto/pkg/ecdsa"
+	ed "github.com/ELares/crypto/pkg/ed25519"
+	"github.com/ELares/crypto/pkg/mldsa"
+	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/policy"
+)
+
+const (
+	// MLDSA44Ed25519SHA512 ML-DSA-44 combined with Ed25519, messages pre-hashed with SHA-512
+	MLDSA44Ed25519SHA512 Algorithm = iota
+
+	// MLDSA44ECDSAP256SHA256 ML-DSA-44 combined with ECDSA P-256, messages pre-hashed with SHA-256
+	MLDSA44ECDSAP256SHA256
+
+	// MLDSA65ECDSAP256SHA512 ML-DSA-65 combined with ECDSA P-256, messages pre-hashed with SHA-512
+	MLDSA65ECDSAP256SHA512
+
+	// MLDSA65ECDSAP384SHA512 ML-DSA-65 combined with ECDSA P-384, messages pre-hashed with SHA-512
+	MLDSA65ECDSAP384SHA512
+
+	// MLDSA65Ed25519SHA512 ML-DSA-65 combined with Ed25519, messages pre-hashed with SHA-512
+	MLDSA65Ed25519SHA512
+
+	// MLDSA87ECDSAP384SHA512 ML-DSA-87 combined with ECDSA P-384, messages pre-hashed with SHA-512
+	MLDSA87ECDSAP384SHA512
+)
+
+const (
+	// ContextMaxSize maximum size of the context string
+	ContextMaxSize = 255
+
+	// prefix prefix of every composite message representative
+	prefix = "CompositeAlgorithmSignatures2025"
+)
+
+var (
+	// algorithms components, ecdsa hash, pre-hash and OID of each composite algorithm, from
+	// draft-ietf-lamps-pq-composite-sigs, a nil curve being Ed25519
+	algorithms = map[Algorithm]struct {
+		name         string
+		parameterSet mldsa.ParameterSet
+		curve        elliptic.Curve
+		ecdsaHash    crypto.Hash
+		preHash      crypto.Hash
+		oid          asn1.ObjectIdentifier
+	}{
+		MLDSA44Ed25519SHA512:   {"MLDSA44-Ed25519-SHA512", mldsa.MLDSA44, nil, 0, crypto.SHA512, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 39}},
+		MLDSA44ECDSAP256SHA256: {"MLDSA44-ECDSA-P256-SHA256", mldsa.MLDSA44, elliptic.P256(), crypto.SHA256, crypto.SHA256, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 40}},
+		MLDSA65ECDSAP256SHA512: {"MLDSA65-ECDSA-P256-SHA512", mldsa.MLDSA65, elliptic.P256(), crypto.SHA256, crypto.SHA512, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 45}},
+		MLDSA65ECDSAP384SHA512: {"MLDSA65-ECDSA-P384-SHA512", mldsa.MLDSA65, elliptic.P384(), crypto.SHA384, crypto.SHA512, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 46}},
+		MLDSA65Ed25519SHA512:   {"MLDSA65-Ed25519-SHA512", mldsa.MLDSA65, nil, 0, crypto.SHA512, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 48}},
+		MLDSA87ECDSAP384SHA512: {"MLDSA87-ECDSA-P384-SHA512", mldsa.MLDSA87, elliptic.P384(), crypto.SHA384, crypto.SHA512, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 49}},
+	}
+
+	// mldsaSizes public key and signature sizes of each ML-DSA parameter set
+	mldsaSizes = map[mldsa.ParameterSet]struct {
+		publicKey int
+		signature int
+	}{
+		mldsa.MLDSA44: {1312, 2420},
+		mldsa.MLDSA65: {1952, 3309},
+		mldsa.MLDSA87: {2592, 4627},
+	}
+)
+
+type (
+	// Algorithm composite signature algorithm
+	Algorithm int
+
+	// PrivateKey composite private key made of a ML-DSA private key and an ecdsa or ed25519 private key
+	PrivateKey struct {
+		algorithm   Algorithm
+		postQuantum *mldsa.PrivateKey
+		classical   crypto.Signer
+		publicKey   *PublicKey
+	}
+
+	// PublicKey composite public key made of a ML-DSA public key and an ecdsa or ed25519 public key
+	PublicKey struct {
+		algorithm   Algorithm
+		postQuantum *mldsa.PublicKey
+		classical   crypto.PublicKey
+	}
+
+	// ICOMPOSITE interface for methods to generate composite keys, conversion to PEM format and
+	// signatures
+	ICOMPOSITE interface {
+		Generate(Algorithm) (*PrivateKey, *PublicKey, error)
+		GeneratePEM(Algorithm) (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error)
+
+		FromKeys(postQuantum *mldsa.PrivateKey, classical crypto.Signer) (*PrivateKey, error)
+
+		FromPEMPrivateKey(p.PrivatePEM) (*PrivateKey, error)
+		FromPEMPublicKey(p.PublicPEM) (*PublicKey, error)
+		FromPEM(p.PrivatePEM, p.PublicPEM) (*PrivateKey, *PublicKey, error)
+
+		ToPEMPrivateKey(*PrivateKey) (p.PrivatePEM, error)
+		ToPEMPublicKey(*PublicKey) (p.PublicPEM, error)
+		ToPEM(*PrivateKey, *PublicKey) (p.PrivatePEM, p.PublicPEM, error)
+
+		Sign(privateKey *PrivateKey, message []byte, context string) ([]byte, error)
+		Verify(publicKey *PublicKey, message, signature []byte, context string) bool
+	}
+
+	// COMPOSITE struct to implement the ICOMPOSITE methods
+	COMPOSITE struct {
+		policy  *policy.Policy
+		mldsa   mldsa.IMLDSA
+		ecdsa   ec.IECDSA
+		ed25519 ed.IED25519
+	}
+
+	// pkcs8 PKCS#8 OneAsymmetricKey structure (RFC 5958)
+	pkcs8 struct {
+		Version    int
+		Algo       pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}
+
+	// publicKeyInfo PKIX SubjectPublicKeyInfo structure (RFC 5280)
+	publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+)
+
+// NewCOMPOSITE gets a new COMPOSITE pointer
+func NewCOMPOSITE() ICOMPOSITE {
+	return &COMPOSITE{mldsa: mldsa.NewMLDSA(), ecdsa: ec.NewECDSA(), ed25519: ed.NewED25519()}
+}
+
+// NewCOMPOSITEWithPolicy gets a new COMPOSITE pointer enforcing the policy on both components of
+// generated and parsed keys
+func NewCOMPOSITEWithPolicy(policy *policy.Policy) ICOMPOSITE {
+	return &COMPOSITE{
+		policy:  policy,
+		mldsa:   mldsa.NewMLDSAWithPolicy(policy),
+		ecdsa:   ec.NewECDSAWithPolicy(policy),
+		ed25519: ed.NewED25519WithPolicy(policy),
+	}
+}
+
+// String gets the algorithm name
+func (a Algorithm) String() string {
+	if params, ok := algorithms[a]; ok {
+		return params.name
+	}
+
+	return "unknown"
+}
+
+// Algorithm gets the algorithm of the private key
+func (k *PrivateKey) Algorithm() Algorithm {
+	return k.algorithm
+}
+
+// Public gets the public half of the private key
+func (k *PrivateKey) Public() *PublicKey {
+	return k.publicKey
+}
+
+// Algorithm gets the algorithm of the public key
+func (k *PublicKey) Algorithm() Algorithm {
+	return k.algorithm
+}
+
+// Equal reports whether both public keys are the same
+func (k *PublicKey) Equal(other *PublicKey) bool {
+	if other == nil || k.algorithm != other.algorithm || !k.postQuantum.Equal(other.postQuantum) {
+		return false
+	}
+
+	classical, ok := k.classical.(interface{ Equal(crypto.PublicKey) bool })
+
+	return ok && classical.Equal(other.classical)
+}
+
+// Generate generates new private/public keys of the algorithm
+func (co *COMPOSITE) Generate(algorithm Algorithm) (*PrivateKey, *PublicKey, error) {
+	params, ok := algorithms[algorithm]
+	if !ok {
+		return nil, nil, c.ErrUnsupportedAlgorithm
+	}
+
+	var (
+		classical crypto.Signer
+		err       error
+	)
+
+	switch params.curve {
+	case nil:
+		classical, _, err = co.ed25519.Ed25519()
+	case elliptic.P256():
+		classical, _, err = co.ecdsa.P256()
+	case elliptic.P384():
+		classical, _, err = co.ecdsa.P384()
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var postQuantum *mldsa.PrivateKey
+	switch params.parameterSet {
+	case mldsa.MLDSA44:
+		postQuantum, _, err = co.mldsa.MLDSA44()
+	case mldsa.MLDSA65:
+		postQuantum, _, err = co.mldsa.MLDSA65()
+	case mldsa.MLDSA87:
+		postQuantum, _, err = co.mldsa.MLDSA87()
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err := co.FromKeys(postQuantum, classical)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return privateKey, privateKey.Public(), nil
+}
+
+// GeneratePEM generates new private/public pem keys of the algorithm
+func (co *COMPOSITE) GeneratePEM(algorithm Algorithm) (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error) {
+	privateKey, publicKey, err := co.Generate(algorithm)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	prvPEM, pubPEM, err := co.ToPEM(privateKey, publicKey)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return privateKey, prvPEM, publicKey, pubPEM, nil
+}
+
+// FromKeys pairs a ML-DSA private key with an existing ecdsa P-256/P-384 or ed25519 private key,
+// the algorithm being picked from the parameter set and curve
+func (co *COMPOSITE) FromKeys(postQuantum *mldsa.PrivateKey, classical crypto.Signer) (*PrivateKey, error) {
+	if postQuantum == nil || classical == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	var curve elliptic.Curve
+	switch key := classical.(type) {
+	case *ecdsa.PrivateKey:
+		if key == nil {
+			return nil, c.ErrNilPrivateKey
+		}
+
+		curve = key.Curve
+	case ed25519.PrivateKey:
+		if len(key) != ed25519.PrivateKeySize {
+			return nil, c.ErrInvalidKeyLength
+		}
+	default:
+		return nil, c.ErrUnsupportedPrivateKey
+	}
+
+	algorithm, ok := algorithmFromComponents(postQuantum.ParameterSet(), curve)
+	if !ok {
+		return nil, c.ErrUnsupportedAlgorithm
+	}
+
+	publicKey := &PublicKey{algorithm: algorithm, postQuantum: postQuantum.Public(), classical: classical.Public()}
+	if err := co.checkPolicy(publicKey); err != nil {
+		return nil, err
+	}
+
+	return &PrivateKey{algorithm: algorithm, postQuantum: postQuantum, classical: classical, publicKey: publicKey}, nil
+}
+
+// FromPEMPrivateKey takes a PKCS#8 private pem key holding the ML-DSA seed followed by the ed25519
+// seed or the ecdsa ECPrivateKey and converts it into a composite private key
+func (co *COMPOSITE) FromPEMPrivateKey(privatePEM p.PrivatePEM) (*PrivateKey, error) {
+	block, _ := pem.Decode(privatePEM)
+
+	if block == nil || block.Type != c.PRIVATEKEY {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	if err := co.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	var info pkcs8
+	if rest, err := asn1.Unmarshal(block.Bytes, &info); err != nil || len(rest) != 0 {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	algorithm, ok := algorithmFromOID(info.Algo.Algorithm)
+	if !ok {
+		return nil, c.ErrUnsupportedPrivateKey
+	}
+
+	params := algorithms[algorithm]
+	if len(info.PrivateKey) <= mldsa.SeedSize {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	postQuantum, err := co.mldsa.FromRawPrivateKey(params.parameterSet, info.PrivateKey[:mldsa.SeedSize])
+	if err != nil {
+		return nil, err
+	}
+
+	var classical crypto.Signer
+	if params.curve == nil {
+		seed := info.PrivateKey[mldsa.SeedSize:]
+		if len(seed) != ed25519.SeedSize {
+			return nil, c.ErrInvalidKeyLength
+		}
+
+		classical = ed25519.NewKeyFromSeed(seed)
+	} else {
+		key, err := x509.ParseECPrivateKey(info.PrivateKey[mldsa.SeedSize:])
+		if err != nil {
+			return nil, c.ErrDecodePEMPrivateKey
+		}
+
+		if key.Curve != params.curve {
+			return nil, c.ErrUnsupportedCurve
+		}
+
+		classical = key
+	}
+
+	return co.FromKeys(postQuantum, classical)
+}
+
+// FromPEMPublicKey takes a PKIX public pem key holding the ML-DSA public key followed by the ed25519
+// public key or the uncompressed ecdsa point and converts it into a composite public key
+func (co *COMPOSITE) FromPEMPublicKey(publicPEM p.PublicPEM) (*PublicKey, error) {
+	block, _ := pem.Decode(publicPEM)
+
+	if block == nil || block.Type != c.PUBLICKEY {
+		return nil, c.ErrDecodePEMPublicKey
+	}
+
+	if err := co.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	var info publicKeyInfo
+	if rest, err := asn1.Unmarshal(block.Bytes, &info); err != nil || len(rest) != 0 {
+		return nil, c.ErrDecodePEMPublicKey
+	}
+
+	algorithm, ok := algorithmFromOID(info.Algorithm.Algorithm)
+	if !ok {
+		return nil, c.ErrUnsupportedPublicKey
+	}
+
+	params := algorithms[algorithm]
+	data := info.PublicKey.RightAlign()
+	size := mldsaSizes[params.parameterSet].publicKey
+
+	if len(data) <= size {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	postQuantum, err := co.mldsa.FromRawPublicKey(params.parameterSet, data[:size])
+	if err != nil {
+		return nil, err
+	}
+
+	var classical crypto.PublicKey
+	if params.curve == nil {
+		if len(data[size:]) != ed25519.PublicKeySize {
+			return nil, c.ErrInvalidKeyLength
+		}
+
+		classical = ed25519.PublicKey(append([]byte(nil), data[size:]...))
+	} else {
+		if classical, err = co.ecdsa.FromUncompressedPoint(params.curve, data[size:]); err != nil {
+			return nil, err
+		}
+	}
+
+	publicKey := &PublicKey{algorithm: algorithm, postQuantum: postQuantum, classical: classical}
+	if err := co.checkPolicy(publicKey); err != nil {
+		return nil, err
+	}
+
+	return publicKey, nil
+}
+
+// FromPEM takes pem keys and converts them into composite keys, failing when the public key is not
+// the public half of the private key
+func (co *COMPOSITE) FromPEM(privatePEM p.PrivatePEM, publicPEM p.PublicPEM) (*PrivateKey, *PublicKey, error) {
+	privateKey, err := co.FromPEMPrivateKey(privatePEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicKey, err := co.FromPEMPublicKey(publicPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !publicKey.Equal(privateKey.Public()) {
+		return nil, nil, c.ErrKeyMismatch
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// ToPEMPrivateKey converts a composite private key into a PKCS#8 private PEM key, failing when the
+// ML-DSA private key has no seed
+func (co *COMPOSITE) ToPEMPrivateKey(privateKey *PrivateKey) (p.PrivatePEM, error) {
+	if privateKey == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if err := co.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	seed, err := co.mldsa.ToRawPrivateKey(privateKey.postQuantum)
+	if err != nil {
+		return nil, err
+	}
+
+	var classical []byte
+	switch key := privateKey.classical.(type) {
+	case ed25519.PrivateKey:
+		classical = key.Seed()
+	case *ecdsa.PrivateKey:
+		if classical, err = x509.MarshalECPrivateKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	encoded, err := asn1.Marshal(pkcs8{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: algorithms[privateKey.algorithm].oid},
+		PrivateKey: append(seed, classical...),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: encoded}), nil
+}
+
+// ToPEMPublicKey converts a composite public key into a PKIX public PEM key
+func (co *COMPOSITE) ToPEMPublicKey(publicKey *PublicKey) (p.PublicPEM, error) {
+	if publicKey == nil {
+		return nil, c.ErrNilPublicKey
+	}
+
+	if err := co.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	raw, err := co.mldsa.ToRawPublicKey(publicKey.postQuantum)
+	if err != nil {
+		return nil, err
+	}
+
+	var classical []byte
+	switch key := publicKey.classical.(type) {
+	case ed25519.PublicKey:
+		classical = key
+	case *ecdsa.PublicKey:
+		if classical, err = co.ecdsa.ToUncompressedPoint(key); err != nil {
+			return nil, err
+		}
+	}
+
+	raw = append(raw, classical...)
+
+	encoded, err := asn1.Marshal(publicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: algorithms[publicKey.algorithm].oid},
+		PublicKey: asn1.BitString{Bytes: raw, BitLength: 8 * len(raw)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: c.PUBLICKEY, Bytes: encoded}), nil
+}
+
+// ToPEM converts composite private & public keys, into private & public PEM keys
+func (co *COMPOSITE) ToPEM(privateKey *PrivateKey, publicKey *PublicKey) (p.PrivatePEM, p.PublicPEM, error) {
+	prvPEM, err := co.ToPEMPrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pubPEM, err := co.ToPEMPublicKey(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return prvPEM, pubPEM, nil
+}
+
+// Sign signs the message with both components, the ML-DSA signature followed by the ed25519 or
+// ASN.1 ecdsa signature of the message representative
+func (co *COMPOSITE) Sign(privateKey *PrivateKey, message []byte, context string) ([]byte, error) {
+	if privateKey == nil || privateKey.postQuantum == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if len(context) > ContextMaxSize {
+		return nil, c.ErrContextTooLong
+	}
+
+	if err := co.checkPolicy(privateKey.Public()); err != nil {
+		return nil, err
+	}
+
+	params := algorithms[privateKey.algorithm]
+	representative := messageRepresentative(privateKey.algorithm, message, context)
+
+	postQuantum, err := co.mldsa.Sign(privateKey.postQuantum, representative, label(privateKey.algorithm))
+	if err != nil {
+		return nil, err
+	}
+
+	var classical []byte
+	switch key := privateKey.classical.(type) {
+	case ed25519.PrivateKey:
+		classical = ed25519.Sign(key, representative)
+	case *ecdsa.PrivateKey:
+		digest := params.ecdsaHash.New()
+		digest.Write(representative)
+
+		if classical, err = co.ecdsa.Sign(key, digest.Sum(nil)); err != nil {
+			return nil, err
+		}
+	}
+
+	return append(postQuantum, classical...), nil
+}
+
+// Verify reports whether both component signatures of the message are valid, false when the policy
+// does not allow the algorithm
+func (co *COMPOSITE) Verify(publicKey *PublicKey, message, signature []byte, context string) bool {
+	if publicKey == nil || publicKey.postQuantum == nil || len(context) > ContextMaxSize {
+		return false
+	}
+
+	if co.checkPolicy(publicKey) != nil {
+		return false
+	}
+
+	params := algorithms[publicKey.algorithm]
+	size := mldsaSizes[params.parameterSet].signature
+
+	if len(signature) <= size {
+		return false
+	}
+
+	representative := messageRepresentative(publicKey.algorithm, message, context)
+
+	if !co.mldsa.Verify(publicKey.postQuantum, representative, signature[:size], label(publicKey.algorithm)) {
+		return false
+	}
+
+	switch key := publicKey.classical.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, representative, signature[size:])
+	case *ecdsa.PublicKey:
+		digest := params.ecdsaHash.New()
+		digest.Write(representative)
+
+		return co.ecdsa.Verify(key, digest.Sum(nil), signature[size:])
+	}
+
+	return false
+}
+
+// checkPolicy checks the policy allows both components and the hash functions of the public key
+func (co *COMPOSITE) checkPolicy(publicKey *PublicKey) error {
+	params := algorithms[publicKey.algorithm]
+
+	if err := co.policy.CheckAlgorithm(policy.Algorithm(params.parameterSet.String())); err != nil {
+		return err
+	}
+
+	if err := co.policy.CheckPublicKey(publicKey.classical); err != nil {
+		return err
+	}
+
+	if err := co.policy.CheckHash(params.preHash); err != nil {
+		return err
+	}
+
+	if params.curve != nil {
+		return co.policy.CheckHash(params.ecdsaHash)
+	}
+
+	return nil
+}
+
+// messageRepresentative builds the message signed by both components, Prefix || Label || len(ctx) ||
+// ctx || PH(M)
+func messageRepresentative(algorithm Algorithm, message []byte, context string) []byte {
+	digest := algorithms[algorithm].preHash.New()
+	digest.Write(message)
+
+	representative := append([]byte(prefix), label(algorithm)...)
+	representative = append(representative, byte(len(context)))
+	representative = append(representative, context...)
+
+	return digest.Sum(representative)
+}
+
+// label gets the domain separation label of the algorithm
+func label(algorithm Algorithm) string {
+	return "COMPSIG-" + algorithms[algorithm].name
+}
+
+// algorithmFromComponents gets the algorithm of the ML-DSA parameter set and curve, nil for ed25519
+func algorithmFromComponents(parameterSet mldsa.ParameterSet, curve elliptic.Curve) (Algorithm, bool) {
+	for algorithm, params := range algorithms {
+		if params.parameterSet == parameterSet && params.curve == curve {
+			return algorithm, true
+		}
+	}
+
+	return 0, false
+}
+
+// algorithmFromOID gets the algorithm of the OID
+func algorithmFromOID(oid asn1.ObjectIdentifier) (Algorithm, bool) {
+	for algorithm, params := range algorithms {
+		if params.oid.Equal(oid) {
+			return algorithm, true
+		}
+	}
+
+	return 0, false
+}
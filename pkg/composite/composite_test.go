@@ -0,0 +1,355 @@
+package composite
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"testing"
+
+	c "github.com/ELares/crypto/pkg"
+	"github.com/ELares/crypto/pkg/mldsa"
+	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+var allAlgorithms = []Algorithm{
+	MLDSA44Ed25519SHA512,
+	MLDSA44ECDSAP256SHA256,
+	MLDSA65ECDSAP256SHA512,
+	MLDSA65ECDSAP384SHA512,
+	MLDSA65Ed25519SHA512,
+	MLDSA87ECDSAP384SHA512,
+}
+
+func TestSign(t *testing.T) {
+	co := NewCOMPOSITE()
+
+	for _, algorithm := range allAlgorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			prvKey, pubKey, err := co.Generate(algorithm)
+			assert.Nil(t, err)
+			assert.Equal(t, algorithm, prvKey.Algorithm())
+
+			signature, err := co.Sign(prvKey, []byte("firmware"), "context")
+			assert.Nil(t, err)
+
+			assert.True(t, co.Verify(pubKey, []byte("firmware"), signature, "context"))
+			assert.False(t, co.Verify(pubKey, []byte("firmware"), signature, ""))
+			assert.False(t, co.Verify(pubKey, []byte("tampered"), signature, "context"))
+
+			size := mldsaSizes[algorithms[algorithm].parameterSet].signature
+
+			// both component signatures must be valid
+			tampered := append([]byte{}, signature...)
+			tampered[0] ^= 1
+			assert.False(t, co.Verify(pubKey, []byte("firmware"), tampered, "context"))
+
+			tampered = append([]byte{}, signature...)
+			tampered[len(tampered)-1] ^= 1
+			assert.False(t, co.Verify(pubKey, []byte("firmware"), tampered, "context"))
+
+			assert.False(t, co.Verify(pubKey, []byte("firmware"), signature[:size], "context"))
+		})
+	}
+}
+
+func TestMessageRepresentative(t *testing.T) {
+	co := NewCOMPOSITE()
+
+	prvKey, pubKey, _ := co.Generate(MLDSA65Ed25519SHA512)
+	signature, _ := co.Sign(prvKey, []byte("firmware"), "ctx")
+
+	digest := sha512.Sum512([]byte("firmware"))
+	representative, _ := hex.DecodeString("436f6d706f73697465416c676f726974686d5369676e61747572657332303235")
+	representative = append(representative, "COMPSIG-MLDSA65-Ed25519-SHA512"...)
+	representative = append(representative, 3, 'c', 't', 'x')
+	representative = append(representative, digest[:]...)
+
+	assert.Equal(t, representative, messageRepresentative(MLDSA65Ed25519SHA512, []byte("firmware"), "ctx"))
+
+	// each component verifies the representative on its own, ML-DSA with the label as context
+	size := mldsaSizes[mldsa.MLDSA65].signature
+	assert.True(t, mldsa.NewMLDSA().Verify(pubKey.postQuantum, representative, signature[:size], "COMPSIG-MLDSA65-Ed25519-SHA512"))
+	assert.True(t, ed25519.Verify(pubKey.classical.(ed25519.PublicKey), representative, signature[size:]))
+}
+
+// mldsaKeyGen NIST ACVP ML-DSA key generation vectors (FIPS 204) of the mldsa package, one per
+// parameter set
+type mldsaKeyGen struct {
+	KeyGen []struct {
+		ParameterSet string `json:"parameterSet"`
+		Seed         string `json:"seed"`
+		PK           string `json:"pk"`
+	} `json:"keyGen"`
+}
+
+// TestKnownAnswer builds the keys and signatures of every algorithm by hand, following
+// draft-ietf-lamps-pq-composite-sigs, from published component keys: the NIST ACVP ML-DSA key
+// generation vectors, the RFC 8032 Ed25519 key and the RFC 6979 P-256 and P-384 keys
+func TestKnownAnswer(t *testing.T) {
+	co := NewCOMPOSITE()
+	m := mldsa.NewMLDSA()
+
+	data, err := os.ReadFile("../mldsa/testdata/acvp.json")
+	assert.Nil(t, err)
+
+	var vectors mldsaKeyGen
+	assert.Nil(t, json.Unmarshal(data, &vectors))
+
+	mldsaKeys := map[string][2][]byte{}
+	for _, v := range vectors.KeyGen {
+		seed, _ := hex.DecodeString(v.Seed)
+		publicKey, _ := hex.DecodeString(v.PK)
+		mldsaKeys[v.ParameterSet] = [2][]byte{seed, publicKey}
+	}
+
+	// RFC 8032 section 7.1 test 1
+	ed25519Seed, _ := hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+	ed25519Public, _ := hex.DecodeString("d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a")
+
+	// RFC 6979 appendices A.2.5 and A.2.6
+	p256 := knownECDSAKey(elliptic.P256(),
+		"c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721",
+		"60fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6",
+		"7903fe1008b8bc99a41ae9e95628bc64f2f1b20c2d7e9f5177a3c294d4462299")
+	p384 := knownECDSAKey(elliptic.P384(),
+		"6b9d3dad2e1b8c1c05b19875b6659f4de23c3b667bf297ba9aa47740787137d896d5724e4c70a825f872c9ea60d2edf5",
+		"ec3a4e415b4e19a4568618029f427fa5da9a8bc4ae92e02e06aae5286b300c64def8f0ea9055866064a254515480bc13",
+		"8015d9b72d7d57244ea8ef9ac0c621896708a59367f9dfb9f54ca84b3f1c9db1288b231c3ae0d4fe7344fd2533264720")
+
+	testcases := []struct {
+		algorithm Algorithm
+
+		oid       asn1.ObjectIdentifier
+		label     string
+		mldsa     string
+		preHash   crypto.Hash
+		ecdsaHash crypto.Hash
+		ecdsa     *ecdsa.PrivateKey
+	}{
+		{algorithm: MLDSA44Ed25519SHA512, oid: asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 39}, label: "COMPSIG-MLDSA44-Ed25519-SHA512", mldsa: "ML-DSA-44", preHash: crypto.SHA512},
+		{algorithm: MLDSA44ECDSAP256SHA256, oid: asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 40}, label: "COMPSIG-MLDSA44-ECDSA-P256-SHA256", mldsa: "ML-DSA-44", preHash: crypto.SHA256, ecdsaHash: crypto.SHA256, ecdsa: p256},
+		{algorithm: MLDSA65ECDSAP256SHA512, oid: asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 45}, label: "COMPSIG-MLDSA65-ECDSA-P256-SHA512", mldsa: "ML-DSA-65", preHash: crypto.SHA512, ecdsaHash: crypto.SHA256, ecdsa: p256},
+		{algorithm: MLDSA65ECDSAP384SHA512, oid: asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 46}, label: "COMPSIG-MLDSA65-ECDSA-P384-SHA512", mldsa: "ML-DSA-65", preHash: crypto.SHA512, ecdsaHash: crypto.SHA384, ecdsa: p384},
+		{algorithm: MLDSA65Ed25519SHA512, oid: asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 48}, label: "COMPSIG-MLDSA65-Ed25519-SHA512", mldsa: "ML-DSA-65", preHash: crypto.SHA512},
+		{algorithm: MLDSA87ECDSAP384SHA512, oid: asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 49}, label: "COMPSIG-MLDSA87-ECDSA-P384-SHA512", mldsa: "ML-DSA-87", preHash: crypto.SHA512, ecdsaHash: crypto.SHA384, ecdsa: p384},
+	}
+
+	message := []byte("The quick brown fox jumps over the lazy dog")
+	context := "known answer"
+
+	for _, tc := range testcases {
+		t.Run(tc.algorithm.String(), func(t *testing.T) {
+			mldsaSeed, mldsaPublic := mldsaKeys[tc.mldsa][0], mldsaKeys[tc.mldsa][1]
+
+			// the composite keys are the ML-DSA key followed by the traditional key: the public key,
+			// or the Ed25519 seed or ECPrivateKey
+			classicalPublic, classicalPrivate := ed25519Public, ed25519Seed
+			if tc.ecdsa != nil {
+				size := (tc.ecdsa.Curve.Params().BitSize + 7) / 8
+				classicalPublic = append([]byte{4}, tc.ecdsa.X.FillBytes(make([]byte, size))...)
+				classicalPublic = append(classicalPublic, tc.ecdsa.Y.FillBytes(make([]byte, size))...)
+				classicalPrivate, err = x509.MarshalECPrivateKey(tc.ecdsa)
+				assert.Nil(t, err)
+			}
+
+			publicKeyDER, err := asn1.Marshal(publicKeyInfo{
+				Algorithm: pkix.AlgorithmIdentifier{Algorithm: tc.oid},
+				PublicKey: asn1.BitString{Bytes: append(append([]byte{}, mldsaPublic...), classicalPublic...), BitLength: 8 * (len(mldsaPublic) + len(classicalPublic))},
+			})
+			assert.Nil(t, err)
+
+			privateKeyDER, err := asn1.Marshal(pkcs8{
+				Algo:       pkix.AlgorithmIdentifier{Algorithm: tc.oid},
+				PrivateKey: append(append([]byte{}, mldsaSeed...), classicalPrivate...),
+			})
+			assert.Nil(t, err)
+
+			publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER})
+			privatePEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyDER})
+
+			prvKey, pubKey, err := co.FromPEM(p.PrivatePEM(privatePEM), p.PublicPEM(publicPEM))
+			assert.Nil(t, err)
+			assert.Equal(t, tc.algorithm, pubKey.Algorithm())
+
+			encodedPublic, err := co.ToPEMPublicKey(pubKey)
+			assert.Nil(t, err)
+			assert.Equal(t, string(publicPEM), string(encodedPublic))
+
+			encodedPrivate, err := co.ToPEMPrivateKey(prvKey)
+			assert.Nil(t, err)
+			assert.Equal(t, string(privatePEM), string(encodedPrivate))
+
+			// M' = Prefix || Label || len(ctx) || ctx || PH(M)
+			preHash := tc.preHash.New()
+			preHash.Write(message)
+
+			representative := []byte("CompositeAlgorithmSignatures2025" + tc.label)
+			representative = append(representative, byte(len(context)))
+			representative = append(representative, context...)
+			representative = preHash.Sum(representative)
+
+			mldsaKey, err := m.FromRawPrivateKey(prvKey.postQuantum.ParameterSet(), mldsaSeed)
+			assert.Nil(t, err)
+
+			// a signature assembled from the components verifies
+			mldsaSignature, err := m.Sign(mldsaKey, representative, tc.label)
+			assert.Nil(t, err)
+
+			var classicalSignature []byte
+			if tc.ecdsa != nil {
+				digest := tc.ecdsaHash.New()
+				digest.Write(representative)
+				classicalSignature, err = ecdsa.SignASN1(rand.Reader, tc.ecdsa, digest.Sum(nil))
+				assert.Nil(t, err)
+			} else {
+				classicalSignature = ed25519.Sign(ed25519.NewKeyFromSeed(ed25519Seed), representative)
+			}
+
+			assert.True(t, co.Verify(pubKey, message, append(mldsaSignature, classicalSignature...), context))
+			assert.False(t, co.Verify(pubKey, message, append(mldsaSignature, classicalSignature...), "other context"))
+
+			// a signature of the package splits into the same components
+			signature, err := co.Sign(prvKey, message, context)
+			assert.Nil(t, err)
+
+			size := len(mldsaSignature)
+			assert.True(t, m.Verify(mldsaKey.Public(), representative, signature[:size], tc.label))
+
+			if tc.ecdsa != nil {
+				digest := tc.ecdsaHash.New()
+				digest.Write(representative)
+				assert.True(t, ecdsa.VerifyASN1(&tc.ecdsa.PublicKey, digest.Sum(nil), signature[size:]))
+			} else {
+				assert.Equal(t, ed25519.Sign(ed25519.NewKeyFromSeed(ed25519Seed), representative), signature[size:])
+			}
+		})
+	}
+}
+
+// knownECDSAKey builds a ecdsa private key from its hexadecimal scalar and public point
+func knownECDSAKey(curve elliptic.Curve, d, x, y string) *ecdsa.PrivateKey {
+	key := &ecdsa.PrivateKey{D: new(big.Int)}
+	key.Curve = curve
+	key.D.SetString(d, 16)
+	key.X, _ = new(big.Int).SetString(x, 16)
+	key.Y, _ = new(big.Int).SetString(y, 16)
+
+	return key
+}
+
+func TestFromKeys(t *testing.T) {
+	co := NewCOMPOSITE()
+
+	mldsa65, _, _ := mldsa.NewMLDSA().MLDSA65()
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p521, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	_, ed, _ := ed25519.GenerateKey(rand.Reader)
+
+	prvKey, err := co.FromKeys(mldsa65, p256)
+	assert.Nil(t, err)
+	assert.Equal(t, MLDSA65ECDSAP256SHA512, prvKey.Algorithm())
+
+	prvKey, err = co.FromKeys(mldsa65, ed)
+	assert.Nil(t, err)
+	assert.Equal(t, MLDSA65Ed25519SHA512, prvKey.Algorithm())
+
+	_, err = co.FromKeys(mldsa65, p521)
+	assert.Equal(t, c.ErrUnsupportedAlgorithm, err)
+
+	_, err = co.FromKeys(nil, p256)
+	assert.Equal(t, c.ErrNilPrivateKey, err)
+
+	_, _, err = co.Generate(Algorithm(42))
+	assert.Equal(t, c.ErrUnsupportedAlgorithm, err)
+}
+
+func TestPEM(t *testing.T) {
+	co := NewCOMPOSITE()
+
+	for _, algorithm := range allAlgorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			prvKey, prvPEM, pubKey, pubPEM, err := co.GeneratePEM(algorithm)
+			assert.Nil(t, err)
+
+			parsedPrvKey, parsedPubKey, err := co.FromPEM(prvPEM, pubPEM)
+			assert.Nil(t, err)
+			assert.True(t, pubKey.Equal(parsedPubKey))
+			assert.True(t, prvKey.postQuantum.Equal(parsedPrvKey.postQuantum))
+
+			signature, err := co.Sign(parsedPrvKey, []byte("firmware"), "")
+			assert.Nil(t, err)
+			assert.True(t, co.Verify(pubKey, []byte("firmware"), signature, ""))
+
+			// ML-DSA public key followed by the classical public key
+			block, _ := pem.Decode(pubPEM)
+			var info publicKeyInfo
+			_, err = asn1.Unmarshal(block.Bytes, &info)
+			assert.Nil(t, err)
+			assert.Equal(t, algorithms[algorithm].oid, info.Algorithm.Algorithm)
+
+			mldsaPublicKey, _ := mldsa.NewMLDSA().ToRawPublicKey(pubKey.postQuantum)
+			assert.Equal(t, mldsaPublicKey, info.PublicKey.Bytes[:len(mldsaPublicKey)])
+		})
+	}
+
+	_, prvPEM, _, _, _ := co.GeneratePEM(MLDSA65ECDSAP384SHA512)
+	_, _, _, otherPubPEM, _ := co.GeneratePEM(MLDSA65ECDSAP384SHA512)
+
+	_, _, err := co.FromPEM(prvPEM, otherPubPEM)
+	assert.Equal(t, c.ErrKeyMismatch, err)
+
+	_, err = co.FromPEMPrivateKey(p.PrivatePEM(otherPubPEM))
+	assert.Equal(t, c.ErrDecodePEMPrivateKey, err)
+
+	_, err = co.FromPEMPublicKey(p.PublicPEM(prvPEM))
+	assert.Equal(t, c.ErrDecodePEMPublicKey, err)
+}
+
+func TestWithPolicy(t *testing.T) {
+	_, prvPEM, _, pubPEM, _ := NewCOMPOSITE().GeneratePEM(MLDSA65ECDSAP256SHA512)
+
+	modern, _ := policy.NewPolicy(policy.Modern)
+	_, _, err := NewCOMPOSITEWithPolicy(modern).FromPEM(prvPEM, pubPEM)
+	assert.Nil(t, err)
+
+	cnsa, _ := policy.NewPolicy(policy.CNSA2)
+	co := NewCOMPOSITEWithPolicy(cnsa)
+
+	_, _, err = co.Generate(MLDSA87ECDSAP384SHA512)
+	assert.Nil(t, err)
+
+	prvKey, pubKey, err := co.Generate(MLDSA65ECDSAP384SHA512)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+	assert.Nil(t, prvKey)
+	assert.Nil(t, pubKey)
+
+	_, err = co.FromPEMPrivateKey(prvPEM)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, err = co.FromPEMPublicKey(pubPEM)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, _, err = NewCOMPOSITEWithPolicy(modern).Generate(MLDSA44Ed25519SHA512)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	// a signature valid without policy does not verify under a policy rejecting its algorithm
+	prvKey, pubKey, _ = NewCOMPOSITE().Generate(MLDSA65ECDSAP256SHA512)
+	signature, err := NewCOMPOSITE().Sign(prvKey, []byte("message"), "")
+	assert.Nil(t, err)
+	assert.True(t, NewCOMPOSITEWithPolicy(modern).Verify(pubKey, []byte("message"), signature, ""))
+	assert.False(t, co.Verify(pubKey, []byte("message"), signature, ""))
+}
@@ -7,6 +7,21 @@ const (
 	// PRIVATEKEY = PRIVATE KEY
 	PRIVATEKEY = "PRIVATE KEY"
 
+	// ENCRYPTEDPRIVATEKEY = ENCRYPTED PRIVATE KEY
+	ENCRYPTEDPRIVATEKEY = "ENCRYPTED PRIVATE KEY"
+
+	// CERTIFICATE = CERTIFICATE
+	CERTIFICATE = "CERTIFICATE"
+
+	// CERTIFICATEREQUEST = CERTIFICATE REQUEST
+	CERTIFICATEREQUEST = "CERTIFICATE REQUEST"
+
+	// X509CRL = X509 CRL
+	X509CRL = "X509 CRL"
+
+	// SHAMIRSHARE = SHAMIR SHARE
+	SHAMIRSHARE = "SHAMIR SHARE"
+
 	// SIG use for JWK use header
 	SIG = "sig"
 )
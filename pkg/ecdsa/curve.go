@@ -0,0 +1,433 @@
+package ecdsa
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"io"
+	"math/big"
+
+	"filippo.io/bigmod"
+)
+
+var (
+	secp256k1 = newCurve("secp256k1", 256,
+		"fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f",
+		"0",
+		"7",
+		"79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		"483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8",
+		"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141",
+	)
+
+	brainpoolP256r1 = newCurve("brainpoolP256r1", 256,
+		"a9fb57dba1eea9bc3e660a909d838d726e3bf623d52620282013481d1f6e5377",
+		"7d5a0975fc2c3057eef67530417affe7fb8055c126dc5c6ce94a4b44f330b5d9",
+		"26dc5c6ce94a4b44f330b5d9bbd77cbf958416295cf7e1ce6bccdc18ff8c07b6",
+		"8bd2aeb9cb7e57cb2c4b482ffc81b7afb9de27e1e3bd23c23a4453bd9ace3262",
+		"547ef835c3dac4fd97f8461a14611dc9c27745132ded8e545c1d54c72f046997",
+		"a9fb57dba1eea9bc3e660a909d838d718c397aa3b561a6f7901e0e82974856a7",
+	)
+
+	brainpoolP384r1 = newCurve("brainpoolP384r1", 384,
+		"8cb91e82a3386d280f5d6f7e50e641df152f7109ed5456b412b1da197fb71123acd3a729901d1a71874700133107ec53",
+		"7bc382c63d8c150c3c72080ace05afa0c2bea28e4fb22787139165efba91f90f8aa5814a503ad4eb04a8c7dd22ce2826",
+		"04a8c7dd22ce28268b39b55416f0447c2fb77de107dcd2a62e880ea53eeb62d57cb4390295dbc9943ab78696fa504c11",
+		"1d1c64f068cf45ffa2a63a81b7c13f6b8847a3e77ef14fe3db7fcafe0cbd10e8e826e03436d646aaef87b2e247d4af1e",
+		"8abe1d7520f9c2a45cb1eb8e95cfd55262b70b29feec5864e19c054ff99129280e4646217791811142820341263c5315",
+		"8cb91e82a3386d280f5d6f7e50e641df152f7109ed5456b31f166e6cac0425a7cf3ab6af6b7fc3103b883202e9046565",
+	)
+
+	brainpoolP512r1 = newCurve("brainpoolP512r1", 512,
+		"aadd9db8dbe9c48b3fd4e6ae33c9fc07cb308db3b3c9d20ed6639cca703308717d4d9b009bc66842aecda12ae6a380e62881ff2f2d82c68528aa6056583a48f3",
+		"7830a3318b603b89e2327145ac234cc594cbdd8d3df91610a83441caea9863bc2ded5d5aa8253aa10a2ef1c98b9ac8b57f1117a72bf2c7b9e7c1ac4d77fc94ca",
+		"3df91610a83441caea9863bc2ded5d5aa8253aa10a2ef1c98b9ac8b57f1117a72bf2c7b9e7c1ac4d77fc94cadc083e67984050b75ebae5dd2809bd638016f723",
+		"81aee4bdd82ed9645a21322e9c4c6a9385ed9f70b5d916c1b43b62eef4d0098eff3b1f78e2d0d48d50d1687b93b97d5f7c6d5047406a5e688b352209bcb9f822",
+		"7dde385d566332ecc0eabfa9cf7822fdf209f70024a57b1aa000c55b881f8111b2dcde494a5f485e5bca4bd88a2763aed1ca2b2fa8f0540678cd1e0f3ad80892",
+		"aadd9db8dbe9c48b3fd4e6ae33c9fc07cb308db3b3c9d20ed6639cca70330870553e5c414ca92619418661197fac10471db1d381085ddaddb58796829ca90069",
+	)
+)
+
+type (
+	// curve short Weierstrass curve y² = x³ + ax + b implementing elliptic.Curve for the curves
+	// crypto/elliptic does not provide, its CurveParams methods assume a = -3. The arithmetic is
+	// constant time: a Montgomery ladder over the complete addition formulas of Renes, Costello and
+	// Batina, with the field and scalar elements of filippo.io/bigmod
+	curve struct {
+		params *elliptic.CurveParams
+		a      *big.Int
+
+		field, order     *bigmod.Modulus
+		fieldA, fieldB3  *bigmod.Nat
+		pMinus2, nMinus2 []byte
+	}
+
+	// projective point (X/Z, Y/Z), (0 : 1 : 0) being the point at infinity
+	projective struct {
+		x, y, z *bigmod.Nat
+	}
+)
+
+// genericHook test hook called whenever the curve arithmetic is reached through the elliptic.Curve
+// methods, as the generic crypto/ecdsa implementation does
+var genericHook func()
+
+// Secp256k1 gets the secp256k1 curve (SEC 2) used by Bitcoin, Ethereum and ES256K
+func Secp256k1() elliptic.Curve {
+	return secp256k1
+}
+
+// BrainpoolP256r1 gets the brainpoolP256r1 curve (RFC 5639)
+func BrainpoolP256r1() elliptic.Curve {
+	return brainpoolP256r1
+}
+
+// BrainpoolP384r1 gets the brainpoolP384r1 curve (RFC 5639)
+func BrainpoolP384r1() elliptic.Curve {
+	return brainpoolP384r1
+}
+
+// BrainpoolP512r1 gets the brainpoolP512r1 curve (RFC 5639)
+func BrainpoolP512r1() elliptic.Curve {
+	return brainpoolP512r1
+}
+
+// newCurve builds a curve from its hexadecimal parameters
+func newCurve(name string, bitSize int, prime, a, b, gx, gy, order string) *curve {
+	return fromParams(&elliptic.CurveParams{
+		Name:    name,
+		BitSize: bitSize,
+		P:       fromHex(prime),
+		B:       fromHex(b),
+		Gx:      fromHex(gx),
+		Gy:      fromHex(gy),
+		N:       fromHex(order),
+	}, fromHex(a))
+}
+
+// fromParams builds a curve from its parameters and a, precomputing their constant-time forms
+func fromParams(params *elliptic.CurveParams, a *big.Int) *curve {
+	field, err := bigmod.NewModulus(params.P.Bytes())
+	if err != nil {
+		panic("ecdsa: invalid curve prime of " + params.Name)
+	}
+
+	order, err := bigmod.NewModulus(params.N.Bytes())
+	if err != nil {
+		panic("ecdsa: invalid curve order of " + params.Name)
+	}
+
+	c := &curve{
+		params:  params,
+		a:       a,
+		field:   field,
+		order:   order,
+		pMinus2: new(big.Int).Sub(params.P, big.NewInt(2)).Bytes(),
+		nMinus2: new(big.Int).Sub(params.N, big.NewInt(2)).Bytes(),
+	}
+	c.fieldA = c.element(a)
+	c.fieldB3 = c.element(new(big.Int).Mul(params.B, big.NewInt(3)))
+
+	return c
+}
+
+// Params gets the curve parameters, A is not part of them
+func (c *curve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+// IsOnCurve reports whether (x, y) is a point of the curve
+func (c *curve) IsOnCurve(x, y *big.Int) bool {
+	if x.Sign() < 0 || x.Cmp(c.params.P) >= 0 || y.Sign() < 0 || y.Cmp(c.params.P) >= 0 {
+		return false
+	}
+
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, c.params.P)
+
+	return c.polynomial(x).Cmp(y2) == 0
+}
+
+// Add gets (x1, y1) + (x2, y2)
+func (c *curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	c.generic()
+
+	return c.affine(c.add(c.projective(x1, y1), c.projective(x2, y2)))
+}
+
+// Double gets 2 * (x, y)
+func (c *curve) Double(x, y *big.Int) (*big.Int, *big.Int) {
+	c.generic()
+
+	point := c.projective(x, y)
+
+	return c.affine(c.add(point, point))
+}
+
+// ScalarMult gets k * (x, y), k being a big endian integer
+func (c *curve) ScalarMult(x, y *big.Int, k []byte) (*big.Int, *big.Int) {
+	c.generic()
+
+	return c.affine(c.scalarMult(c.projective(x, y), k))
+}
+
+// ScalarBaseMult gets k * G, k being a big endian integer
+func (c *curve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	c.generic()
+
+	return c.scalarBaseMult(k)
+}
+
+// scalarBaseMult gets k * G without going through the elliptic.Curve methods
+func (c *curve) scalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.affine(c.scalarMult(c.projective(c.params.Gx, c.params.Gy), k))
+}
+
+// generateKey generates a private key whose scalar is uniform in [1, N-1]
+func (c *curve) generateKey(random io.Reader) (*ecdsa.PrivateKey, error) {
+	d, err := c.randomScalar(random)
+	if err != nil {
+		return nil, err
+	}
+
+	scalar := d.Bytes(c.order)
+	x, y := c.scalarBaseMult(scalar)
+
+	return &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: c, X: x, Y: y}, D: new(big.Int).SetBytes(scalar)}, nil
+}
+
+// sign signs the digest into an ASN.1 signature, s = k⁻¹(e + rd) mod N being computed modulo N with
+// k⁻¹ = k^(N-2), the private key scalar being in [1, N-1]
+func (c *curve) sign(random io.Reader, privateKey *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	d, err := bigmod.NewNat().SetBytes(privateKey.D.FillBytes(make([]byte, c.order.Size())), c.order)
+	if err != nil {
+		return nil, err
+	}
+
+	e := c.scalar(c.hashToInt(digest))
+
+	for {
+		k, err := c.randomScalar(random)
+		if err != nil {
+			return nil, err
+		}
+
+		x, _ := c.scalarBaseMult(k.Bytes(c.order))
+		r := x.Mod(x, c.params.N)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		kInv := bigmod.NewNat().Exp(k, c.nMinus2, c.order)
+		s := c.scalar(r).Mul(d, c.order).Add(e, c.order).Mul(kInv, c.order)
+		if s.IsZero() == 1 {
+			continue
+		}
+
+		return asn1.Marshal(signature{R: r, S: new(big.Int).SetBytes(s.Bytes(c.order))})
+	}
+}
+
+// randomScalar gets a scalar uniform in [1, N-1] by rejection sampling, only the rejected
+// candidates being compared in variable time
+func (c *curve) randomScalar(random io.Reader) (*bigmod.Nat, error) {
+	buf := make([]byte, c.order.Size())
+	for {
+		if _, err := io.ReadFull(random, buf); err != nil {
+			return nil, err
+		}
+
+		buf[0] &= 0xff >> (len(buf)*8 - c.order.BitLen())
+
+		k, err := bigmod.NewNat().SetBytes(buf, c.order)
+		if err == nil && k.IsZero() == 0 {
+			return k, nil
+		}
+	}
+}
+
+// hashToInt converts the leftmost bits of the digest, as many as the order has, into an integer
+// modulo N, as crypto/ecdsa does
+func (c *curve) hashToInt(digest []byte) *big.Int {
+	size := c.order.Size()
+	if len(digest) > size {
+		digest = digest[:size]
+	}
+
+	e := new(big.Int).SetBytes(digest)
+	if excess := len(digest)*8 - c.order.BitLen(); excess > 0 {
+		e.Rsh(e, uint(excess))
+	}
+
+	return e.Mod(e, c.params.N)
+}
+
+// scalar converts an integer in [0, N) into a scalar element
+func (c *curve) scalar(v *big.Int) *bigmod.Nat {
+	n, _ := bigmod.NewNat().SetBytes(v.FillBytes(make([]byte, c.order.Size())), c.order)
+
+	return n
+}
+
+// polynomial gets x³ + ax + b
+func (c *curve) polynomial(x *big.Int) *big.Int {
+	x3 := new(big.Int).Mul(x, x)
+	x3.Mul(x3, x)
+
+	ax := new(big.Int).Mul(c.a, x)
+
+	x3.Add(x3, ax)
+	x3.Add(x3, c.params.B)
+
+	return x3.Mod(x3, c.params.P)
+}
+
+// generic calls the test hook of the elliptic.Curve methods
+func (c *curve) generic() {
+	if genericHook != nil {
+		genericHook()
+	}
+}
+
+// scalarMult gets k * point with a Montgomery ladder, the sequence of operations only depending on
+// the length of k
+func (c *curve) scalarMult(point *projective, k []byte) *projective {
+	r0, r1 := c.infinity(), point
+
+	for _, b := range k {
+		for bit := 7; bit >= 0; bit-- {
+			choice := uint(b>>uint(bit)) & 1
+
+			swap(r0, r1, choice)
+			r1 = c.add(r0, r1)
+			r0 = c.add(r0, r0)
+			swap(r0, r1, choice)
+		}
+	}
+
+	return r0
+}
+
+// add gets point1 + point2 with the complete formulas of Renes, Costello and Batina (algorithm 1),
+// which also hold for doublings and the point at infinity
+func (c *curve) add(point1, point2 *projective) *projective {
+	t0 := c.mul(point1.x, point2.x)
+	t1 := c.mul(point1.y, point2.y)
+	t2 := c.mul(point1.z, point2.z)
+
+	t3 := c.mul(c.sum(point1.x, point1.y), c.sum(point2.x, point2.y))
+	t3 = c.sub(t3, c.sum(t0, t1))
+
+	t4 := c.mul(c.sum(point1.x, point1.z), c.sum(point2.x, point2.z))
+	t4 = c.sub(t4, c.sum(t0, t2))
+
+	t5 := c.mul(c.sum(point1.y, point1.z), c.sum(point2.y, point2.z))
+	t5 = c.sub(t5, c.sum(t1, t2))
+
+	z3 := c.sum(c.mul(c.fieldA, t4), c.mul(c.fieldB3, t2))
+	x3 := c.sub(t1, z3)
+	z3 = c.sum(t1, z3)
+	y3 := c.mul(x3, z3)
+
+	t1 = c.sum(c.sum(t0, t0), t0)
+	t2 = c.mul(c.fieldA, t2)
+	t4 = c.mul(c.fieldB3, t4)
+	t1 = c.sum(t1, t2)
+	t2 = c.mul(c.fieldA, c.sub(t0, t2))
+	t4 = c.sum(t4, t2)
+
+	y3 = c.sum(y3, c.mul(t1, t4))
+	x3 = c.sub(c.mul(x3, t3), c.mul(t5, t4))
+	z3 = c.sum(c.mul(t5, z3), c.mul(t3, t1))
+
+	return &projective{x: x3, y: y3, z: z3}
+}
+
+// projective converts an affine point, (0, 0) being the point at infinity
+func (c *curve) projective(x, y *big.Int) *projective {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return c.infinity()
+	}
+
+	return &projective{x: c.element(x), y: c.element(y), z: c.element(big.NewInt(1))}
+}
+
+// infinity gets the point at infinity
+func (c *curve) infinity() *projective {
+	return &projective{x: c.element(new(big.Int)), y: c.element(big.NewInt(1)), z: c.element(new(big.Int))}
+}
+
+// affine converts a projective point, the point at infinity being (0, 0) as 0^(P-2) = 0
+func (c *curve) affine(point *projective) (*big.Int, *big.Int) {
+	zInv := bigmod.NewNat().Exp(point.z, c.pMinus2, c.field)
+
+	x := c.mul(point.x, zInv).Bytes(c.field)
+	y := c.mul(point.y, zInv).Bytes(c.field)
+
+	return new(big.Int).SetBytes(x), new(big.Int).SetBytes(y)
+}
+
+// element converts an integer into a field element, reducing it modulo P
+func (c *curve) element(v *big.Int) *bigmod.Nat {
+	n, _ := bigmod.NewNat().SetBytes(new(big.Int).Mod(v, c.params.P).FillBytes(make([]byte, c.field.Size())), c.field)
+
+	return n
+}
+
+// mul gets x * y modulo P
+func (c *curve) mul(x, y *bigmod.Nat) *bigmod.Nat {
+	return c.clone(x).Mul(y, c.field)
+}
+
+// sum gets x + y modulo P
+func (c *curve) sum(x, y *bigmod.Nat) *bigmod.Nat {
+	return c.clone(x).Add(y, c.field)
+}
+
+// sub gets x - y modulo P
+func (c *curve) sub(x, y *bigmod.Nat) *bigmod.Nat {
+	return c.clone(x).Sub(y, c.field)
+}
+
+// clone copies a field element
+func (c *curve) clone(x *bigmod.Nat) *bigmod.Nat {
+	y := bigmod.NewNat().ExpandFor(c.field)
+	copy(y.Bits(), x.Bits())
+
+	return y
+}
+
+// swap exchanges the two points when choice is 1 and keeps them when it is 0, masking their limbs
+// instead of branching
+func swap(point1, point2 *projective, choice uint) {
+	mask := -choice
+
+	for _, pair := range [][2]*bigmod.Nat{{point1.x, point2.x}, {point1.y, point2.y}, {point1.z, point2.z}} {
+		x, y := pair[0].Bits(), pair[1].Bits()
+		for i := range x {
+			t := mask & (x[i] ^ y[i])
+			x[i] ^= t
+			y[i] ^= t
+		}
+	}
+}
+
+// scalarBaseMult gets k * G in constant time, the curves of the package not going through their
+// elliptic.Curve methods
+func scalarBaseMult(ec elliptic.Curve, k []byte) (*big.Int, *big.Int) {
+	if c, ok := ec.(*curve); ok {
+		return c.scalarBaseMult(k)
+	}
+
+	return ec.ScalarBaseMult(k)
+}
+
+// fromHex parses a hexadecimal curve parameter
+func fromHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("ecdsa: invalid curve parameter " + s)
+	}
+
+	return n
+}
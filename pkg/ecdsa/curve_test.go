@@ -0,0 +1,113 @@
+package ecdsa
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurveMatchesP256(t *testing.T) {
+	params := elliptic.P256().Params()
+	generic := fromParams(params, new(big.Int).Sub(params.P, big.NewInt(3)))
+
+	for i := 0; i < 8; i++ {
+		k := make([]byte, 32)
+		_, err := rand.Read(k)
+		assert.Nil(t, err)
+
+		x, y := elliptic.P256().ScalarBaseMult(k)
+		gx, gy := generic.ScalarBaseMult(k)
+		assert.Equal(t, x, gx)
+		assert.Equal(t, y, gy)
+		assert.True(t, generic.IsOnCurve(gx, gy))
+
+		x2, y2 := elliptic.P256().Double(x, y)
+		gx2, gy2 := generic.Double(gx, gy)
+		assert.Equal(t, x2, gx2)
+		assert.Equal(t, y2, gy2)
+
+		x3, y3 := elliptic.P256().Add(x, y, params.Gx, params.Gy)
+		gx3, gy3 := generic.Add(gx, gy, params.Gx, params.Gy)
+		assert.Equal(t, x3, gx3)
+		assert.Equal(t, y3, gy3)
+
+		x4, y4 := elliptic.P256().ScalarMult(x, y, k[:i+1])
+		gx4, gy4 := generic.ScalarMult(gx, gy, k[:i+1])
+		assert.Equal(t, x4, gx4)
+		assert.Equal(t, y4, gy4)
+	}
+}
+
+func TestCurveSecp256k1(t *testing.T) {
+	params := secp256k1.Params()
+
+	assert.True(t, secp256k1.IsOnCurve(params.Gx, params.Gy))
+	assert.False(t, secp256k1.IsOnCurve(params.Gx, new(big.Int).Add(params.Gy, big.NewInt(1))))
+
+	// 2G and 3G from the SEC 2 test vectors
+	x, y := secp256k1.ScalarBaseMult([]byte{2})
+	assert.Equal(t, fromHex("c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5"), x)
+	assert.Equal(t, fromHex("1ae168fea63dc339a3c58419466ceaeef7f632653266d0e1236431a950cfe52a"), y)
+
+	x, y = secp256k1.Add(x, y, params.Gx, params.Gy)
+	assert.Equal(t, fromHex("f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9"), x)
+	assert.Equal(t, fromHex("388f7b0f632de8140fe337e62a37f3566500a99934c2231b6cb9fd7584b8e672"), y)
+
+	x, y = secp256k1.ScalarBaseMult(params.N.Bytes())
+	assert.Equal(t, 0, x.Sign())
+	assert.Equal(t, 0, y.Sign())
+
+	x, y = secp256k1.Add(params.Gx, params.Gy, params.Gx, new(big.Int).Sub(params.P, params.Gy))
+	assert.Equal(t, 0, x.Sign())
+	assert.Equal(t, 0, y.Sign())
+}
+
+func TestCurveBrainpool(t *testing.T) {
+	for _, ec := range []*curve{brainpoolP256r1, brainpoolP384r1, brainpoolP512r1} {
+		t.Run(ec.Params().Name, func(t *testing.T) {
+			params := ec.Params()
+
+			assert.True(t, params.P.ProbablyPrime(20))
+			assert.True(t, params.N.ProbablyPrime(20))
+			assert.Equal(t, params.BitSize, params.P.BitLen())
+			assert.True(t, ec.IsOnCurve(params.Gx, params.Gy))
+
+			x, y := ec.ScalarBaseMult(params.N.Bytes())
+			assert.Equal(t, 0, x.Sign())
+			assert.Equal(t, 0, y.Sign())
+
+			k := make([]byte, params.BitSize/8)
+			_, err := rand.Read(k)
+			assert.Nil(t, err)
+
+			x, y = ec.ScalarBaseMult(k)
+			assert.True(t, ec.IsOnCurve(x, y))
+			assert.Equal(t, y, decompressY(ec, x, y.Bit(0) == 1))
+
+			// k2 * (k * G) = (k * k2 mod N) * G
+			k2 := make([]byte, params.BitSize/8)
+			_, err = rand.Read(k2)
+			assert.Nil(t, err)
+
+			x2, y2 := ec.ScalarMult(x, y, k2)
+			product := new(big.Int).Mul(new(big.Int).SetBytes(k), new(big.Int).SetBytes(k2))
+			x3, y3 := ec.ScalarBaseMult(product.Mod(product, params.N).Bytes())
+			assert.Equal(t, x3, x2)
+			assert.Equal(t, y3, y2)
+
+			// 3G = G + 2G
+			x, y = ec.Add(params.Gx, params.Gy, params.Gx, params.Gy)
+			dx, dy := ec.Double(params.Gx, params.Gy)
+			assert.Equal(t, dx, x)
+			assert.Equal(t, dy, y)
+
+			x, y = ec.Add(x, y, params.Gx, params.Gy)
+			x3, y3 = ec.ScalarBaseMult([]byte{3})
+			assert.Equal(t, x3, x)
+			assert.Equal(t, y3, y)
+		})
+	}
+}
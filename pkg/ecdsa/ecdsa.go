@@ -4,14 +4,55 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
 	"encoding/pem"
+	"math/big"
 
 	c "github.com/ELares/crypto/pkg"
 	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/policy"
 	jose "gopkg.in/square/go-jose.v2"
 )
 
+const (
+	// ES256K jwk signature algorithm of secp256k1 keys (RFC 8812)
+	ES256K = jose.SignatureAlgorithm("ES256K")
+
+	// ESB256 jwk signature algorithm of brainpoolP256r1 keys (RFC 9864)
+	ESB256 = jose.SignatureAlgorithm("ESB256")
+
+	// ESB384 jwk signature algorithm of brainpoolP384r1 keys (RFC 9864)
+	ESB384 = jose.SignatureAlgorithm("ESB384")
+
+	// ESB512 jwk signature algorithm of brainpoolP512r1 keys (RFC 9864)
+	ESB512 = jose.SignatureAlgorithm("ESB512")
+)
+
+var (
+	// curveAlgorithms jwk signature algorithm of each curve, P-224 has none
+	curveAlgorithms = map[string]jose.SignatureAlgorithm{
+		"P-256":     jose.ES256,
+		"P-384":     jose.ES384,
+		"P-521":     jose.ES512,
+		"secp256k1": ES256K,
+
+		"brainpoolP256r1": ESB256,
+		"brainpoolP384r1": ESB384,
+		"brainpoolP512r1": ESB512,
+	}
+
+	// jwkCurves jwk crv of the curves go-jose does not know
+	jwkCurves = map[string]string{
+		"secp256k1": "secp256k1",
+
+		"brainpoolP256r1": "BP-256",
+		"brainpoolP384r1": "BP-384",
+		"brainpoolP512r1": "BP-512",
+	}
+)
+
 type (
 	// IECDSA interface for methods to generate ecdsa keys and conversion to PEM format
 	IECDSA interface {
@@ -19,38 +60,95 @@ type (
 		P384() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error)
 		P256() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error)
 		P224() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error)
+		K256() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error)
+		BP256() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error)
+		BP384() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error)
+		BP512() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error)
 
 		P521PrivateKey() (*ecdsa.PrivateKey, error)
 		P384PrivateKey() (*ecdsa.PrivateKey, error)
 		P256PrivateKey() (*ecdsa.PrivateKey, error)
 		P224PrivateKey() (*ecdsa.PrivateKey, error)
+		K256PrivateKey() (*ecdsa.PrivateKey, error)
+		BP256PrivateKey() (*ecdsa.PrivateKey, error)
+		BP384PrivateKey() (*ecdsa.PrivateKey, error)
+		BP512PrivateKey() (*ecdsa.PrivateKey, error)
 
 		P521PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.PublicPEM, error)
 		P384PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.PublicPEM, error)
 		P256PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.PublicPEM, error)
 		P224PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.PublicPEM, error)
+		K256PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.PublicPEM, error)
+		BP256PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.PublicPEM, error)
+		BP384PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.PublicPEM, error)
+		BP512PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.PublicPEM, error)
 
 		P521PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error)
 		P384PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error)
 		P256PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error)
 		P224PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error)
+		K256PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error)
+		BP256PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error)
+		BP384PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error)
+		BP512PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error)
 
 		FromPEMPrivateKey(p.PrivatePEM) (*ecdsa.PrivateKey, error)
 		FromPEMPublicKey(p.PublicPEM) (*ecdsa.PublicKey, error)
 		FromPEM(p.PrivatePEM, p.PublicPEM) (*ecdsa.PrivateKey, *ecdsa.PublicKey, error)
+		FromPEMValidated(p.PrivatePEM, p.PublicPEM) (*ecdsa.PrivateKey, *ecdsa.PublicKey, error)
+
+		ValidatePrivateKey(*ecdsa.PrivateKey) error
+		ValidatePublicKey(*ecdsa.PublicKey) error
 
 		ToPEMPrivateKey(*ecdsa.PrivateKey) (p.PrivatePEM, error)
+		ToPEMPKCS8PrivateKey(*ecdsa.PrivateKey) (p.PrivatePEM, error)
 		ToPEMPublicKey(*ecdsa.PublicKey) (p.PublicPEM, error)
 		ToPEM(*ecdsa.PrivateKey, *ecdsa.PublicKey) (p.PrivatePEM, p.PublicPEM, error)
 
 		ToJWKES512(publicKey *ecdsa.PublicKey, id string) ([]byte, error)
 		ToJWKES384(publicKey *ecdsa.PublicKey, id string) ([]byte, error)
 		ToJWKES256(publicKey *ecdsa.PublicKey, id string) ([]byte, error)
+		ToJWKES256K(publicKey *ecdsa.PublicKey, id string) ([]byte, error)
+		ToJWKESB256(publicKey *ecdsa.PublicKey, id string) ([]byte, error)
+		ToJWKESB384(publicKey *ecdsa.PublicKey, id string) ([]byte, error)
+		ToJWKESB512(publicKey *ecdsa.PublicKey, id string) ([]byte, error)
 		ToJWK(publicKey *ecdsa.PublicKey, id string, algo jose.SignatureAlgorithm) ([]byte, error)
+		ToJWKAuto(publicKey *ecdsa.PublicKey, id string) ([]byte, error)
+
+		ToCompressedPoint(*ecdsa.PublicKey) ([]byte, error)
+		FromCompressedPoint(elliptic.Curve, []byte) (*ecdsa.PublicKey, error)
+		ToUncompressedPoint(*ecdsa.PublicKey) ([]byte, error)
+		FromUncompressedPoint(elliptic.Curve, []byte) (*ecdsa.PublicKey, error)
+		ToRawPoint(*ecdsa.PublicKey) ([]byte, error)
+		FromRawPoint(elliptic.Curve, []byte) (*ecdsa.PublicKey, error)
+		ToRawPrivateKey(*ecdsa.PrivateKey) ([]byte, error)
+		FromRawPrivateKey(elliptic.Curve, []byte) (*ecdsa.PrivateKey, error)
+
+		Sign(privateKey *ecdsa.PrivateKey, digest []byte) ([]byte, error)
+		Verify(publicKey *ecdsa.PublicKey, digest, signature []byte) bool
+		NormalizeLowS(curve elliptic.Curve, signature []byte) ([]byte, error)
 	}
 
 	// ECDSA struct to implement the IECDSA methods
-	ECDSA struct{}
+	ECDSA struct {
+		policy *policy.Policy
+	}
+
+	// signature ASN.1 ECDSA signature
+	signature struct {
+		R, S *big.Int
+	}
+
+	// jwk json web key of the curves go-jose does not know, fields in go-jose order
+	jwk struct {
+		Use       string `json:"use,omitempty"`
+		KeyType   string `json:"kty"`
+		KeyID     string `json:"kid,omitempty"`
+		Curve     string `json:"crv"`
+		Algorithm string `json:"alg,omitempty"`
+		X         string `json:"x"`
+		Y         string `json:"y"`
+	}
 )
 
 // NewECDSA get a new ECDSA pointer
@@ -58,6 +156,12 @@ func NewECDSA() IECDSA {
 	return &ECDSA{}
 }
 
+// NewECDSAWithPolicy get a new ECDSA pointer enforcing the policy on generated, parsed and exported
+// keys
+func NewECDSAWithPolicy(policy *policy.Policy) IECDSA {
+	return &ECDSA{policy: policy}
+}
+
 // P521 generates new ECDSA P521 private/public keys
 func (e *ECDSA) P521() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
 	return e.generateKeys(e.P521PrivateKey)
@@ -78,6 +182,26 @@ func (e *ECDSA) P224() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
 	return e.generateKeys(e.P224PrivateKey)
 }
 
+// K256 generates new ECDSA secp256k1 private/public keys
+func (e *ECDSA) K256() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
+	return e.generateKeys(e.K256PrivateKey)
+}
+
+// BP256 generates new ECDSA brainpoolP256r1 private/public keys
+func (e *ECDSA) BP256() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
+	return e.generateKeys(e.BP256PrivateKey)
+}
+
+// BP384 generates new ECDSA brainpoolP384r1 private/public keys
+func (e *ECDSA) BP384() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
+	return e.generateKeys(e.BP384PrivateKey)
+}
+
+// BP512 generates new ECDSA brainpoolP512r1 private/public keys
+func (e *ECDSA) BP512() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
+	return e.generateKeys(e.BP512PrivateKey)
+}
+
 // P521PrivateKey generates a new ECDSA P521 private key
 func (e *ECDSA) P521PrivateKey() (*ecdsa.PrivateKey, error) {
 	return e.generateKey(elliptic.P521())
@@ -98,6 +222,26 @@ func (e *ECDSA) P224PrivateKey() (*ecdsa.PrivateKey, error) {
 	return e.generateKey(elliptic.P224())
 }
 
+// K256PrivateKey generates a new ECDSA secp256k1 private key
+func (e *ECDSA) K256PrivateKey() (*ecdsa.PrivateKey, error) {
+	return e.generateKey(secp256k1)
+}
+
+// BP256PrivateKey generates a new ECDSA brainpoolP256r1 private key
+func (e *ECDSA) BP256PrivateKey() (*ecdsa.PrivateKey, error) {
+	return e.generateKey(brainpoolP256r1)
+}
+
+// BP384PrivateKey generates a new ECDSA brainpoolP384r1 private key
+func (e *ECDSA) BP384PrivateKey() (*ecdsa.PrivateKey, error) {
+	return e.generateKey(brainpoolP384r1)
+}
+
+// BP512PrivateKey generates a new ECDSA brainpoolP512r1 private key
+func (e *ECDSA) BP512PrivateKey() (*ecdsa.PrivateKey, error) {
+	return e.generateKey(brainpoolP512r1)
+}
+
 // P521PEM generates new ECDSA P521 private/public pem keys
 func (e *ECDSA) P521PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.PublicPEM, error) {
 	return e.generatePEMKeys(e.P521PEMPrivateKey)
@@ -118,6 +262,26 @@ func (e *ECDSA) P224PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.
 	return e.generatePEMKeys(e.P224PEMPrivateKey)
 }
 
+// K256PEM generates new ECDSA secp256k1 private/public pem keys
+func (e *ECDSA) K256PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.PublicPEM, error) {
+	return e.generatePEMKeys(e.K256PEMPrivateKey)
+}
+
+// BP256PEM generates new ECDSA brainpoolP256r1 private/public pem keys
+func (e *ECDSA) BP256PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.PublicPEM, error) {
+	return e.generatePEMKeys(e.BP256PEMPrivateKey)
+}
+
+// BP384PEM generates new ECDSA brainpoolP384r1 private/public pem keys
+func (e *ECDSA) BP384PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.PublicPEM, error) {
+	return e.generatePEMKeys(e.BP384PEMPrivateKey)
+}
+
+// BP512PEM generates new ECDSA brainpoolP512r1 private/public pem keys
+func (e *ECDSA) BP512PEM() (*ecdsa.PrivateKey, p.PrivatePEM, *ecdsa.PublicKey, p.PublicPEM, error) {
+	return e.generatePEMKeys(e.BP512PEMPrivateKey)
+}
+
 // P521PEMPrivateKey generates a new ECDSA P521 private PEM key
 func (e *ECDSA) P521PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error) {
 	return e.generatePrivatePEMKey(e.P521PrivateKey)
@@ -138,7 +302,28 @@ func (e *ECDSA) P224PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error) {
 	return e.generatePrivatePEMKey(e.P224PrivateKey)
 }
 
-// FromPEMPrivateKey takes a private pem key and converts it into a ecdsa private key
+// K256PEMPrivateKey generates a new ECDSA secp256k1 private PEM key
+func (e *ECDSA) K256PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error) {
+	return e.generatePrivatePEMKey(e.K256PrivateKey)
+}
+
+// BP256PEMPrivateKey generates a new ECDSA brainpoolP256r1 private PEM key
+func (e *ECDSA) BP256PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error) {
+	return e.generatePrivatePEMKey(e.BP256PrivateKey)
+}
+
+// BP384PEMPrivateKey generates a new ECDSA brainpoolP384r1 private PEM key
+func (e *ECDSA) BP384PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error) {
+	return e.generatePrivatePEMKey(e.BP384PrivateKey)
+}
+
+// BP512PEMPrivateKey generates a new ECDSA brainpoolP512r1 private PEM key
+func (e *ECDSA) BP512PEMPrivateKey() (*ecdsa.PrivateKey, p.PrivatePEM, error) {
+	return e.generatePrivatePEMKey(e.BP512PrivateKey)
+}
+
+// FromPEMPrivateKey takes a private pem key, SEC 1 or PKCS#8, and converts it into a ecdsa private
+// key
 func (e *ECDSA) FromPEMPrivateKey(privatePEM p.PrivatePEM) (*ecdsa.PrivateKey, error) {
 	block, _ := pem.Decode(privatePEM)
 
@@ -146,7 +331,20 @@ func (e *ECDSA) FromPEMPrivateKey(privatePEM p.PrivatePEM) (*ecdsa.PrivateKey, e
 		return nil, c.ErrDecodePEMPrivateKey
 	}
 
-	return x509.ParseECPrivateKey(block.Bytes)
+	if err := e.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := parsePrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.policy.CheckPublicKey(&privateKey.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return privateKey, nil
 }
 
 // FromPEMPublicKey takes a public pem key and converts it into a ecdsa public key
@@ -157,12 +355,25 @@ func (e *ECDSA) FromPEMPublicKey(publicPEM p.PublicPEM) (*ecdsa.PublicKey, error
 		return nil, c.ErrDecodePEMPublicKey
 	}
 
-	genericPublicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err := e.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	genericPublicKey, err := parsePublicKey(block.Bytes)
 	if err != nil {
 		return nil, err
 	}
 
-	return genericPublicKey.(*ecdsa.PublicKey), nil
+	if err := e.policy.CheckPublicKey(genericPublicKey); err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := genericPublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, c.ErrUnsupportedPublicKey
+	}
+
+	return publicKey, nil
 }
 
 // FromPEM takes pem keys and converts them into a ecdsa keys
@@ -180,13 +391,115 @@ func (e *ECDSA) FromPEM(privatePEM p.PrivatePEM, publicPEM p.PublicPEM) (*ecdsa.
 	return privateKey, publicKey, nil
 }
 
+// FromPEMValidated takes pem keys and converts them into validated ecdsa keys, failing when the
+// public key is not the public half of the private key
+func (e *ECDSA) FromPEMValidated(privatePEM p.PrivatePEM, publicPEM p.PublicPEM) (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
+	privateKey, publicKey, err := e.FromPEM(privatePEM, publicPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := e.ValidatePrivateKey(privateKey); err != nil {
+		return nil, nil, err
+	}
+
+	if !privateKey.PublicKey.Equal(publicKey) {
+		return nil, nil, c.ErrKeyMismatch
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// ValidatePrivateKey checks the private key scalar is in [1, N-1] and its public half is D*G
+func (e *ECDSA) ValidatePrivateKey(privateKey *ecdsa.PrivateKey) error {
+	if privateKey == nil {
+		return c.ErrNilPrivateKey
+	}
+
+	if privateKey.D == nil {
+		return c.ErrNilPrivateKeyD
+	}
+
+	if err := e.ValidatePublicKey(&privateKey.PublicKey); err != nil {
+		return err
+	}
+
+	n := privateKey.Curve.Params().N
+	if privateKey.D.Sign() <= 0 || privateKey.D.Cmp(n) >= 0 {
+		return c.ErrScalarOutOfRange
+	}
+
+	x, y := scalarBaseMult(privateKey.Curve, privateKey.D.FillBytes(make([]byte, (n.BitLen()+7)/8)))
+	if x.Cmp(privateKey.X) != 0 || y.Cmp(privateKey.Y) != 0 {
+		return c.ErrKeyMismatch
+	}
+
+	return nil
+}
+
+// ValidatePublicKey checks the public key point is on its curve and is not the point at infinity
+func (e *ECDSA) ValidatePublicKey(publicKey *ecdsa.PublicKey) error {
+	if publicKey == nil {
+		return c.ErrNilPublicKey
+	}
+
+	if publicKey.X == nil {
+		return c.ErrNilPublicKeyX
+	}
+
+	if publicKey.Y == nil {
+		return c.ErrNilPublicKeyY
+	}
+
+	if publicKey.Curve == nil {
+		return c.ErrNilPublicKeyCurve
+	}
+
+	if publicKey.X.Sign() == 0 && publicKey.Y.Sign() == 0 {
+		return c.ErrPointAtInfinity
+	}
+
+	prime := publicKey.Curve.Params().P
+	if publicKey.X.Sign() < 0 || publicKey.X.Cmp(prime) >= 0 || publicKey.Y.Sign() < 0 || publicKey.Y.Cmp(prime) >= 0 {
+		return c.ErrPointNotOnCurve
+	}
+
+	if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+		return c.ErrPointNotOnCurve
+	}
+
+	return nil
+}
+
 // ToPEMPrivateKey converts a ECDSA private key into a private PEM key
 func (e *ECDSA) ToPEMPrivateKey(privateKey *ecdsa.PrivateKey) (p.PrivatePEM, error) {
 	if privateKey == nil {
 		return nil, c.ErrNilPrivateKey
 	}
 
-	x509Encoded, err := x509.MarshalECPrivateKey(privateKey)
+	if err := e.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	x509Encoded, err := marshalSEC1PrivateKey(privateKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: x509Encoded}), nil
+}
+
+// ToPEMPKCS8PrivateKey converts a ECDSA private key into a PKCS#8 private PEM key
+func (e *ECDSA) ToPEMPKCS8PrivateKey(privateKey *ecdsa.PrivateKey) (p.PrivatePEM, error) {
+	if privateKey == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if err := e.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	x509Encoded, err := marshalPKCS8PrivateKey(privateKey)
 	if err != nil {
 		return nil, err
 	}
@@ -212,7 +525,11 @@ func (e *ECDSA) ToPEMPublicKey(publicKey *ecdsa.PublicKey) (p.PublicPEM, error)
 		return nil, c.ErrNilPublicKeyCurve
 	}
 
-	x509EncodedPub, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err := e.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	x509EncodedPub, err := marshalPKIXPublicKey(publicKey)
 	if err != nil {
 		return nil, err
 	}
@@ -250,8 +567,55 @@ func (e *ECDSA) ToJWKES256(publicKey *ecdsa.PublicKey, id string) ([]byte, error
 	return e.ToJWK(publicKey, id, jose.ES256)
 }
 
-// ToJWK converts ECDSA public key into a jwk
+// ToJWKES256K converts ECDSA secp256k1 public key into a jwk ES256K
+func (e *ECDSA) ToJWKES256K(publicKey *ecdsa.PublicKey, id string) ([]byte, error) {
+	return e.ToJWK(publicKey, id, ES256K)
+}
+
+// ToJWKESB256 converts ECDSA brainpoolP256r1 public key into a jwk ESB256
+func (e *ECDSA) ToJWKESB256(publicKey *ecdsa.PublicKey, id string) ([]byte, error) {
+	return e.ToJWK(publicKey, id, ESB256)
+}
+
+// ToJWKESB384 converts ECDSA brainpoolP384r1 public key into a jwk ESB384
+func (e *ECDSA) ToJWKESB384(publicKey *ecdsa.PublicKey, id string) ([]byte, error) {
+	return e.ToJWK(publicKey, id, ESB384)
+}
+
+// ToJWKESB512 converts ECDSA brainpoolP512r1 public key into a jwk ESB512
+func (e *ECDSA) ToJWKESB512(publicKey *ecdsa.PublicKey, id string) ([]byte, error) {
+	return e.ToJWK(publicKey, id, ESB512)
+}
+
+// ToJWK converts ECDSA public key into a jwk, the algorithm must be the one of the key curve
 func (e *ECDSA) ToJWK(publicKey *ecdsa.PublicKey, id string, algo jose.SignatureAlgorithm) ([]byte, error) {
+	expected, err := e.jwkAlgorithm(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if algo != expected {
+		return nil, c.ErrAlgorithmMismatch
+	}
+
+	if err := e.policy.CheckJWK(publicKey, algo); err != nil {
+		return nil, err
+	}
+
+	if crv, ok := jwkCurves[publicKey.Curve.Params().Name]; ok {
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+
+		return json.Marshal(jwk{
+			Use:       c.SIG,
+			KeyType:   "EC",
+			KeyID:     id,
+			Curve:     crv,
+			Algorithm: string(algo),
+			X:         base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size))),
+			Y:         base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size))),
+		})
+	}
+
 	jwk := jose.JSONWebKey{
 		Use:       c.SIG,
 		Algorithm: string(algo),
@@ -262,11 +626,233 @@ func (e *ECDSA) ToJWK(publicKey *ecdsa.PublicKey, id string, algo jose.Signature
 	return jwk.MarshalJSON()
 }
 
-// generateKey wrapper for ecdsa.GenerateKey() for unit-test mocking purposes
+// ToJWKAuto converts ECDSA public key into a jwk using the algorithm of the key curve
+func (e *ECDSA) ToJWKAuto(publicKey *ecdsa.PublicKey, id string) ([]byte, error) {
+	algo, err := e.jwkAlgorithm(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.ToJWK(publicKey, id, algo)
+}
+
+// ToCompressedPoint converts a ECDSA public key into the SEC 1 compressed point 0x02/0x03 || X
+func (e *ECDSA) ToCompressedPoint(publicKey *ecdsa.PublicKey) ([]byte, error) {
+	if err := e.ValidatePublicKey(publicKey); err != nil {
+		return nil, err
+	}
+
+	return marshalCompressedPoint(publicKey.Curve, publicKey.X, publicKey.Y), nil
+}
+
+// FromCompressedPoint takes a SEC 1 compressed point and converts it into a ECDSA public key on the
+// curve
+func (e *ECDSA) FromCompressedPoint(curve elliptic.Curve, data []byte) (*ecdsa.PublicKey, error) {
+	if curve == nil {
+		return nil, c.ErrNilPublicKeyCurve
+	}
+
+	if len(data) == 0 || data[0] == 4 {
+		return nil, c.ErrInvalidPoint
+	}
+
+	x, y, err := unmarshalPoint(curve, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// ToUncompressedPoint converts a ECDSA public key into the SEC 1 uncompressed point 0x04 || X || Y
+func (e *ECDSA) ToUncompressedPoint(publicKey *ecdsa.PublicKey) ([]byte, error) {
+	if err := e.ValidatePublicKey(publicKey); err != nil {
+		return nil, err
+	}
+
+	return marshalPoint(publicKey.Curve, publicKey.X, publicKey.Y), nil
+}
+
+// FromUncompressedPoint takes a SEC 1 uncompressed point and converts it into a ECDSA public key on
+// the curve
+func (e *ECDSA) FromUncompressedPoint(curve elliptic.Curve, data []byte) (*ecdsa.PublicKey, error) {
+	if curve == nil {
+		return nil, c.ErrNilPublicKeyCurve
+	}
+
+	if len(data) == 0 || data[0] != 4 {
+		return nil, c.ErrInvalidPoint
+	}
+
+	x, y, err := unmarshalPoint(curve, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// ToRawPoint converts a ECDSA public key into the raw X || Y point, without the SEC 1 prefix
+func (e *ECDSA) ToRawPoint(publicKey *ecdsa.PublicKey) ([]byte, error) {
+	if err := e.ValidatePublicKey(publicKey); err != nil {
+		return nil, err
+	}
+
+	return marshalPoint(publicKey.Curve, publicKey.X, publicKey.Y)[1:], nil
+}
+
+// FromRawPoint takes a raw X || Y point and converts it into a ECDSA public key on the curve
+func (e *ECDSA) FromRawPoint(curve elliptic.Curve, data []byte) (*ecdsa.PublicKey, error) {
+	if curve == nil {
+		return nil, c.ErrNilPublicKeyCurve
+	}
+
+	if len(data) != 2*((curve.Params().BitSize+7)/8) {
+		return nil, c.ErrInvalidPoint
+	}
+
+	return e.FromUncompressedPoint(curve, append([]byte{4}, data...))
+}
+
+// ToRawPrivateKey converts a ECDSA private key into its big-endian scalar, padded to the size of the
+// curve order
+func (e *ECDSA) ToRawPrivateKey(privateKey *ecdsa.PrivateKey) ([]byte, error) {
+	if err := e.ValidatePrivateKey(privateKey); err != nil {
+		return nil, err
+	}
+
+	return privateKey.D.FillBytes(make([]byte, (privateKey.Curve.Params().N.BitLen()+7)/8)), nil
+}
+
+// FromRawPrivateKey takes a big-endian scalar of the size of the curve order and converts it into a
+// ECDSA private key on the curve, failing when the scalar is not in [1, N-1]
+func (e *ECDSA) FromRawPrivateKey(curve elliptic.Curve, data []byte) (*ecdsa.PrivateKey, error) {
+	if curve == nil {
+		return nil, c.ErrNilPublicKeyCurve
+	}
+
+	n := curve.Params().N
+	if len(data) != (n.BitLen()+7)/8 {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	if err := e.policy.CheckCurve(curve); err != nil {
+		return nil, err
+	}
+
+	d := new(big.Int).SetBytes(data)
+	if d.Sign() <= 0 || d.Cmp(n) >= 0 {
+		return nil, c.ErrScalarOutOfRange
+	}
+
+	x, y := scalarBaseMult(curve, data)
+
+	return &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y}, D: d}, nil
+}
+
+// Sign signs the digest into an ASN.1 signature with s normalized to the lower half of the curve
+// order, as required by secp256k1 verifiers; the secp256k1 and brainpool curves are signed in
+// constant time instead of by the generic crypto/ecdsa implementation
+func (e *ECDSA) Sign(privateKey *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	if privateKey == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if err := e.policy.CheckCurve(privateKey.Curve); err != nil {
+		return nil, err
+	}
+
+	sig, err := e.sign(privateKey, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.NormalizeLowS(privateKey.Curve, sig)
+}
+
+// Verify reports whether the ASN.1 signature of the digest is valid, high s signatures included
+func (e *ECDSA) Verify(publicKey *ecdsa.PublicKey, digest, signature []byte) bool {
+	if publicKey == nil || publicKey.Curve == nil {
+		return false
+	}
+
+	return ecdsa.VerifyASN1(publicKey, digest, signature)
+}
+
+// NormalizeLowS replaces s by N - s when s is in the upper half of the curve order, both signatures
+// being valid
+func (e *ECDSA) NormalizeLowS(curve elliptic.Curve, sig []byte) ([]byte, error) {
+	if curve == nil {
+		return nil, c.ErrNilPublicKeyCurve
+	}
+
+	var parsed signature
+	if rest, err := asn1.Unmarshal(sig, &parsed); err != nil || len(rest) != 0 || parsed.R == nil || parsed.S == nil {
+		return nil, c.ErrInvalidSignature
+	}
+
+	n := curve.Params().N
+	if parsed.S.Cmp(new(big.Int).Rsh(n, 1)) <= 0 {
+		return sig, nil
+	}
+
+	parsed.S.Sub(n, parsed.S)
+
+	return asn1.Marshal(parsed)
+}
+
+// jwkAlgorithm gets the jwk signature algorithm of the public key curve
+func (e *ECDSA) jwkAlgorithm(publicKey *ecdsa.PublicKey) (jose.SignatureAlgorithm, error) {
+	if publicKey == nil {
+		return "", c.ErrNilPublicKey
+	}
+
+	if publicKey.Curve == nil {
+		return "", c.ErrNilPublicKeyCurve
+	}
+
+	algo, ok := curveAlgorithms[publicKey.Curve.Params().Name]
+	if !ok {
+		return "", c.ErrNoJWKAlgorithm
+	}
+
+	return algo, nil
+}
+
+// generateKey wrapper for ecdsa.GenerateKey() for unit-test mocking purposes, rejecting curves the
+// policy does not allow; the secp256k1 and brainpool keys are generated in constant time instead of
+// by the generic crypto/ecdsa implementation
 func (e *ECDSA) generateKey(algorithm elliptic.Curve) (*ecdsa.PrivateKey, error) {
+	if err := e.policy.CheckCurve(algorithm); err != nil {
+		return nil, err
+	}
+
+	if custom, ok := algorithm.(*curve); ok {
+		return custom.generateKey(rand.Reader)
+	}
+
 	return ecdsa.GenerateKey(algorithm, rand.Reader)
 }
 
+// sign signs the digest with crypto/ecdsa, or in constant time on the curves of the package once
+// the private key scalar is checked to be in [1, N-1]
+func (e *ECDSA) sign(privateKey *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	custom, ok := privateKey.Curve.(*curve)
+	if !ok {
+		return ecdsa.SignASN1(rand.Reader, privateKey, digest)
+	}
+
+	if privateKey.D == nil {
+		return nil, c.ErrNilPrivateKeyD
+	}
+
+	if privateKey.D.Sign() <= 0 || privateKey.D.Cmp(custom.params.N) >= 0 {
+		return nil, c.ErrScalarOutOfRange
+	}
+
+	return custom.sign(rand.Reader, privateKey, digest)
+}
+
 // generateKeys generates a new ECDSA private/public key given a genKey method
 func (e *ECDSA) generateKeys(genKeyMethod func() (*ecdsa.PrivateKey, error)) (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
 	privateKey, err := genKeyMethod()
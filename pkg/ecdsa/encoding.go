@@ -0,0 +1,321 @@
+package ecdsa
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+
+	c "github.com/ELares/crypto/pkg"
+)
+
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+	// namedCurves curves crypto/x509 does not know and their OIDs
+	namedCurves = []struct {
+		curve elliptic.Curve
+		oid   asn1.ObjectIdentifier
+	}{
+		{secp256k1, asn1.ObjectIdentifier{1, 3, 132, 0, 10}},
+		{brainpoolP256r1, asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 7}},
+		{brainpoolP384r1, asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 11}},
+		{brainpoolP512r1, asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 13}},
+	}
+)
+
+type (
+	// ecPrivateKey SEC 1 ECPrivateKey structure (RFC 5915)
+	ecPrivateKey struct {
+		Version       int
+		PrivateKey    []byte
+		NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+		PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+	}
+
+	// pkcs8 PKCS#8 PrivateKeyInfo structure (RFC 5208)
+	pkcs8 struct {
+		Version    int
+		Algo       pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}
+
+	// publicKeyInfo PKIX SubjectPublicKeyInfo structure (RFC 5280)
+	publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+)
+
+// isNamedCurve reports whether the curve is one of the namedCurves
+func isNamedCurve(curve elliptic.Curve) bool {
+	_, ok := oidFromCurve(curve)
+	return ok
+}
+
+// oidFromCurve gets the OID of one of the namedCurves
+func oidFromCurve(curve elliptic.Curve) (asn1.ObjectIdentifier, bool) {
+	for _, named := range namedCurves {
+		if named.curve == curve {
+			return named.oid, true
+		}
+	}
+
+	return nil, false
+}
+
+// curveFromOID gets one of the namedCurves from its OID
+func curveFromOID(oid asn1.ObjectIdentifier) (elliptic.Curve, bool) {
+	for _, named := range namedCurves {
+		if named.oid.Equal(oid) {
+			return named.curve, true
+		}
+	}
+
+	return nil, false
+}
+
+// marshalSEC1PrivateKey converts a private key into a SEC 1 ECPrivateKey, the curve OID is left out
+// when the key is wrapped into PKCS#8
+func marshalSEC1PrivateKey(privateKey *ecdsa.PrivateKey, withOID bool) ([]byte, error) {
+	if !isNamedCurve(privateKey.Curve) {
+		if withOID {
+			return x509.MarshalECPrivateKey(privateKey)
+		}
+
+		return nil, c.ErrUnsupportedCurve
+	}
+
+	oid, _ := oidFromCurve(privateKey.Curve)
+	if !withOID {
+		oid = nil
+	}
+
+	size := (privateKey.Curve.Params().N.BitLen() + 7) / 8
+	point := marshalPoint(privateKey.Curve, privateKey.X, privateKey.Y)
+
+	return asn1.Marshal(ecPrivateKey{
+		Version:       1,
+		PrivateKey:    privateKey.D.FillBytes(make([]byte, size)),
+		NamedCurveOID: oid,
+		PublicKey:     asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+}
+
+// marshalPKCS8PrivateKey converts a private key into a PKCS#8 PrivateKeyInfo
+func marshalPKCS8PrivateKey(privateKey *ecdsa.PrivateKey) ([]byte, error) {
+	oid, ok := oidFromCurve(privateKey.Curve)
+	if !ok {
+		return x509.MarshalPKCS8PrivateKey(privateKey)
+	}
+
+	params, err := asn1.Marshal(oid)
+	if err != nil {
+		return nil, err
+	}
+
+	sec1, err := marshalSEC1PrivateKey(privateKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkcs8{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}},
+		PrivateKey: sec1,
+	})
+}
+
+// marshalPKIXPublicKey converts a public key into a PKIX SubjectPublicKeyInfo
+func marshalPKIXPublicKey(publicKey *ecdsa.PublicKey) ([]byte, error) {
+	oid, ok := oidFromCurve(publicKey.Curve)
+	if !ok {
+		return x509.MarshalPKIXPublicKey(publicKey)
+	}
+
+	params, err := asn1.Marshal(oid)
+	if err != nil {
+		return nil, err
+	}
+
+	point := marshalPoint(publicKey.Curve, publicKey.X, publicKey.Y)
+
+	return asn1.Marshal(publicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}},
+		PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+}
+
+// parsePrivateKey converts a SEC 1 ECPrivateKey or a PKCS#8 PrivateKeyInfo into a private key
+func parsePrivateKey(der []byte) (*ecdsa.PrivateKey, error) {
+	privateKey, sec1Err := x509.ParseECPrivateKey(der)
+	if sec1Err == nil {
+		return privateKey, nil
+	}
+
+	if generic, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		privateKey, ok := generic.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, c.ErrUnsupportedPrivateKey
+		}
+
+		return privateKey, nil
+	}
+
+	var info pkcs8
+	if rest, err := asn1.Unmarshal(der, &info); err == nil && len(rest) == 0 && info.Algo.Algorithm.Equal(oidPublicKeyECDSA) {
+		var oid asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(info.Algo.Parameters.FullBytes, &oid); err != nil {
+			return nil, err
+		}
+
+		return parseSEC1PrivateKey(oid, info.PrivateKey, sec1Err)
+	}
+
+	return parseSEC1PrivateKey(nil, der, sec1Err)
+}
+
+// parseSEC1PrivateKey converts a SEC 1 ECPrivateKey on one of the namedCurves into a private key,
+// failing with fallback when the curve is not one of them
+func parseSEC1PrivateKey(oid asn1.ObjectIdentifier, der []byte, fallback error) (*ecdsa.PrivateKey, error) {
+	var key ecPrivateKey
+	if _, err := asn1.Unmarshal(der, &key); err != nil {
+		return nil, fallback
+	}
+
+	if oid == nil {
+		oid = key.NamedCurveOID
+	}
+
+	curve, ok := curveFromOID(oid)
+	if !ok {
+		return nil, fallback
+	}
+
+	if key.Version != 1 {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	d := new(big.Int).SetBytes(key.PrivateKey)
+	if d.Sign() == 0 || d.Cmp(curve.Params().N) >= 0 {
+		return nil, c.ErrScalarOutOfRange
+	}
+
+	privateKey := &ecdsa.PrivateKey{D: d}
+	privateKey.Curve = curve
+	privateKey.X, privateKey.Y = scalarBaseMult(curve, key.PrivateKey)
+
+	return privateKey, nil
+}
+
+// parsePublicKey converts a PKIX SubjectPublicKeyInfo into a public key
+func parsePublicKey(der []byte) (interface{}, error) {
+	publicKey, pkixErr := x509.ParsePKIXPublicKey(der)
+	if pkixErr == nil {
+		return publicKey, nil
+	}
+
+	var info publicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) != 0 || !info.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		return nil, pkixErr
+	}
+
+	var oid asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &oid); err != nil {
+		return nil, pkixErr
+	}
+
+	curve, ok := curveFromOID(oid)
+	if !ok {
+		return nil, pkixErr
+	}
+
+	x, y, err := unmarshalPoint(curve, info.PublicKey.RightAlign())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// marshalPoint converts a point into the SEC 1 uncompressed form 0x04 || X || Y
+func marshalPoint(curve elliptic.Curve, x, y *big.Int) []byte {
+	size := (curve.Params().BitSize + 7) / 8
+
+	point := make([]byte, 1+2*size)
+	point[0] = 4
+	x.FillBytes(point[1 : 1+size])
+	y.FillBytes(point[1+size:])
+
+	return point
+}
+
+// unmarshalPoint converts a SEC 1 uncompressed or compressed point into a point on the curve
+func unmarshalPoint(curve elliptic.Curve, data []byte) (*big.Int, *big.Int, error) {
+	size := (curve.Params().BitSize + 7) / 8
+
+	var x, y *big.Int
+	switch {
+	case len(data) == 1+2*size && data[0] == 4:
+		x = new(big.Int).SetBytes(data[1 : 1+size])
+		y = new(big.Int).SetBytes(data[1+size:])
+	case len(data) == 1+size && (data[0] == 2 || data[0] == 3):
+		x = new(big.Int).SetBytes(data[1:])
+		y = decompressY(curve, x, data[0] == 3)
+		if y == nil {
+			return nil, nil, c.ErrPointNotOnCurve
+		}
+	default:
+		return nil, nil, c.ErrInvalidPoint
+	}
+
+	if !curve.IsOnCurve(x, y) {
+		return nil, nil, c.ErrPointNotOnCurve
+	}
+
+	return x, y, nil
+}
+
+// marshalCompressedPoint converts a point into the SEC 1 compressed form 0x02/0x03 || X
+func marshalCompressedPoint(curve elliptic.Curve, x, y *big.Int) []byte {
+	size := (curve.Params().BitSize + 7) / 8
+
+	point := make([]byte, 1+size)
+	point[0] = byte(2 + y.Bit(0))
+	x.FillBytes(point[1:])
+
+	return point
+}
+
+// decompressY solves y² = x³ + ax + b for the y of the parity, nil when x is not on the curve
+func decompressY(ec elliptic.Curve, x *big.Int, odd bool) *big.Int {
+	params := ec.Params()
+	if x.Cmp(params.P) >= 0 {
+		return nil
+	}
+
+	var y2 *big.Int
+	if generic, ok := ec.(*curve); ok {
+		y2 = generic.polynomial(x)
+	} else {
+		// crypto/elliptic curves have a = -3
+		y2 = new(big.Int).Mul(x, x)
+		y2.Mul(y2, x)
+		y2.Sub(y2, new(big.Int).Mul(x, big.NewInt(3)))
+		y2.Add(y2, params.B)
+		y2.Mod(y2, params.P)
+	}
+
+	y := new(big.Int).ModSqrt(y2, params.P)
+	if y == nil {
+		return nil
+	}
+
+	if y.Bit(0) == 1 != odd {
+		y.Sub(params.P, y)
+		y.Mod(y, params.P)
+	}
+
+	return y
+}
@@ -0,0 +1,6 @@
+package ecdsa
+
+// SetGenericHook sets the test hook called by the elliptic.Curve methods of the package curves
+func SetGenericHook(hook func()) {
+	genericHook = hook
+}
@@ -0,0 +1,122 @@
+package ecdsa_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+
+	ec "github.com/ELares/crypto/pkg/ecdsa"
+	"github.com/ELares/crypto/pkg/hdkey"
+	"github.com/ELares/crypto/pkg/mnemonic"
+	"github.com/ELares/crypto/pkg/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenericPath(t *testing.T) {
+	e := ec.NewECDSA()
+	digest := sha256.Sum256([]byte("message"))
+
+	testcases := []struct {
+		name string
+
+		curve    elliptic.Curve
+		generate func() (*ecdsa.PrivateKey, error)
+		mnemonic bool
+	}{
+		{
+			name:     "K-256",
+			curve:    ec.Secp256k1(),
+			generate: e.K256PrivateKey,
+			mnemonic: true,
+		},
+		{
+			name:     "BP-256",
+			curve:    ec.BrainpoolP256r1(),
+			generate: e.BP256PrivateKey,
+			mnemonic: true,
+		},
+		{
+			name:     "BP-384",
+			curve:    ec.BrainpoolP384r1(),
+			generate: e.BP384PrivateKey,
+		},
+		{
+			name:     "BP-512",
+			curve:    ec.BrainpoolP512r1(),
+			generate: e.BP512PrivateKey,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			ec.SetGenericHook(func() { calls++ })
+			defer ec.SetGenericHook(nil)
+
+			privateKey, err := tc.generate()
+			assert.Nil(t, err)
+
+			sig, err := e.Sign(privateKey, digest[:])
+			assert.Nil(t, err)
+
+			raw, err := e.ToRawPrivateKey(privateKey)
+			assert.Nil(t, err)
+
+			restored, err := e.FromRawPrivateKey(tc.curve, raw)
+			assert.Nil(t, err)
+			assert.True(t, privateKey.Equal(restored))
+
+			privatePEM, err := e.ToPEMPrivateKey(privateKey)
+			assert.Nil(t, err)
+
+			parsed, err := e.FromPEMPrivateKey(privatePEM)
+			assert.Nil(t, err)
+			assert.True(t, privateKey.Equal(parsed))
+
+			if tc.mnemonic {
+				words, err := mnemonic.NewMnemonic().FromECDSA(privateKey, "")
+				assert.Nil(t, err)
+
+				restored, _, err = mnemonic.NewMnemonic().ToECDSA(tc.curve, words, "")
+				assert.Nil(t, err)
+				assert.True(t, privateKey.Equal(restored))
+			}
+
+			s, err := signer.NewAdapter().FromPrivateKey(privateKey)
+			assert.Nil(t, err)
+
+			signerSig, err := s.Sign(rand.Reader, digest[:], crypto.SHA256)
+			assert.Nil(t, err)
+
+			assert.Equal(t, 0, calls)
+
+			// verifying goes through the generic crypto/ecdsa implementation, public data only
+			assert.True(t, e.Verify(&privateKey.PublicKey, digest[:], sig))
+			assert.True(t, e.Verify(&privateKey.PublicKey, digest[:], signerSig))
+			assert.NotZero(t, calls)
+		})
+	}
+}
+
+func TestGenericPathHDKey(t *testing.T) {
+	calls := 0
+	ec.SetGenericHook(func() { calls++ })
+	defer ec.SetGenericHook(nil)
+
+	h := hdkey.NewHDKey()
+
+	master, err := h.NewMaster(hdkey.Secp256k1, make([]byte, 32))
+	assert.Nil(t, err)
+
+	child, err := h.Derive(master, "m/44'/0'/0'/0/7")
+	assert.Nil(t, err)
+
+	privateKey, err := h.ToPrivateKey(child)
+	assert.Nil(t, err)
+	assert.IsType(t, &ecdsa.PrivateKey{}, privateKey)
+
+	assert.Equal(t, 0, calls)
+}
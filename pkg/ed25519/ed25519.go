@@ -1,17 +1,21 @@
 package ed25519
 
 import (
+	"crypto/ecdh"
 	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/x509"
 	"encoding/pem"
 
+	"filippo.io/edwards25519"
 	c "github.com/ELares/crypto/pkg"
 	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/policy"
 )
 
 type (
-	// IED25519 interface for methods to generate ed25519 keys and conversion to PEM format
+	// IED25519 interface for methods to generate ed25519 keys, conversion to PEM and raw format and
+	// X25519 key agreement
 	IED25519 interface {
 		Ed25519() (ed25519.PrivateKey, ed25519.PublicKey, error)
 		Ed25519PEM() (ed25519.PrivateKey, p.PrivatePEM, ed25519.PublicKey, p.PublicPEM, error)
@@ -19,14 +23,28 @@ type (
 		FromPEMPrivateKey(p.PrivatePEM) (ed25519.PrivateKey, error)
 		FromPEMPublicKey(p.PublicPEM) (ed25519.PublicKey, error)
 		FromPEM(p.PrivatePEM, p.PublicPEM) (ed25519.PrivateKey, ed25519.PublicKey, error)
+		FromPEMValidated(p.PrivatePEM, p.PublicPEM) (ed25519.PrivateKey, ed25519.PublicKey, error)
+
+		ValidatePrivateKey(ed25519.PrivateKey) error
+		ValidatePublicKey(ed25519.PublicKey) error
 
 		ToPEMPrivateKey(ed25519.PrivateKey) (p.PrivatePEM, error)
 		ToPEMPublicKey(ed25519.PublicKey) (p.PublicPEM, error)
 		ToPEM(ed25519.PrivateKey, ed25519.PublicKey) (p.PrivatePEM, p.PublicPEM, error)
+
+		FromRawPrivateKey([]byte) (ed25519.PrivateKey, error)
+		FromRawPublicKey([]byte) (ed25519.PublicKey, error)
+		ToRawPrivateKey(ed25519.PrivateKey) ([]byte, error)
+		ToRawPublicKey(ed25519.PublicKey) ([]byte, error)
+
+		X25519() (*ecdh.PrivateKey, *ecdh.PublicKey, error)
+		X25519SharedSecret(privateKey *ecdh.PrivateKey, peerPublicKey *ecdh.PublicKey) ([]byte, error)
 	}
 
 	// ED25519 struct to implement the IED25519 methods
-	ED25519 struct{}
+	ED25519 struct {
+		policy *policy.Policy
+	}
 )
 
 // NewED25519 gets a new ED25519 pointer
@@ -34,8 +52,18 @@ func NewED25519() IED25519 {
 	return &ED25519{}
 }
 
+// NewED25519WithPolicy gets a new ED25519 pointer enforcing the policy on generated, parsed and
+// exported keys
+func NewED25519WithPolicy(policy *policy.Policy) IED25519 {
+	return &ED25519{policy: policy}
+}
+
 // Ed25519 generates a new ed25519 private/public keys
 func (e *ED25519) Ed25519() (ed25519.PrivateKey, ed25519.PublicKey, error) {
+	if err := e.policy.CheckAlgorithm(policy.AlgorithmEd25519); err != nil {
+		return nil, nil, err
+	}
+
 	pubkey, prvkey, err := ed25519.GenerateKey(rand.Reader)
 	return prvkey, pubkey, err
 }
@@ -63,6 +91,10 @@ func (e *ED25519) FromPEMPrivateKey(privatePEM p.PrivatePEM) (ed25519.PrivateKey
 		return nil, c.ErrDecodePEMPrivateKey
 	}
 
+	if err := e.checkPolicy(); err != nil {
+		return nil, err
+	}
+
 	genericPrivateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
 	if err != nil {
 		return nil, err
@@ -79,6 +111,10 @@ func (e *ED25519) FromPEMPublicKey(publicPEM p.PublicPEM) (ed25519.PublicKey, er
 		return nil, c.ErrDecodePEMPublicKey
 	}
 
+	if err := e.checkPolicy(); err != nil {
+		return nil, err
+	}
+
 	genericPublicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
 		return nil, err
@@ -102,12 +138,74 @@ func (e *ED25519) FromPEM(privatePEM p.PrivatePEM, publicPEM p.PublicPEM) (ed255
 	return privateKey, publicKey, nil
 }
 
+// FromPEMValidated takes pem keys and converts them into validated ed25519 keys, failing when the
+// public key is not the public half of the private key
+func (e *ED25519) FromPEMValidated(privatePEM p.PrivatePEM, publicPEM p.PublicPEM) (ed25519.PrivateKey, ed25519.PublicKey, error) {
+	privateKey, publicKey, err := e.FromPEM(privatePEM, publicPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := e.ValidatePrivateKey(privateKey); err != nil {
+		return nil, nil, err
+	}
+
+	if err := e.ValidatePublicKey(publicKey); err != nil {
+		return nil, nil, err
+	}
+
+	if !publicKey.Equal(privateKey.Public()) {
+		return nil, nil, c.ErrKeyMismatch
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// ValidatePrivateKey checks the private key length and that its public half derives from its seed
+func (e *ED25519) ValidatePrivateKey(privateKey ed25519.PrivateKey) error {
+	if privateKey == nil {
+		return c.ErrNilPrivateKey
+	}
+
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return c.ErrInvalidKeyLength
+	}
+
+	derived := ed25519.NewKeyFromSeed(privateKey.Seed())
+	if !derived.Equal(privateKey) {
+		return c.ErrKeyMismatch
+	}
+
+	return nil
+}
+
+// ValidatePublicKey checks the public key length and that it decodes into a point of the curve
+func (e *ED25519) ValidatePublicKey(publicKey ed25519.PublicKey) error {
+	if publicKey == nil {
+		return c.ErrNilPublicKey
+	}
+
+	if len(publicKey) != ed25519.PublicKeySize {
+		return c.ErrInvalidKeyLength
+	}
+
+	if _, err := new(edwards25519.Point).SetBytes(publicKey); err != nil {
+		return c.ErrPointNotOnCurve
+	}
+
+	return nil
+}
+
 // ToPEMPrivateKey converts a ed25519 private key into a private PEM key
 func (e *ED25519) ToPEMPrivateKey(privateKey ed25519.PrivateKey) (p.PrivatePEM, error) {
 	if privateKey == nil {
 		return nil, c.ErrNilPrivateKey
 	}
 
+	if err := e.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
 	x509Encoded, err := x509.MarshalPKCS8PrivateKey(privateKey)
 	if err != nil {
 		return nil, err
@@ -122,6 +220,10 @@ func (e *ED25519) ToPEMPublicKey(publicKey ed25519.PublicKey) (p.PublicPEM, erro
 		return nil, c.ErrNilPublicKey
 	}
 
+	if err := e.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
 	x509EncodedPub, err := x509.MarshalPKIXPublicKey(publicKey)
 	if err != nil {
 		return nil, err
@@ -144,3 +246,102 @@ func (e *ED25519) ToPEM(privateKey ed25519.PrivateKey, publicKey ed25519.PublicK
 
 	return prvPEM, pubPEM, nil
 }
+
+// FromRawPrivateKey takes a raw 32 bytes RFC 8032 seed and converts it into a ed25519 private key
+func (e *ED25519) FromRawPrivateKey(seed []byte) (ed25519.PrivateKey, error) {
+	if seed == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if len(seed) != ed25519.SeedSize {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	if err := e.policy.CheckAlgorithm(policy.AlgorithmEd25519); err != nil {
+		return nil, err
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// FromRawPublicKey takes a raw 32 bytes public key and converts it into a ed25519 public key, failing
+// when it is not a point of the curve
+func (e *ED25519) FromRawPublicKey(data []byte) (ed25519.PublicKey, error) {
+	if err := e.ValidatePublicKey(data); err != nil {
+		return nil, err
+	}
+
+	if err := e.policy.CheckAlgorithm(policy.AlgorithmEd25519); err != nil {
+		return nil, err
+	}
+
+	return append(ed25519.PublicKey{}, data...), nil
+}
+
+// ToRawPrivateKey converts a ed25519 private key into its raw 32 bytes RFC 8032 seed
+func (e *ED25519) ToRawPrivateKey(privateKey ed25519.PrivateKey) ([]byte, error) {
+	if err := e.ValidatePrivateKey(privateKey); err != nil {
+		return nil, err
+	}
+
+	return privateKey.Seed(), nil
+}
+
+// ToRawPublicKey converts a ed25519 public key into its raw 32 bytes encoding
+func (e *ED25519) ToRawPublicKey(publicKey ed25519.PublicKey) ([]byte, error) {
+	if err := e.ValidatePublicKey(publicKey); err != nil {
+		return nil, err
+	}
+
+	return append([]byte{}, publicKey...), nil
+}
+
+// checkPolicy checks the policy allows ed25519 pem keys
+func (e *ED25519) checkPolicy() error {
+	if err := e.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return err
+	}
+
+	return e.policy.CheckAlgorithm(policy.AlgorithmEd25519)
+}
+
+// X25519 generates a new x25519 private/public keys for key agreement (RFC 7748)
+func (e *ED25519) X25519() (*ecdh.PrivateKey, *ecdh.PublicKey, error) {
+	if err := e.policy.CheckAlgorithm(policy.AlgorithmX25519); err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return privateKey, privateKey.PublicKey(), nil
+}
+
+// X25519SharedSecret computes the x25519 shared secret with the peer public key, failing when the
+// peer public key is a low order point
+func (e *ED25519) X25519SharedSecret(privateKey *ecdh.PrivateKey, peerPublicKey *ecdh.PublicKey) ([]byte, error) {
+	if privateKey == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if peerPublicKey == nil {
+		return nil, c.ErrNilPublicKey
+	}
+
+	if privateKey.Curve() != ecdh.X25519() || peerPublicKey.Curve() != ecdh.X25519() {
+		return nil, c.ErrUnsupportedCurve
+	}
+
+	if err := e.policy.CheckAlgorithm(policy.AlgorithmX25519); err != nil {
+		return nil, err
+	}
+
+	shared, err := privateKey.ECDH(peerPublicKey)
+	if err != nil {
+		return nil, c.ErrLowOrderPoint
+	}
+
+	return shared, nil
+}
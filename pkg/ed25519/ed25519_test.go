@@ -0,0 +1,246 @@
+package ed25519
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	c "github.com/ELares/crypto/pkg"
+	"github.com/ELares/crypto/pkg/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	ed := NewED25519()
+
+	prvKey, pubKey, _ := ed.Ed25519()
+	_, otherPubKey, _ := ed.Ed25519()
+
+	mismatch := append(ed25519.PrivateKey{}, prvKey...)
+	copy(mismatch[ed25519.SeedSize:], otherPubKey)
+
+	// y = 2 has no x on the curve
+	notOnCurve := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	notOnCurve[0] = 2
+
+	testcases := []struct {
+		name string
+
+		validate func() error
+
+		expectError error
+	}{
+		{
+			name:     "Valid Private Key",
+			validate: func() error { return ed.ValidatePrivateKey(prvKey) },
+		},
+		{
+			name:     "Valid Public Key",
+			validate: func() error { return ed.ValidatePublicKey(pubKey) },
+		},
+		{
+			name:        "Invalid Private Key: Public Half Mismatch",
+			validate:    func() error { return ed.ValidatePrivateKey(mismatch) },
+			expectError: c.ErrKeyMismatch,
+		},
+		{
+			name:        "Invalid Private Key: Seed Only",
+			validate:    func() error { return ed.ValidatePrivateKey(prvKey.Seed()) },
+			expectError: c.ErrInvalidKeyLength,
+		},
+		{
+			name:        "Invalid Private Key: Nil",
+			validate:    func() error { return ed.ValidatePrivateKey(nil) },
+			expectError: c.ErrNilPrivateKey,
+		},
+		{
+			name:        "Invalid Public Key: Not On Curve",
+			validate:    func() error { return ed.ValidatePublicKey(notOnCurve) },
+			expectError: c.ErrPointNotOnCurve,
+		},
+		{
+			name:        "Invalid Public Key: Truncated",
+			validate:    func() error { return ed.ValidatePublicKey(pubKey[:16]) },
+			expectError: c.ErrInvalidKeyLength,
+		},
+		{
+			name:        "Invalid Public Key: Nil",
+			validate:    func() error { return ed.ValidatePublicKey(nil) },
+			expectError: c.ErrNilPublicKey,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectError, tc.validate())
+		})
+	}
+}
+
+func TestFromPEMValidated(t *testing.T) {
+	ed := NewED25519()
+
+	_, prvPEM, _, pubPEM, _ := ed.Ed25519PEM()
+	_, _, _, otherPubPEM, _ := ed.Ed25519PEM()
+
+	prvKey, pubKey, err := ed.FromPEMValidated(prvPEM, pubPEM)
+	assert.Nil(t, err)
+	assert.NotNil(t, prvKey)
+	assert.NotNil(t, pubKey)
+
+	prvKey, pubKey, err = ed.FromPEMValidated(prvPEM, otherPubPEM)
+	assert.Equal(t, c.ErrKeyMismatch, err)
+	assert.Nil(t, prvKey)
+	assert.Nil(t, pubKey)
+
+	_, _, err = ed.FromPEMValidated(prvPEM, nil)
+	assert.NotNil(t, err)
+}
+
+func TestWithPolicy(t *testing.T) {
+	_, prvPEM, _, pubPEM, _ := NewED25519().Ed25519PEM()
+
+	modern, _ := policy.NewPolicy(policy.Modern)
+	_, _, err := NewED25519WithPolicy(modern).FromPEM(prvPEM, pubPEM)
+	assert.Nil(t, err)
+
+	cnsa, _ := policy.NewPolicy(policy.CNSA2)
+	ed := NewED25519WithPolicy(cnsa)
+
+	prvKey, pubKey, err := ed.Ed25519()
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+	assert.Nil(t, prvKey)
+	assert.Nil(t, pubKey)
+
+	_, err = ed.FromPEMPrivateKey(prvPEM)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, err = ed.FromPEMPublicKey(pubPEM)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	// FIPS 140-3 allows ed25519 but not x25519 key agreement
+	prvX25519, pubX25519, _ := NewED25519().X25519()
+
+	fips, _ := policy.NewPolicy(policy.FIPS1403)
+	ed = NewED25519WithPolicy(fips)
+
+	_, _, err = ed.Ed25519()
+	assert.Nil(t, err)
+
+	prvKeyX25519, pubKeyX25519, err := ed.X25519()
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+	assert.Nil(t, prvKeyX25519)
+	assert.Nil(t, pubKeyX25519)
+
+	_, err = ed.X25519SharedSecret(prvX25519, pubX25519)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, _, err = NewED25519WithPolicy(modern).X25519()
+	assert.Nil(t, err)
+}
+
+func TestRaw(t *testing.T) {
+	ed := NewED25519()
+
+	// RFC 8032 section 7.1 test 1
+	seed, _ := hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+	public, _ := hex.DecodeString("d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a")
+
+	prvKey, err := ed.FromRawPrivateKey(seed)
+	assert.Nil(t, err)
+	assert.Equal(t, public, []byte(prvKey.Public().(ed25519.PublicKey)))
+
+	pubKey, err := ed.FromRawPublicKey(public)
+	assert.Nil(t, err)
+	assert.True(t, pubKey.Equal(prvKey.Public()))
+
+	raw, err := ed.ToRawPrivateKey(prvKey)
+	assert.Nil(t, err)
+	assert.Equal(t, seed, raw)
+
+	raw, err = ed.ToRawPublicKey(pubKey)
+	assert.Nil(t, err)
+	assert.Equal(t, public, raw)
+
+	notOnCurve := make([]byte, ed25519.PublicKeySize)
+	notOnCurve[0] = 2
+
+	testcases := []struct {
+		name string
+
+		decode func() error
+
+		expectError error
+	}{
+		{
+			name:        "Invalid Private Key: Expanded",
+			decode:      func() error { _, err := ed.FromRawPrivateKey(prvKey); return err },
+			expectError: c.ErrInvalidKeyLength,
+		},
+		{
+			name:        "Invalid Private Key: Nil",
+			decode:      func() error { _, err := ed.FromRawPrivateKey(nil); return err },
+			expectError: c.ErrNilPrivateKey,
+		},
+		{
+			name:        "Invalid Public Key: Not On Curve",
+			decode:      func() error { _, err := ed.FromRawPublicKey(notOnCurve); return err },
+			expectError: c.ErrPointNotOnCurve,
+		},
+		{
+			name:        "Invalid Public Key: Compressed Length",
+			decode:      func() error { _, err := ed.FromRawPublicKey(append([]byte{2}, public...)); return err },
+			expectError: c.ErrInvalidKeyLength,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectError, tc.decode())
+		})
+	}
+
+	cnsa, _ := policy.NewPolicy(policy.CNSA2)
+	_, err = NewED25519WithPolicy(cnsa).FromRawPublicKey(public)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+}
+
+func TestX25519(t *testing.T) {
+	ed := NewED25519()
+
+	// RFC 7748 section 6.1
+	alicePrivate, _ := hex.DecodeString("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	bobPublic, _ := hex.DecodeString("de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f")
+	expected := "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742"
+
+	alicePrvKey, _ := ecdh.X25519().NewPrivateKey(alicePrivate)
+	bobPubKey, _ := ecdh.X25519().NewPublicKey(bobPublic)
+
+	shared, err := ed.X25519SharedSecret(alicePrvKey, bobPubKey)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, hex.EncodeToString(shared))
+
+	prvKey, pubKey, err := ed.X25519()
+	assert.Nil(t, err)
+
+	shared, err = ed.X25519SharedSecret(prvKey, alicePrvKey.PublicKey())
+	assert.Nil(t, err)
+
+	other, err := ed.X25519SharedSecret(alicePrvKey, pubKey)
+	assert.Nil(t, err)
+	assert.Equal(t, shared, other)
+
+	lowOrder, _ := ecdh.X25519().NewPublicKey(make([]byte, 32))
+	_, err = ed.X25519SharedSecret(prvKey, lowOrder)
+	assert.Equal(t, c.ErrLowOrderPoint, err)
+
+	p256, _ := ecdh.P256().GenerateKey(rand.Reader)
+	_, err = ed.X25519SharedSecret(prvKey, p256.PublicKey())
+	assert.Equal(t, c.ErrUnsupportedCurve, err)
+
+	_, err = ed.X25519SharedSecret(nil, pubKey)
+	assert.Equal(t, c.ErrNilPrivateKey, err)
+}
@@ -0,0 +1,408 @@
+package ed448
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+
+	c "github.com/ELares/crypto/pkg"
+	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/policy"
+	"github.com/cloudflare/circl/dh/x448"
+	"github.com/cloudflare/circl/ecc/goldilocks"
+	"github.com/cloudflare/circl/sign/ed448"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+var (
+	// oidEd448 Ed448 algorithm identifier (RFC 8410)
+	oidEd448 = asn1.ObjectIdentifier{1, 3, 101, 113}
+)
+
+type (
+	// IED448 interface for methods to generate ed448 keys, conversion to PEM and JWK format and
+	// x448 key agreement
+	IED448 interface {
+		Ed448() (ed448.PrivateKey, ed448.PublicKey, error)
+		Ed448PEM() (ed448.PrivateKey, p.PrivatePEM, ed448.PublicKey, p.PublicPEM, error)
+
+		FromPEMPrivateKey(p.PrivatePEM) (ed448.PrivateKey, error)
+		FromPEMPublicKey(p.PublicPEM) (ed448.PublicKey, error)
+		FromPEM(p.PrivatePEM, p.PublicPEM) (ed448.PrivateKey, ed448.PublicKey, error)
+		FromPEMValidated(p.PrivatePEM, p.PublicPEM) (ed448.PrivateKey, ed448.PublicKey, error)
+
+		ValidatePrivateKey(ed448.PrivateKey) error
+		ValidatePublicKey(ed448.PublicKey) error
+
+		ToPEMPrivateKey(ed448.PrivateKey) (p.PrivatePEM, error)
+		ToPEMPublicKey(ed448.PublicKey) (p.PublicPEM, error)
+		ToPEM(ed448.PrivateKey, ed448.PublicKey) (p.PrivatePEM, p.PublicPEM, error)
+
+		ToJWK(publicKey ed448.PublicKey, id string) ([]byte, error)
+
+		Sign(privateKey ed448.PrivateKey, message []byte, context string) ([]byte, error)
+		Verify(publicKey ed448.PublicKey, message, signature []byte, context string) bool
+
+		X448() (x448.Key, x448.Key, error)
+		X448SharedSecret(privateKey, peerPublicKey x448.Key) ([]byte, error)
+	}
+
+	// ED448 struct to implement the IED448 methods
+	ED448 struct {
+		policy *policy.Policy
+	}
+
+	// pkcs8 PKCS#8 OneAsymmetricKey structure (RFC 5958)
+	pkcs8 struct {
+		Version    int
+		Algo       pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}
+
+	// publicKeyInfo PKIX SubjectPublicKeyInfo structure (RFC 5280)
+	publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+
+	// jwk OKP json web key (RFC 8037)
+	jwk struct {
+		Use       string `json:"use,omitempty"`
+		KeyType   string `json:"kty"`
+		KeyID     string `json:"kid,omitempty"`
+		Curve     string `json:"crv"`
+		Algorithm string `json:"alg,omitempty"`
+		X         string `json:"x"`
+	}
+)
+
+// NewED448 gets a new ED448 pointer
+func NewED448() IED448 {
+	return &ED448{}
+}
+
+// NewED448WithPolicy gets a new ED448 pointer enforcing the policy on generated, parsed and exported
+// keys
+func NewED448WithPolicy(policy *policy.Policy) IED448 {
+	return &ED448{policy: policy}
+}
+
+// Ed448 generates a new ed448 private/public keys
+func (e *ED448) Ed448() (ed448.PrivateKey, ed448.PublicKey, error) {
+	if err := e.policy.CheckAlgorithm(policy.AlgorithmEd448); err != nil {
+		return nil, nil, err
+	}
+
+	pubkey, prvkey, err := ed448.GenerateKey(rand.Reader)
+	return prvkey, pubkey, err
+}
+
+// Ed448PEM generates new ed448 private/public pem keys
+func (e *ED448) Ed448PEM() (ed448.PrivateKey, p.PrivatePEM, ed448.PublicKey, p.PublicPEM, error) {
+	privateKey, publicKey, err := e.Ed448()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	privatePEMKey, publicPEMKey, err := e.ToPEM(privateKey, publicKey)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return privateKey, privatePEMKey, publicKey, publicPEMKey, nil
+}
+
+// FromPEMPrivateKey takes a PKCS#8 private pem key and converts it into a ed448 private key
+func (e *ED448) FromPEMPrivateKey(privatePEM p.PrivatePEM) (ed448.PrivateKey, error) {
+	block, _ := pem.Decode(privatePEM)
+
+	if block == nil || block.Type != c.PRIVATEKEY {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	if err := e.checkPolicy(); err != nil {
+		return nil, err
+	}
+
+	var info pkcs8
+	if rest, err := asn1.Unmarshal(block.Bytes, &info); err != nil || len(rest) != 0 {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	if !info.Algo.Algorithm.Equal(oidEd448) {
+		return nil, c.ErrUnsupportedPrivateKey
+	}
+
+	var seed []byte
+	if rest, err := asn1.Unmarshal(info.PrivateKey, &seed); err != nil || len(rest) != 0 {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	if len(seed) != ed448.SeedSize {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	return ed448.NewKeyFromSeed(seed), nil
+}
+
+// FromPEMPublicKey takes a PKIX public pem key and converts it into a ed448 public key
+func (e *ED448) FromPEMPublicKey(publicPEM p.PublicPEM) (ed448.PublicKey, error) {
+	block, _ := pem.Decode(publicPEM)
+
+	if block == nil || block.Type != c.PUBLICKEY {
+		return nil, c.ErrDecodePEMPublicKey
+	}
+
+	if err := e.checkPolicy(); err != nil {
+		return nil, err
+	}
+
+	var info publicKeyInfo
+	if rest, err := asn1.Unmarshal(block.Bytes, &info); err != nil || len(rest) != 0 {
+		return nil, c.ErrDecodePEMPublicKey
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidEd448) {
+		return nil, c.ErrUnsupportedPublicKey
+	}
+
+	publicKey := info.PublicKey.RightAlign()
+	if len(publicKey) != ed448.PublicKeySize {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	return ed448.PublicKey(publicKey), nil
+}
+
+// FromPEM takes pem keys and converts them into a ed448 keys
+func (e *ED448) FromPEM(privatePEM p.PrivatePEM, publicPEM p.PublicPEM) (ed448.PrivateKey, ed448.PublicKey, error) {
+	privateKey, err := e.FromPEMPrivateKey(privatePEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicKey, err := e.FromPEMPublicKey(publicPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// FromPEMValidated takes pem keys and converts them into validated ed448 keys, failing when the
+// public key is not the public half of the private key
+func (e *ED448) FromPEMValidated(privatePEM p.PrivatePEM, publicPEM p.PublicPEM) (ed448.PrivateKey, ed448.PublicKey, error) {
+	privateKey, publicKey, err := e.FromPEM(privatePEM, publicPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := e.ValidatePrivateKey(privateKey); err != nil {
+		return nil, nil, err
+	}
+
+	if err := e.ValidatePublicKey(publicKey); err != nil {
+		return nil, nil, err
+	}
+
+	if !publicKey.Equal(privateKey.Public()) {
+		return nil, nil, c.ErrKeyMismatch
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// ValidatePrivateKey checks the private key length and that its public half derives from its seed
+func (e *ED448) ValidatePrivateKey(privateKey ed448.PrivateKey) error {
+	if privateKey == nil {
+		return c.ErrNilPrivateKey
+	}
+
+	if len(privateKey) != ed448.PrivateKeySize {
+		return c.ErrInvalidKeyLength
+	}
+
+	derived := ed448.NewKeyFromSeed(privateKey.Seed())
+	if !derived.Equal(privateKey) {
+		return c.ErrKeyMismatch
+	}
+
+	return nil
+}
+
+// ValidatePublicKey checks the public key length and that it decodes into a point of the curve
+func (e *ED448) ValidatePublicKey(publicKey ed448.PublicKey) error {
+	if publicKey == nil {
+		return c.ErrNilPublicKey
+	}
+
+	if len(publicKey) != ed448.PublicKeySize {
+		return c.ErrInvalidKeyLength
+	}
+
+	if _, err := goldilocks.FromBytes(publicKey); err != nil {
+		return c.ErrPointNotOnCurve
+	}
+
+	return nil
+}
+
+// ToPEMPrivateKey converts a ed448 private key into a PKCS#8 private PEM key
+func (e *ED448) ToPEMPrivateKey(privateKey ed448.PrivateKey) (p.PrivatePEM, error) {
+	if privateKey == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if len(privateKey) != ed448.PrivateKeySize {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	if err := e.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	seed, err := asn1.Marshal(privateKey.Seed())
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := asn1.Marshal(pkcs8{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: oidEd448},
+		PrivateKey: seed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: encoded}), nil
+}
+
+// ToPEMPublicKey converts a ed448 public key into a PKIX public PEM key
+func (e *ED448) ToPEMPublicKey(publicKey ed448.PublicKey) (p.PublicPEM, error) {
+	if publicKey == nil {
+		return nil, c.ErrNilPublicKey
+	}
+
+	if len(publicKey) != ed448.PublicKeySize {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	if err := e.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	encoded, err := asn1.Marshal(publicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidEd448},
+		PublicKey: asn1.BitString{Bytes: publicKey, BitLength: 8 * len(publicKey)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: c.PUBLICKEY, Bytes: encoded}), nil
+}
+
+// ToPEM converts ed448 private & public keys, into private & public PEM keys
+func (e *ED448) ToPEM(privateKey ed448.PrivateKey, publicKey ed448.PublicKey) (p.PrivatePEM, p.PublicPEM, error) {
+	prvPEM, err := e.ToPEMPrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pubPEM, err := e.ToPEMPublicKey(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return prvPEM, pubPEM, nil
+}
+
+// ToJWK converts a ed448 public key into an OKP jwk EdDSA
+func (e *ED448) ToJWK(publicKey ed448.PublicKey, id string) ([]byte, error) {
+	if err := e.ValidatePublicKey(publicKey); err != nil {
+		return nil, err
+	}
+
+	if err := e.policy.CheckJWK(publicKey, jose.EdDSA); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jwk{
+		Use:       c.SIG,
+		KeyType:   "OKP",
+		KeyID:     id,
+		Curve:     "Ed448",
+		Algorithm: string(jose.EdDSA),
+		X:         base64.RawURLEncoding.EncodeToString(publicKey),
+	})
+}
+
+// Sign signs the message with Ed448 (RFC 8032) under the context, empty for plain Ed448
+func (e *ED448) Sign(privateKey ed448.PrivateKey, message []byte, context string) ([]byte, error) {
+	if privateKey == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if len(privateKey) != ed448.PrivateKeySize {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	if len(context) > ed448.ContextMaxSize {
+		return nil, c.ErrContextTooLong
+	}
+
+	if err := e.policy.CheckAlgorithm(policy.AlgorithmEd448); err != nil {
+		return nil, err
+	}
+
+	return ed448.Sign(privateKey, message, context), nil
+}
+
+// Verify reports whether the signature of the message under the context is valid
+func (e *ED448) Verify(publicKey ed448.PublicKey, message, signature []byte, context string) bool {
+	if len(publicKey) != ed448.PublicKeySize || len(context) > ed448.ContextMaxSize {
+		return false
+	}
+
+	return ed448.Verify(publicKey, message, signature, context)
+}
+
+// X448 generates a new x448 private/public keys for key agreement (RFC 7748)
+func (e *ED448) X448() (x448.Key, x448.Key, error) {
+	var privateKey, publicKey x448.Key
+	if _, err := rand.Read(privateKey[:]); err != nil {
+		return privateKey, publicKey, err
+	}
+
+	x448.KeyGen(&publicKey, &privateKey)
+
+	return privateKey, publicKey, nil
+}
+
+// X448SharedSecret computes the x448 shared secret with the peer public key, failing when the peer
+// public key is a low order point
+func (e *ED448) X448SharedSecret(privateKey, peerPublicKey x448.Key) ([]byte, error) {
+	var shared x448.Key
+	if !x448.Shared(&shared, &privateKey, &peerPublicKey) {
+		return nil, c.ErrLowOrderPoint
+	}
+
+	if subtle.ConstantTimeCompare(shared[:], make([]byte, x448.Size)) == 1 {
+		return nil, c.ErrLowOrderPoint
+	}
+
+	return shared[:], nil
+}
+
+// checkPolicy checks the policy allows ed448 pem keys
+func (e *ED448) checkPolicy() error {
+	if err := e.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return err
+	}
+
+	return e.policy.CheckAlgorithm(policy.AlgorithmEd448)
+}
@@ -0,0 +1,470 @@
+package envelope
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+
+	c "github.com/ELares/crypto/pkg"
+	ec "github.com/ELares/crypto/pkg/ecdsa"
+	"github.com/ELares/crypto/pkg/policy"
+	"golang.org/x/crypto/hkdf"
+	"gopkg.in/square/go-jose.v2"
+	josecipher "gopkg.in/square/go-jose.v2/cipher"
+)
+
+const (
+	// Version version of the envelope format
+	Version = 1
+
+	// AlgorithmRSAOAEP256 data key wrapped with RSA-OAEP and SHA-256
+	AlgorithmRSAOAEP256 Algorithm = "RSA-OAEP-256"
+
+	// AlgorithmECDHHKDFA256KW data key wrapped with AES-256 key wrap (RFC 3394) under a key derived
+	// with HKDF-SHA256 from an ephemeral-static ECDH shared secret; unlike the JOSE ECDH-ES+A256KW
+	// (RFC 7518) the key is not derived with the Concat KDF, hence its own identifier
+	AlgorithmECDHHKDFA256KW Algorithm = "ECDH-HKDF-SHA256+A256KW"
+
+	// DataKeySize size of the AES-256 data key
+	DataKeySize = 32
+
+	// hkdfInfo prefix of the HKDF info binding the key encryption key to the envelope header
+	hkdfInfo = "github.com/ELares/crypto/pkg/envelope"
+)
+
+var (
+	// curves ecdh curve of each envelope curve name
+	curves = map[string]ecdh.Curve{
+		"X25519": ecdh.X25519(),
+		"P-256":  ecdh.P256(),
+		"P-384":  ecdh.P384(),
+		"P-521":  ecdh.P521(),
+	}
+)
+
+type (
+	// Algorithm data key wrapping algorithm
+	Algorithm string
+
+	// Envelope payload encrypted with AES-256-GCM under a random data key, and the data key wrapped
+	// for the key identified by KeyID, the payload staying untouched when the data key is rewrapped
+	Envelope struct {
+		Version            int       `json:"v"`
+		KeyID              string    `json:"kid"`
+		Algorithm          Algorithm `json:"alg"`
+		Curve              string    `json:"crv,omitempty"`
+		EphemeralPublicKey []byte    `json:"epk,omitempty"`
+		WrappedKey         []byte    `json:"wk"`
+		Nonce              []byte    `json:"iv"`
+		Ciphertext         []byte    `json:"ct"`
+	}
+
+	// IEnvelope interface for methods to seal payloads in envelopes, open them, rewrap their data
+	// key on key rotation and serialize them
+	IEnvelope interface {
+		Seal(publicKey crypto.PublicKey, kid string, plaintext, additionalData []byte) (*Envelope, error)
+		Open(envelope *Envelope, privateKey crypto.PrivateKey, additionalData []byte) ([]byte, error)
+		Rewrap(envelope *Envelope, privateKey crypto.PrivateKey, publicKey crypto.PublicKey, kid string) (*Envelope, error)
+
+		Marshal(envelope *Envelope) ([]byte, error)
+		Unmarshal(data []byte) (*Envelope, error)
+
+		KeyID(publicKey crypto.PublicKey) (string, error)
+	}
+
+	// Envelopes struct to implement the IEnvelope methods
+	Envelopes struct {
+		policy *policy.Policy
+	}
+)
+
+// NewEnvelope gets a new Envelopes pointer
+func NewEnvelope() IEnvelope {
+	return &Envelopes{}
+}
+
+// NewEnvelopeWithPolicy gets a new Envelopes pointer enforcing the policy on the wrapping public
+// keys
+func NewEnvelopeWithPolicy(policy *policy.Policy) IEnvelope {
+	return &Envelopes{policy: policy}
+}
+
+// Seal encrypts the plaintext with a new data key, authenticating the additional data, e.g. the
+// table, column and row of a database field, and wraps the data key for a *rsa.PublicKey, a
+// *ecdsa.PublicKey or a *ecdh.PublicKey on X25519, P-256, P-384 or P-521, an empty kid being
+// replaced by the RFC 7638 JWK thumbprint of the public key
+func (e *Envelopes) Seal(publicKey crypto.PublicKey, kid string, plaintext, additionalData []byte) (*Envelope, error) {
+	dataKey := make([]byte, DataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+
+	envelope, err := e.wrap(dataKey, publicKey, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope.Nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, envelope.Nonce); err != nil {
+		return nil, err
+	}
+
+	envelope.Ciphertext = aead.Seal(nil, envelope.Nonce, plaintext, additionalData)
+
+	return envelope, nil
+}
+
+// Open unwraps the data key with a *rsa.PrivateKey, a crypto.Decrypter of a rsa key, e.g. a key
+// manager decryption key, a *ecdsa.PrivateKey or a *ecdh.PrivateKey, and decrypts the payload,
+// failing when the additional data differs from the sealed one
+func (e *Envelopes) Open(envelope *Envelope, privateKey crypto.PrivateKey, additionalData []byte) ([]byte, error) {
+	dataKey, err := e.unwrap(envelope, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(envelope.Nonce) != aead.NonceSize() {
+		return nil, c.ErrInvalidEnvelope
+	}
+
+	plaintext, err := aead.Open(nil, envelope.Nonce, envelope.Ciphertext, additionalData)
+	if err != nil {
+		return nil, c.ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
+
+// Rewrap unwraps the data key with the private key of the previous wrapping key and wraps it for
+// the public key of the new one, e.g. after a key rotation, the nonce and ciphertext of the payload
+// being kept as is
+func (e *Envelopes) Rewrap(envelope *Envelope, privateKey crypto.PrivateKey, publicKey crypto.PublicKey, kid string) (*Envelope, error) {
+	dataKey, err := e.unwrap(envelope, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	rewrapped, err := e.wrap(dataKey, publicKey, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	rewrapped.Nonce = append([]byte{}, envelope.Nonce...)
+	rewrapped.Ciphertext = append([]byte{}, envelope.Ciphertext...)
+
+	return rewrapped, nil
+}
+
+// Marshal serializes the envelope as JSON, binary fields being base64 encoded
+func (e *Envelopes) Marshal(envelope *Envelope) ([]byte, error) {
+	if err := validate(envelope); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(envelope)
+}
+
+// Unmarshal parses a JSON envelope, checking its version, algorithm and fields
+func (e *Envelopes) Unmarshal(data []byte) (*Envelope, error) {
+	envelope := &Envelope{}
+	if err := json.Unmarshal(data, envelope); err != nil {
+		return nil, c.ErrInvalidEnvelope
+	}
+
+	if err := validate(envelope); err != nil {
+		return nil, err
+	}
+
+	return envelope, nil
+}
+
+// KeyID gets the RFC 7638 JWK thumbprint of a rsa, ecdsa or ecdh public key, the RFC 8037 one for
+// X25519 keys
+func (e *Envelopes) KeyID(publicKey crypto.PublicKey) (string, error) {
+	switch key := publicKey.(type) {
+	case *ecdh.PublicKey:
+		if key == nil {
+			return "", c.ErrNilPublicKey
+		}
+
+		if key.Curve() == ecdh.X25519() {
+			// members in lexicographic order, as RFC 7638 requires
+			thumbprint := sha256.Sum256([]byte(`{"crv":"X25519","kty":"OKP","x":"` + base64.RawURLEncoding.EncodeToString(key.Bytes()) + `"}`))
+
+			return base64.RawURLEncoding.EncodeToString(thumbprint[:]), nil
+		}
+
+		ecdsaKey, err := toECDSA(key)
+		if err != nil {
+			return "", err
+		}
+
+		return e.KeyID(ecdsaKey)
+	case *rsa.PublicKey:
+		if key == nil {
+			return "", c.ErrNilPublicKey
+		}
+	case *ecdsa.PublicKey:
+		if key == nil {
+			return "", c.ErrNilPublicKey
+		}
+	case nil:
+		return "", c.ErrNilPublicKey
+	default:
+		return "", c.ErrUnsupportedPublicKey
+	}
+
+	thumbprint, err := (&jose.JSONWebKey{Key: publicKey}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", c.ErrUnsupportedPublicKey
+	}
+
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// wrap wraps the data key for the public key in a new envelope without payload
+func (e *Envelopes) wrap(dataKey []byte, publicKey crypto.PublicKey, kid string) (*Envelope, error) {
+	if ecdsaKey, ok := publicKey.(*ecdsa.PublicKey); ok && ecdsaKey != nil {
+		ecdhKey, err := ecdsaKey.ECDH()
+		if err != nil {
+			return nil, c.ErrUnsupportedCurve
+		}
+
+		publicKey = ecdhKey
+	}
+
+	if err := e.policy.CheckPublicKey(publicKey); err != nil {
+		return nil, err
+	}
+
+	if kid == "" {
+		var err error
+		if kid, err = e.KeyID(publicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	envelope := &Envelope{Version: Version, KeyID: kid}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key, dataKey, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		envelope.Algorithm = AlgorithmRSAOAEP256
+		envelope.WrappedKey = wrappedKey
+	case *ecdh.PublicKey:
+		curve, ok := curveName(key.Curve())
+		if !ok {
+			return nil, c.ErrUnsupportedCurve
+		}
+
+		ephemeralKey, err := key.Curve().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		envelope.Algorithm = AlgorithmECDHHKDFA256KW
+		envelope.Curve = curve
+		envelope.EphemeralPublicKey = ephemeralKey.PublicKey().Bytes()
+
+		kek, err := deriveKEK(envelope, ephemeralKey, key, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if envelope.WrappedKey, err = josecipher.KeyWrap(kek, dataKey); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, c.ErrUnsupportedPublicKey
+	}
+
+	return envelope, nil
+}
+
+// unwrap unwraps the data key of the envelope with the private key
+func (e *Envelopes) unwrap(envelope *Envelope, privateKey crypto.PrivateKey) ([]byte, error) {
+	if err := validate(envelope); err != nil {
+		return nil, err
+	}
+
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if key == nil {
+			return nil, c.ErrNilPrivateKey
+		}
+	case *ecdsa.PrivateKey:
+		if key == nil {
+			return nil, c.ErrNilPrivateKey
+		}
+
+		ecdhKey, err := key.ECDH()
+		if err != nil {
+			return nil, c.ErrUnsupportedCurve
+		}
+
+		privateKey = ecdhKey
+	}
+
+	var (
+		dataKey []byte
+		err     error
+	)
+
+	switch envelope.Algorithm {
+	case AlgorithmRSAOAEP256:
+		decrypter, ok := privateKey.(crypto.Decrypter)
+		if !ok {
+			return nil, c.ErrUnsupportedPrivateKey
+		}
+
+		if _, ok := decrypter.Public().(*rsa.PublicKey); !ok {
+			return nil, c.ErrKeyMismatch
+		}
+
+		dataKey, err = decrypter.Decrypt(rand.Reader, envelope.WrappedKey, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	case AlgorithmECDHHKDFA256KW:
+		key, ok := privateKey.(*ecdh.PrivateKey)
+		if !ok {
+			return nil, c.ErrUnsupportedPrivateKey
+		}
+
+		if key == nil {
+			return nil, c.ErrNilPrivateKey
+		}
+
+		if key.Curve() != curves[envelope.Curve] {
+			return nil, c.ErrKeyMismatch
+		}
+
+		ephemeralKey, err := key.Curve().NewPublicKey(envelope.EphemeralPublicKey)
+		if err != nil {
+			return nil, c.ErrInvalidPoint
+		}
+
+		kek, err := deriveKEK(envelope, key, ephemeralKey, key.PublicKey())
+		if err != nil {
+			return nil, err
+		}
+
+		dataKey, err = josecipher.KeyUnwrap(kek, envelope.WrappedKey)
+		if err != nil {
+			return nil, c.ErrUnwrapDataKey
+		}
+	}
+
+	if err != nil || len(dataKey) != DataKeySize {
+		return nil, c.ErrUnwrapDataKey
+	}
+
+	return dataKey, nil
+}
+
+// deriveKEK derives the AES-256 key encryption key from the ECDH shared secret, binding it to the
+// algorithm, curve, ephemeral public key and recipient public key
+func deriveKEK(envelope *Envelope, privateKey *ecdh.PrivateKey, peerPublicKey, recipient *ecdh.PublicKey) (cipher.Block, error) {
+	shared, err := privateKey.ECDH(peerPublicKey)
+	if err != nil {
+		return nil, c.ErrLowOrderPoint
+	}
+
+	info := []byte(hkdfInfo)
+	info = append(info, envelope.Algorithm...)
+	info = append(info, envelope.Curve...)
+	info = append(info, envelope.EphemeralPublicKey...)
+	info = append(info, recipient.Bytes()...)
+
+	kek := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, nil, info), kek); err != nil {
+		return nil, err
+	}
+
+	return aes.NewCipher(kek)
+}
+
+// newGCM gets the AES-256-GCM cipher of the data key
+func newGCM(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// validate checks the version, algorithm and fields of the envelope
+func validate(envelope *Envelope) error {
+	if envelope == nil {
+		return c.ErrInvalidEnvelope
+	}
+
+	if envelope.Version != Version || envelope.KeyID == "" || len(envelope.WrappedKey) == 0 {
+		return c.ErrInvalidEnvelope
+	}
+
+	switch envelope.Algorithm {
+	case AlgorithmRSAOAEP256:
+		if envelope.Curve != "" || len(envelope.EphemeralPublicKey) != 0 {
+			return c.ErrInvalidEnvelope
+		}
+	case AlgorithmECDHHKDFA256KW:
+		if _, ok := curves[envelope.Curve]; !ok || len(envelope.EphemeralPublicKey) == 0 {
+			return c.ErrInvalidEnvelope
+		}
+	default:
+		return c.ErrInvalidEnvelope
+	}
+
+	return nil
+}
+
+// curveName gets the envelope curve name of the ecdh curve
+func curveName(curve ecdh.Curve) (string, bool) {
+	for name, known := range curves {
+		if known == curve {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// toECDSA converts an ecdh public key on a NIST curve to an ecdsa public key
+func toECDSA(publicKey *ecdh.PublicKey) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+
+	switch publicKey.Curve() {
+	case ecdh.P256():
+		curve = elliptic.P256()
+	case ecdh.P384():
+		curve = elliptic.P384()
+	case ecdh.P521():
+		curve = elliptic.P521()
+	default:
+		return nil, c.ErrUnsupportedCurve
+	}
+
+	return ec.NewECDSA().FromUncompressedPoint(curve, publicKey.Bytes())
+}
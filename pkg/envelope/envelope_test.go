@@ -0,0 +1,336 @@
+package envelope
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	c "github.com/ELares/crypto/pkg"
+	"github.com/ELares/crypto/pkg/kms"
+	"github.com/ELares/crypto/pkg/policy"
+	"github.com/ELares/crypto/pkg/signer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestSealOpen(t *testing.T) {
+	e := NewEnvelope()
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p521, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	x25519, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	ecdhP384, _ := ecdh.P384().GenerateKey(rand.Reader)
+
+	testcases := []struct {
+		name string
+
+		privateKey crypto.PrivateKey
+		publicKey  crypto.PublicKey
+
+		algorithm Algorithm
+		curve     string
+	}{
+		{name: "RSA-2048", privateKey: rsaKey, publicKey: &rsaKey.PublicKey, algorithm: AlgorithmRSAOAEP256},
+		{name: "ECDSA P-256", privateKey: p256, publicKey: &p256.PublicKey, algorithm: AlgorithmECDHHKDFA256KW, curve: "P-256"},
+		{name: "ECDSA P-521", privateKey: p521, publicKey: &p521.PublicKey, algorithm: AlgorithmECDHHKDFA256KW, curve: "P-521"},
+		{name: "ECDH P-384", privateKey: ecdhP384, publicKey: ecdhP384.PublicKey(), algorithm: AlgorithmECDHHKDFA256KW, curve: "P-384"},
+		{name: "X25519", privateKey: x25519, publicKey: x25519.PublicKey(), algorithm: AlgorithmECDHHKDFA256KW, curve: "X25519"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			kid, err := e.KeyID(tc.publicKey)
+			require.Nil(t, err)
+
+			envelope, err := e.Seal(tc.publicKey, "", []byte("4111 1111 1111 1111"), []byte("users/42/card"))
+			require.Nil(t, err)
+
+			assert.Equal(t, Version, envelope.Version)
+			assert.Equal(t, kid, envelope.KeyID)
+			assert.Equal(t, tc.algorithm, envelope.Algorithm)
+			assert.Equal(t, tc.curve, envelope.Curve)
+			assert.NotContains(t, string(envelope.Ciphertext), "4111")
+
+			plaintext, err := e.Open(envelope, tc.privateKey, []byte("users/42/card"))
+			assert.Nil(t, err)
+			assert.Equal(t, []byte("4111 1111 1111 1111"), plaintext)
+
+			_, err = e.Open(envelope, tc.privateKey, []byte("users/43/card"))
+			assert.Equal(t, c.ErrInvalidCiphertext, err)
+
+			tampered := *envelope
+			tampered.Ciphertext = append([]byte{}, envelope.Ciphertext...)
+			tampered.Ciphertext[0] ^= 1
+			_, err = e.Open(&tampered, tc.privateKey, []byte("users/42/card"))
+			assert.Equal(t, c.ErrInvalidCiphertext, err)
+
+			// every envelope has its own data key
+			other, err := e.Seal(tc.publicKey, "", []byte("4111 1111 1111 1111"), []byte("users/42/card"))
+			require.Nil(t, err)
+			assert.NotEqual(t, envelope.WrappedKey, other.WrappedKey)
+			assert.NotEqual(t, envelope.Ciphertext, other.Ciphertext)
+		})
+	}
+}
+
+func TestWrongKey(t *testing.T) {
+	e := NewEnvelope()
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherRSAKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	x25519, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	otherX25519, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	rsaEnvelope, err := e.Seal(&rsaKey.PublicKey, "", []byte("secret"), nil)
+	require.Nil(t, err)
+
+	x25519Envelope, err := e.Seal(x25519.PublicKey(), "", []byte("secret"), nil)
+	require.Nil(t, err)
+
+	testcases := []struct {
+		name string
+
+		envelope   *Envelope
+		privateKey crypto.PrivateKey
+
+		err error
+	}{
+		{name: "Other RSA key", envelope: rsaEnvelope, privateKey: otherRSAKey, err: c.ErrUnwrapDataKey},
+		{name: "Other X25519 key", envelope: x25519Envelope, privateKey: otherX25519, err: c.ErrUnwrapDataKey},
+		{name: "X25519 key for RSA", envelope: rsaEnvelope, privateKey: x25519, err: c.ErrUnsupportedPrivateKey},
+		{name: "RSA key for X25519", envelope: x25519Envelope, privateKey: rsaKey, err: c.ErrUnsupportedPrivateKey},
+		{name: "P-256 key for X25519", envelope: x25519Envelope, privateKey: p256, err: c.ErrKeyMismatch},
+		{name: "Nil RSA key", envelope: rsaEnvelope, privateKey: (*rsa.PrivateKey)(nil), err: c.ErrNilPrivateKey},
+		{name: "Nil ECDH key", envelope: x25519Envelope, privateKey: (*ecdh.PrivateKey)(nil), err: c.ErrNilPrivateKey},
+		{name: "Nil envelope", envelope: nil, privateKey: rsaKey, err: c.ErrInvalidEnvelope},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := e.Open(tc.envelope, tc.privateKey, nil)
+			assert.Equal(t, tc.err, err)
+		})
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	e := NewEnvelope()
+
+	previous, _ := rsa.GenerateKey(rand.Reader, 2048)
+	current, _ := ecdh.X25519().GenerateKey(rand.Reader)
+
+	envelope, err := e.Seal(&previous.PublicKey, "orders-v1", []byte("secret"), []byte("orders/7"))
+	require.Nil(t, err)
+
+	rewrapped, err := e.Rewrap(envelope, previous, current.PublicKey(), "orders-v2")
+	require.Nil(t, err)
+
+	assert.Equal(t, "orders-v2", rewrapped.KeyID)
+	assert.Equal(t, AlgorithmECDHHKDFA256KW, rewrapped.Algorithm)
+	assert.Equal(t, envelope.Nonce, rewrapped.Nonce)
+	assert.Equal(t, envelope.Ciphertext, rewrapped.Ciphertext)
+	assert.NotEqual(t, envelope.WrappedKey, rewrapped.WrappedKey)
+
+	plaintext, err := e.Open(rewrapped, current, []byte("orders/7"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+
+	_, err = e.Open(rewrapped, previous, []byte("orders/7"))
+	assert.Equal(t, c.ErrUnsupportedPrivateKey, err)
+
+	_, err = e.Rewrap(envelope, current, &previous.PublicKey, "")
+	assert.Equal(t, c.ErrUnsupportedPrivateKey, err)
+}
+
+func TestKeyManager(t *testing.T) {
+	ctx := context.Background()
+	e := NewEnvelope()
+	km := kms.NewLocalKeyManager()
+
+	key, err := km.CreateKey(ctx, kms.RSA2048, kms.UsageDecrypt)
+	require.Nil(t, err)
+
+	publicKey, err := km.GetPublicKey(ctx, key.ID)
+	require.Nil(t, err)
+
+	envelope, err := e.Seal(publicKey, key.ID, []byte("secret"), nil)
+	require.Nil(t, err)
+
+	// the data key of older envelopes stays available after the key manager rotates the key
+	_, err = km.Rotate(ctx, key.ID)
+	require.Nil(t, err)
+
+	decrypter, err := signer.NewAdapter().DecrypterFromKeyManager(ctx, km, envelope.KeyID)
+	require.Nil(t, err)
+
+	plaintext, err := e.Open(envelope, decrypter, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+
+	rewrapped, err := e.Rewrap(envelope, decrypter, decrypter.Public(), key.ID)
+	require.Nil(t, err)
+	assert.Equal(t, envelope.Ciphertext, rewrapped.Ciphertext)
+}
+
+func TestMarshal(t *testing.T) {
+	e := NewEnvelope()
+
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+	envelope, err := e.Seal(&p384.PublicKey, "", []byte("secret"), nil)
+	require.Nil(t, err)
+
+	data, err := e.Marshal(envelope)
+	require.Nil(t, err)
+
+	var fields map[string]interface{}
+	require.Nil(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, float64(Version), fields["v"])
+	assert.Equal(t, "ECDH-HKDF-SHA256+A256KW", fields["alg"])
+	assert.Equal(t, "P-384", fields["crv"])
+	assert.Equal(t, envelope.KeyID, fields["kid"])
+
+	parsed, err := e.Unmarshal(data)
+	require.Nil(t, err)
+	assert.Equal(t, envelope, parsed)
+
+	plaintext, err := e.Open(parsed, p384, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+
+	testcases := []struct {
+		name string
+
+		modify func(*Envelope)
+	}{
+		{name: "Unsupported version", modify: func(envelope *Envelope) { envelope.Version = 2 }},
+		{name: "Unsupported algorithm", modify: func(envelope *Envelope) { envelope.Algorithm = "A256GCMKW" }},
+		{name: "Unsupported JOSE ECDH-ES", modify: func(envelope *Envelope) { envelope.Algorithm = "ECDH-ES+A256KW" }},
+		{name: "Unsupported curve", modify: func(envelope *Envelope) { envelope.Curve = "P-224" }},
+		{name: "Missing kid", modify: func(envelope *Envelope) { envelope.KeyID = "" }},
+		{name: "Missing wrapped key", modify: func(envelope *Envelope) { envelope.WrappedKey = nil }},
+		{name: "Missing ephemeral key", modify: func(envelope *Envelope) { envelope.EphemeralPublicKey = nil }},
+		{name: "RSA with curve", modify: func(envelope *Envelope) { envelope.Algorithm = AlgorithmRSAOAEP256 }},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			invalid := *envelope
+			tc.modify(&invalid)
+
+			_, err := e.Marshal(&invalid)
+			assert.Equal(t, c.ErrInvalidEnvelope, err)
+
+			data, _ := json.Marshal(&invalid)
+			_, err = e.Unmarshal(data)
+			assert.Equal(t, c.ErrInvalidEnvelope, err)
+		})
+	}
+
+	_, err = e.Unmarshal([]byte("not json"))
+	assert.Equal(t, c.ErrInvalidEnvelope, err)
+
+	invalidPoint := *envelope
+	invalidPoint.EphemeralPublicKey = []byte{4, 1, 2, 3}
+	_, err = e.Open(&invalidPoint, p384, nil)
+	assert.Equal(t, c.ErrInvalidPoint, err)
+
+	invalidNonce := *envelope
+	invalidNonce.Nonce = invalidNonce.Nonce[:8]
+	_, err = e.Open(&invalidNonce, p384, nil)
+	assert.Equal(t, c.ErrInvalidEnvelope, err)
+}
+
+func TestKeyID(t *testing.T) {
+	e := NewEnvelope()
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecdhP256, _ := p256.ECDH()
+
+	thumbprint, _ := (&jose.JSONWebKey{Key: &rsaKey.PublicKey}).Thumbprint(crypto.SHA256)
+	kid, err := e.KeyID(&rsaKey.PublicKey)
+	assert.Nil(t, err)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(thumbprint), kid)
+
+	// ecdsa keys and their ecdh conversion share the kid
+	ecdsaKID, err := e.KeyID(&p256.PublicKey)
+	assert.Nil(t, err)
+
+	ecdhKID, err := e.KeyID(ecdhP256.PublicKey())
+	assert.Nil(t, err)
+	assert.Equal(t, ecdsaKID, ecdhKID)
+
+	// RFC 8037 appendix A.3 X25519 public key
+	x25519Public, _ := base64.RawURLEncoding.DecodeString("hSDwCYkwp1R0i33ctD73Wg2_Og0mOBr066SpjqqbTmo")
+	x25519, err := ecdh.X25519().NewPublicKey(x25519Public)
+	require.Nil(t, err)
+
+	x25519KID, err := e.KeyID(x25519)
+	assert.Nil(t, err)
+	assert.Len(t, x25519KID, 43)
+
+	_, err = e.KeyID(nil)
+	assert.Equal(t, c.ErrNilPublicKey, err)
+
+	_, err = e.KeyID((*ecdh.PublicKey)(nil))
+	assert.Equal(t, c.ErrNilPublicKey, err)
+
+	_, err = e.KeyID("key")
+	assert.Equal(t, c.ErrUnsupportedPublicKey, err)
+}
+
+func TestInvalidPublicKey(t *testing.T) {
+	e := NewEnvelope()
+
+	p224, _ := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+
+	_, err := e.Seal(nil, "", []byte("secret"), nil)
+	assert.Equal(t, c.ErrNilPublicKey, err)
+
+	_, err = e.Seal((*ecdsa.PublicKey)(nil), "", []byte("secret"), nil)
+	assert.Equal(t, c.ErrNilPublicKey, err)
+
+	_, err = e.Seal(&p224.PublicKey, "", []byte("secret"), nil)
+	assert.Equal(t, c.ErrUnsupportedCurve, err)
+
+	_, err = e.Seal("key", "", []byte("secret"), nil)
+	assert.Equal(t, c.ErrUnsupportedPublicKey, err)
+}
+
+func TestWithPolicy(t *testing.T) {
+	cnsa, _ := policy.NewPolicy(policy.CNSA2)
+	e := NewEnvelopeWithPolicy(cnsa)
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	x25519, _ := ecdh.X25519().GenerateKey(rand.Reader)
+
+	envelope, err := e.Seal(&p384.PublicKey, "", []byte("secret"), nil)
+	assert.Nil(t, err)
+
+	_, err = e.Seal(&rsaKey.PublicKey, "", []byte("secret"), nil)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, err = e.Seal(x25519.PublicKey(), "", []byte("secret"), nil)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	// opening is allowed, rewrapping checks the new wrapping key
+	plaintext, err := e.Open(envelope, p384, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+
+	_, err = e.Rewrap(envelope, p384, x25519.PublicKey(), "")
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+}
@@ -34,4 +34,204 @@ var (
 
 	// ErrNilPublicKeyCurve error when the public key Curve is nil
 	ErrNilPublicKeyCurve = errors.New("public key Curve is nil")
+
+	// ErrDecodePEMCRL error when trying to decode a crl pem
+	ErrDecodePEMCRL = errors.New("failed to decode PEM block containing CRL")
+
+	// ErrDecodePEMCertificate error when trying to decode a certificate pem
+	ErrDecodePEMCertificate = errors.New("failed to decode PEM block containing certificate")
+
+	// ErrEmptyPEMBundle error when a pem bundle does not contain any block
+	ErrEmptyPEMBundle = errors.New("PEM bundle does not contain any block")
+
+	// ErrEmptyDER error when the DER bytes are empty
+	ErrEmptyDER = errors.New("DER bytes are empty")
+
+	// ErrNilCertificate error when the certificate is nil
+	ErrNilCertificate = errors.New("certificate is nil")
+
+	// ErrNilSigner error when the signer is nil
+	ErrNilSigner = errors.New("signer is nil")
+
+	// ErrNilSerialNumber error when the serial number is nil
+	ErrNilSerialNumber = errors.New("serial number is nil")
+
+	// ErrNilDatabase error when the revocation database is nil
+	ErrNilDatabase = errors.New("revocation database is nil")
+
+	// ErrMissingOCSPSigning error when a delegated OCSP responder certificate lacks the OCSP signing
+	// extended key usage
+	ErrMissingOCSPSigning = errors.New("responder certificate lacks the OCSP signing extended key usage")
+
+	// ErrResponderNotIssued error when a delegated OCSP responder certificate is not signed by the
+	// issuer it answers for
+	ErrResponderNotIssued = errors.New("responder certificate is not issued by the issuer")
+
+	// ErrInvalidRevocationReason error when the revocation reason is not a RFC 5280 reason code
+	ErrInvalidRevocationReason = errors.New("invalid revocation reason")
+
+	// ErrUnsupportedSigner error when the signer public key type is not supported
+	ErrUnsupportedSigner = errors.New("unsupported signer public key type")
+
+	// ErrKeyMismatch error when the private key does not belong to the public key or certificate
+	ErrKeyMismatch = errors.New("private key does not match public key")
+
+	// ErrUnknownPreset error when the preset is not known
+	ErrUnknownPreset = errors.New("unknown preset")
+
+	// ErrUnsupportedPrivateKey error when the private key type is not supported
+	ErrUnsupportedPrivateKey = errors.New("unsupported private key type")
+
+	// ErrLegacyPKCS12 error when a PKCS#12 bundle uses legacy 3DES/RC2 encryption
+	ErrLegacyPKCS12 = errors.New("PKCS#12 bundle uses legacy encryption")
+
+	// ErrKeyTooSmall error when the key size is below the minimum
+	ErrKeyTooSmall = errors.New("key size is below the minimum")
+
+	// ErrInvalidModulus error when the rsa modulus is not a positive odd number
+	ErrInvalidModulus = errors.New("modulus is invalid")
+
+	// ErrInvalidPublicExponent error when the rsa public exponent is too small or even
+	ErrInvalidPublicExponent = errors.New("public exponent is invalid")
+
+	// ErrPointAtInfinity error when the public key is the point at infinity
+	ErrPointAtInfinity = errors.New("public key is the point at infinity")
+
+	// ErrPointNotOnCurve error when the public key point is not on the curve
+	ErrPointNotOnCurve = errors.New("public key point is not on the curve")
+
+	// ErrScalarOutOfRange error when the private key scalar is not in [1, N-1]
+	ErrScalarOutOfRange = errors.New("private key scalar is out of range")
+
+	// ErrInvalidKeyLength error when the key does not have the expected length
+	ErrInvalidKeyLength = errors.New("key length is invalid")
+
+	// ErrUnsupportedPublicKey error when the public key type is not supported
+	ErrUnsupportedPublicKey = errors.New("unsupported public key type")
+
+	// ErrUnsupportedAlgorithm error when the signature algorithm is not supported for the key type
+	ErrUnsupportedAlgorithm = errors.New("unsupported signature algorithm")
+
+	// ErrAlgorithmMismatch error when the signature algorithm does not match the key curve or size
+	ErrAlgorithmMismatch = errors.New("signature algorithm does not match the key")
+
+	// ErrNoJWKAlgorithm error when no jwk signature algorithm is defined for the key curve
+	ErrNoJWKAlgorithm = errors.New("no jwk signature algorithm for the key curve")
+
+	// ErrUnsupportedCurve error when the elliptic curve is not supported
+	ErrUnsupportedCurve = errors.New("unsupported elliptic curve")
+
+	// ErrInvalidPoint error when an encoded elliptic curve point is malformed
+	ErrInvalidPoint = errors.New("invalid elliptic curve point encoding")
+
+	// ErrInvalidSignature error when a signature is malformed
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrContextTooLong error when the signature context is longer than 255 bytes
+	ErrContextTooLong = errors.New("signature context is too long")
+
+	// ErrLowOrderPoint error when a key agreement public key is a low order point
+	ErrLowOrderPoint = errors.New("public key is a low order point")
+
+	// ErrUnsupportedParameterSet error when the post-quantum parameter set is not supported
+	ErrUnsupportedParameterSet = errors.New("unsupported parameter set")
+
+	// ErrInvalidPublicKey error when the encoded public key is malformed
+	ErrInvalidPublicKey = errors.New("public key is invalid")
+
+	// ErrInvalidCiphertext error when the key encapsulation ciphertext is malformed
+	ErrInvalidCiphertext = errors.New("ciphertext is invalid")
+
+	// ErrNoActiveKey error when the keyring has no active key version
+	ErrNoActiveKey = errors.New("keyring has no active key")
+
+	// ErrKeyNotFound error when the keyring or key store has no key with the id or name
+	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrInvalidKeyState error when the state of the key version does not allow the operation
+	ErrInvalidKeyState = errors.New("invalid key version state")
+
+	// ErrKeyExists error when the key store already has a key with the name
+	ErrKeyExists = errors.New("key already exists")
+
+	// ErrInvalidKeyName error when the key name is empty or not a plain file name
+	ErrInvalidKeyName = errors.New("invalid key name")
+
+	// ErrInsecurePermissions error when a key file or directory is accessible by group or others
+	ErrInsecurePermissions = errors.New("key file or directory permissions are too open")
+
+	// ErrPassphraseRequired error when the private key is encrypted and no passphrase was given
+	ErrPassphraseRequired = errors.New("private key is encrypted, passphrase required")
+
+	// ErrIncorrectPassphrase error when the encrypted private key cannot be decrypted with the passphrase
+	ErrIncorrectPassphrase = errors.New("incorrect passphrase")
+
+	// ErrUnsupportedEncryption error when the encrypted private key uses an unsupported algorithm
+	ErrUnsupportedEncryption = errors.New("unsupported private key encryption")
+
+	// ErrKeyDisabled error when the managed key is disabled
+	ErrKeyDisabled = errors.New("key is disabled")
+
+	// ErrInvalidKeyUsage error when the managed key usage does not allow the operation
+	ErrInvalidKeyUsage = errors.New("key usage does not allow the operation")
+
+	// ErrInvalidDigest error when the digest length does not match the hash function
+	ErrInvalidDigest = errors.New("digest length does not match the hash function")
+
+	// ErrUnsupportedKeySpec error when the key spec or spec and usage combination is not supported
+	ErrUnsupportedKeySpec = errors.New("unsupported key spec")
+
+	// ErrInvalidEnvelope error when the envelope is malformed or uses an unsupported version or
+	// algorithm
+	ErrInvalidEnvelope = errors.New("envelope is invalid")
+
+	// ErrUnwrapDataKey error when the wrapped data key cannot be unwrapped with the private key
+	ErrUnwrapDataKey = errors.New("failed to unwrap data key")
+
+	// ErrEmptySecret error when the secret to split is empty
+	ErrEmptySecret = errors.New("secret is empty")
+
+	// ErrInvalidThreshold error when the threshold is not between 2 and the number of shares, or
+	// more than 255 shares are requested
+	ErrInvalidThreshold = errors.New("invalid share threshold")
+
+	// ErrInvalidShare error when a share is malformed or fails its checksum
+	ErrInvalidShare = errors.New("share is invalid")
+
+	// ErrNotEnoughShares error when fewer shares than the threshold are given
+	ErrNotEnoughShares = errors.New("not enough shares")
+
+	// ErrShareMismatch error when the shares do not come from the same split or do not recombine
+	// into the secret
+	ErrShareMismatch = errors.New("shares do not match")
+
+	// ErrInvalidSeed error when the seed of a hierarchical deterministic key is not 16 to 64 bytes long
+	ErrInvalidSeed = errors.New("seed length is invalid")
+
+	// ErrInvalidPath error when the derivation path is malformed or too deep
+	ErrInvalidPath = errors.New("invalid derivation path")
+
+	// ErrHardenedOnly error when a non-hardened child is derived on a curve only supporting
+	// hardened derivation
+	ErrHardenedOnly = errors.New("curve only supports hardened derivation")
+
+	// ErrPrivateDerivation error when a hardened child is derived from a public extended key
+	ErrPrivateDerivation = errors.New("hardened derivation requires a private key")
+
+	// ErrInvalidExtendedKey error when the serialized extended key is malformed or fails its
+	// checksum
+	ErrInvalidExtendedKey = errors.New("extended key is invalid")
+
+	// ErrInvalidEntropy error when the entropy of a mnemonic is not 16 to 32 bytes long in steps of 4
+	ErrInvalidEntropy = errors.New("entropy length is invalid")
+
+	// ErrInvalidMnemonic error when the mnemonic does not have 12 to 24 words in steps of 3 or has a
+	// word out of the wordlist
+	ErrInvalidMnemonic = errors.New("mnemonic is invalid")
+
+	// ErrMnemonicChecksum error when the checksum of the mnemonic does not match its entropy
+	ErrMnemonicChecksum = errors.New("mnemonic checksum mismatch")
+
+	// ErrPolicyViolation error when a key or operation is not allowed by the crypto policy
+	ErrPolicyViolation = errors.New("crypto policy violation")
 )
@@ -0,0 +1,268 @@
+package filestore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"hash"
+
+	c "github.com/ELares/crypto/pkg"
+	p "github.com/ELares/crypto/pkg/pem"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// Iterations PBKDF2 iteration count of newly encrypted private keys
+	Iterations = 600000
+
+	saltSize = 16
+)
+
+var (
+	oidPBES2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidECDSA      = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidRSA        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+)
+
+type (
+	// encryptedPrivateKeyInfo RFC 5958 section 3
+	encryptedPrivateKeyInfo struct {
+		EncryptionAlgorithm pkix.AlgorithmIdentifier
+		EncryptedData       []byte
+	}
+
+	// privateKeyInfo RFC 5208 section 5
+	privateKeyInfo struct {
+		Version    int
+		Algorithm  pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}
+
+	// ecPrivateKey RFC 5915 section 3
+	ecPrivateKey struct {
+		Version       int
+		PrivateKey    []byte
+		NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+		PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+	}
+
+	// pbes2Params RFC 8018 appendix A.4
+	pbes2Params struct {
+		KeyDerivationFunc pkix.AlgorithmIdentifier
+		EncryptionScheme  pkix.AlgorithmIdentifier
+	}
+
+	// pbkdf2Params RFC 8018 appendix A.2, the key length being omitted
+	pbkdf2Params struct {
+		Salt           []byte
+		IterationCount int
+		PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+	}
+)
+
+// encryptPEM encrypts a PKCS#8 private key PEM into an ENCRYPTED PRIVATE KEY PEM with PBES2
+// (PBKDF2-HMAC-SHA256, AES-256-CBC), as read by openssl pkey, PKCS#1 rsa and SEC1 ecdsa private
+// keys being wrapped into PKCS#8 first
+func encryptPEM(privatePEM p.PrivatePEM, passphrase []byte, iterations int) (p.PrivatePEM, error) {
+	block, _ := pem.Decode(privatePEM)
+	if block == nil || block.Type != c.PRIVATEKEY {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	plaintext, err := toPKCS8(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, saltSize)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	prf := pkix.AlgorithmIdentifier{Algorithm: oidHMACSHA256, Parameters: asn1.NullRawValue}
+	kdf, err := asn1.Marshal(pbkdf2Params{Salt: salt, IterationCount: iterations, PRF: prf})
+	if err != nil {
+		return nil, err
+	}
+
+	ivParams, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdf}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParams}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key := pbkdf2.Key(passphrase, salt, iterations, 32, sha256.New)
+	aesCipher, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	encrypted := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(aesCipher, iv).CryptBlocks(encrypted, encrypted)
+
+	der, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		EncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData:       encrypted,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: c.ENCRYPTEDPRIVATEKEY, Bytes: der}), nil
+}
+
+// decryptPEM decrypts a PBES2 (PBKDF2 with HMAC-SHA1 or HMAC-SHA256, AES-128-CBC or AES-256-CBC)
+// ENCRYPTED PRIVATE KEY PEM into a private key PEM, rsa keys and ecdsa keys wrapped by encryptPEM
+// being unwrapped back to PKCS#1 and SEC1
+func decryptPEM(encryptedPEM p.PrivatePEM, passphrase []byte) (p.PrivatePEM, error) {
+	block, _ := pem.Decode(encryptedPEM)
+	if block == nil || block.Type != c.ENCRYPTEDPRIVATEKEY {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(block.Bytes, &info); err != nil || len(rest) > 0 {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	if !info.EncryptionAlgorithm.Algorithm.Equal(oidPBES2) {
+		return nil, c.ErrUnsupportedEncryption
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.EncryptionAlgorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, c.ErrUnsupportedEncryption
+	}
+
+	var kdf pbkdf2Params
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, c.ErrUnsupportedEncryption
+	}
+
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil || kdf.IterationCount <= 0 {
+		return nil, c.ErrUnsupportedEncryption
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case kdf.PRF.Algorithm == nil, kdf.PRF.Algorithm.Equal(oidHMACSHA1):
+		prf = sha1.New
+	case kdf.PRF.Algorithm.Equal(oidHMACSHA256):
+		prf = sha256.New
+	default:
+		return nil, c.ErrUnsupportedEncryption
+	}
+
+	var keySize int
+	switch {
+	case params.EncryptionScheme.Algorithm.Equal(oidAES128CBC):
+		keySize = 16
+	case params.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		keySize = 32
+	default:
+		return nil, c.ErrUnsupportedEncryption
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, c.ErrUnsupportedEncryption
+	}
+
+	if len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	key := pbkdf2.Key(passphrase, kdf.Salt, kdf.IterationCount, keySize, prf)
+	aesCipher, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(aesCipher, iv).CryptBlocks(decrypted, info.EncryptedData)
+
+	// CBC is not authenticated, a wrong passphrase shows as bad padding or a malformed PKCS#8 structure
+	padding := int(decrypted[len(decrypted)-1])
+	if padding == 0 || padding > aes.BlockSize || subtle.ConstantTimeCompare(decrypted[len(decrypted)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) != 1 {
+		return nil, c.ErrIncorrectPassphrase
+	}
+
+	decrypted = decrypted[:len(decrypted)-padding]
+
+	var info8 privateKeyInfo
+	if rest, err := asn1.Unmarshal(decrypted, &info8); err != nil || len(rest) > 0 {
+		return nil, c.ErrIncorrectPassphrase
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: fromPKCS8(info8, decrypted)}), nil
+}
+
+// toPKCS8 wraps a PKCS#1 rsa private key or a SEC1 ecdsa private key, as written by
+// IRSA.ToPEMPrivateKey and IECDSA.ToPEMPrivateKey, into a PKCS#8 PrivateKeyInfo, other private keys
+// being returned unchanged
+func toPKCS8(der []byte) ([]byte, error) {
+	if _, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return asn1.Marshal(privateKeyInfo{
+			Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oidRSA, Parameters: asn1.NullRawValue},
+			PrivateKey: der,
+		})
+	}
+
+	var key ecPrivateKey
+	if rest, err := asn1.Unmarshal(der, &key); err != nil || len(rest) > 0 || key.Version != 1 || key.NamedCurveOID == nil {
+		return der, nil
+	}
+
+	params, err := asn1.Marshal(key.NamedCurveOID)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(privateKeyInfo{
+		Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oidECDSA, Parameters: asn1.RawValue{FullBytes: params}},
+		PrivateKey: der,
+	})
+}
+
+// fromPKCS8 unwraps the PKCS#1 key of a rsa PrivateKeyInfo and the SEC1 key of an ecdsa
+// PrivateKeyInfo when it carries its curve OID, other private keys being returned unchanged
+func fromPKCS8(info privateKeyInfo, der []byte) []byte {
+	if info.Algorithm.Algorithm.Equal(oidRSA) {
+		return info.PrivateKey
+	}
+
+	var key ecPrivateKey
+	if info.Algorithm.Algorithm.Equal(oidECDSA) {
+		if rest, err := asn1.Unmarshal(info.PrivateKey, &key); err == nil && len(rest) == 0 && key.NamedCurveOID != nil {
+			return info.PrivateKey
+		}
+	}
+
+	return der
+}
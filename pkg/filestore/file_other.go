@@ -0,0 +1,38 @@
+//go:build !unix && !windows
+
+package filestore
+
+import (
+	"os"
+
+	c "github.com/ELares/crypto/pkg"
+)
+
+// lockFile takes the mutex of the lock file, these systems having no advisory file lock: the
+// goroutines of the process are serialized, readers included, other processes are not
+func lockFile(file *os.File, _ bool) error {
+	processLock(file).Lock()
+
+	return nil
+}
+
+// unlockFile releases the lock taken by lockFile and closes the file
+func unlockFile(file *os.File) error {
+	defer processLock(file).Unlock()
+
+	return file.Close()
+}
+
+// syncDir is a no-op, directories cannot be flushed on every one of these systems
+func syncDir(string) error {
+	return nil
+}
+
+// checkPermissions rejects files and directories accessible by group or others
+func checkPermissions(info os.FileInfo) error {
+	if info.Mode().Perm()&0o077 != 0 {
+		return c.ErrInsecurePermissions
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+//go:build unix
+
+package filestore
+
+import (
+	"os"
+
+	c "github.com/ELares/crypto/pkg"
+)
+
+// syncDir flushes the directory entry of a renamed file
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// checkPermissions rejects files and directories accessible by group or others
+func checkPermissions(info os.FileInfo) error {
+	if info.Mode().Perm()&0o077 != 0 {
+		return c.ErrInsecurePermissions
+	}
+
+	return nil
+}
@@ -0,0 +1,356 @@
+package filestore
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	c "github.com/ELares/crypto/pkg"
+	p "github.com/ELares/crypto/pkg/pem"
+)
+
+const (
+	privateKeyExtension = ".key"
+	publicKeyExtension  = ".pub"
+	metadataExtension   = ".json"
+	lockFileName        = ".lock"
+
+	dirMode  fs.FileMode = 0o700
+	fileMode fs.FileMode = 0o600
+)
+
+// validName plain file names, no path separators and no leading dot
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+type (
+	// Metadata sidecar of a stored key pair, Encrypted being set by the store
+	Metadata struct {
+		Algorithm string    `json:"algorithm"`
+		KeyID     string    `json:"kid,omitempty"`
+		Created   time.Time `json:"created"`
+		Encrypted bool      `json:"encrypted"`
+	}
+
+	// IFileStore interface for methods to save, load, list and delete key pairs stored in a
+	// directory
+	IFileStore interface {
+		Save(name string, privatePEM p.PrivatePEM, publicPEM p.PublicPEM, metadata Metadata, passphrase []byte) error
+		Load(name string, passphrase []byte) (p.PrivatePEM, p.PublicPEM, Metadata, error)
+		LoadPublic(name string) (p.PublicPEM, Metadata, error)
+		List() ([]string, error)
+		Delete(name string) error
+	}
+
+	// FileStore struct to implement the IFileStore methods, each key pair being stored as
+	// <name>.key, <name>.pub and <name>.json files
+	FileStore struct {
+		dir        string
+		iterations int
+		now        func() time.Time
+	}
+)
+
+// NewFileStore gets a new FileStore pointer for the directory, created with 0700 permissions when
+// missing, an existing directory accessible by group or others is rejected
+func NewFileStore(dir string) (IFileStore, error) {
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: dir, Err: fs.ErrInvalid}
+	}
+
+	if err := checkPermissions(info); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{dir: dir, iterations: Iterations, now: time.Now}, nil
+}
+
+// Save atomically writes a key pair and its metadata with 0600 permissions, the private key being
+// encrypted with PBES2 (PBKDF2-HMAC-SHA256, AES-256-CBC) when a passphrase is given, an existing
+// key pair with the name is never overwritten
+func (f *FileStore) Save(name string, privatePEM p.PrivatePEM, publicPEM p.PublicPEM, metadata Metadata, passphrase []byte) error {
+	if !validName.MatchString(name) {
+		return c.ErrInvalidKeyName
+	}
+
+	if block, _ := pem.Decode(privatePEM); block == nil {
+		return c.ErrDecodePEMPrivateKey
+	}
+
+	if block, _ := pem.Decode(publicPEM); block == nil || block.Type != c.PUBLICKEY {
+		return c.ErrDecodePEMPublicKey
+	}
+
+	metadata.Encrypted = len(passphrase) > 0
+	if metadata.Encrypted {
+		encrypted, err := encryptPEM(privatePEM, passphrase, f.iterations)
+		if err != nil {
+			return err
+		}
+
+		privatePEM = encrypted
+	}
+
+	if metadata.Created.IsZero() {
+		metadata.Created = f.now().UTC()
+	}
+
+	encodedMetadata, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	unlock, err := f.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(f.path(name, privateKeyExtension)); err == nil {
+		return c.ErrKeyExists
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// the private key is renamed last, a key pair is only listed once all of its files exist
+	files := []struct {
+		extension string
+		data      []byte
+	}{
+		{extension: publicKeyExtension, data: publicPEM},
+		{extension: metadataExtension, data: append(encodedMetadata, '\n')},
+		{extension: privateKeyExtension, data: privatePEM},
+	}
+
+	for _, file := range files {
+		if err := f.writeFile(name+file.extension, file.data); err != nil {
+			return err
+		}
+	}
+
+	return syncDir(f.dir)
+}
+
+// Load reads a key pair and its metadata, decrypting the private key with the passphrase when it is
+// encrypted, a private key file accessible by group or others is rejected
+func (f *FileStore) Load(name string, passphrase []byte) (p.PrivatePEM, p.PublicPEM, Metadata, error) {
+	if !validName.MatchString(name) {
+		return nil, nil, Metadata{}, c.ErrInvalidKeyName
+	}
+
+	unlock, err := f.lock(false)
+	if err != nil {
+		return nil, nil, Metadata{}, err
+	}
+	defer unlock()
+
+	privatePEM, err := f.readFile(name, privateKeyExtension)
+	if err != nil {
+		return nil, nil, Metadata{}, err
+	}
+
+	publicPEM, metadata, err := f.loadPublic(name)
+	if err != nil {
+		return nil, nil, Metadata{}, err
+	}
+
+	if block, _ := pem.Decode(privatePEM); block != nil && block.Type == c.ENCRYPTEDPRIVATEKEY {
+		if len(passphrase) == 0 {
+			return nil, nil, Metadata{}, c.ErrPassphraseRequired
+		}
+
+		privatePEM, err = decryptPEM(privatePEM, passphrase)
+		if err != nil {
+			return nil, nil, Metadata{}, err
+		}
+	}
+
+	return privatePEM, publicPEM, metadata, nil
+}
+
+// LoadPublic reads the public key and metadata of a key pair, without touching the private key
+func (f *FileStore) LoadPublic(name string) (p.PublicPEM, Metadata, error) {
+	if !validName.MatchString(name) {
+		return nil, Metadata{}, c.ErrInvalidKeyName
+	}
+
+	unlock, err := f.lock(false)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer unlock()
+
+	if _, err := os.Lstat(f.path(name, privateKeyExtension)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, Metadata{}, c.ErrKeyNotFound
+		}
+
+		return nil, Metadata{}, err
+	}
+
+	return f.loadPublic(name)
+}
+
+// List gets the names of the stored key pairs, sorted
+func (f *FileStore) List() ([]string, error) {
+	unlock, err := f.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), privateKeyExtension)
+		if ok && entry.Type().IsRegular() && validName.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Delete removes a key pair and its metadata, the private key first
+func (f *FileStore) Delete(name string) error {
+	if !validName.MatchString(name) {
+		return c.ErrInvalidKeyName
+	}
+
+	unlock, err := f.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := os.Remove(f.path(name, privateKeyExtension)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c.ErrKeyNotFound
+		}
+
+		return err
+	}
+
+	for _, extension := range []string{publicKeyExtension, metadataExtension} {
+		if err := os.Remove(f.path(name, extension)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return syncDir(f.dir)
+}
+
+// loadPublic reads the public key and metadata files, the lock being held
+func (f *FileStore) loadPublic(name string) (p.PublicPEM, Metadata, error) {
+	publicPEM, err := f.readFile(name, publicKeyExtension)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	encodedMetadata, err := f.readFile(name, metadataExtension)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	var metadata Metadata
+	if err := json.Unmarshal(encodedMetadata, &metadata); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return publicPEM, metadata, nil
+}
+
+// readFile reads a file of the key pair, checking its permissions
+func (f *FileStore) readFile(name, extension string) ([]byte, error) {
+	path := f.path(name, extension)
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, c.ErrKeyNotFound
+		}
+
+		return nil, err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrInvalid}
+	}
+
+	if err := checkPermissions(info); err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(path)
+}
+
+// writeFile atomically writes a file of the store through a synced temporary file renamed over it
+func (f *FileStore) writeFile(fileName string, data []byte) error {
+	// CreateTemp creates the file with 0600 permissions
+	file, err := os.CreateTemp(f.dir, "."+fileName+"-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := file.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(f.dir, fileName))
+}
+
+// lock takes the store lock, shared for readers and exclusive for writers, serializing writers
+// across goroutines and processes
+func (f *FileStore) lock(exclusive bool) (func(), error) {
+	file, err := os.OpenFile(filepath.Join(f.dir, lockFileName), os.O_CREATE|os.O_RDWR, fileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(file, exclusive); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = unlockFile(file)
+	}, nil
+}
+
+// path gets the path of a file of the key pair
+func (f *FileStore) path(name, extension string) string {
+	return filepath.Join(f.dir, name+extension)
+}
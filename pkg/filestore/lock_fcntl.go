@@ -0,0 +1,50 @@
+//go:build aix || solaris
+
+package filestore
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile takes a fcntl record lock on the lock file, shared for readers and exclusive for
+// writers, closing the file when it fails. Record locks belong to the process, not to the open
+// file, and closing any descriptor of the file releases them, so the goroutines of the process
+// first take the mutex of the lock file, readers included
+func lockFile(file *os.File, exclusive bool) error {
+	mu := processLock(file)
+	mu.Lock()
+
+	lock := unix.Flock_t{Type: unix.F_RDLCK, Whence: io.SeekStart}
+	if exclusive {
+		lock.Type = unix.F_WRLCK
+	}
+
+	for {
+		err := unix.FcntlFlock(file.Fd(), unix.F_SETLKW, &lock)
+		if err == unix.EINTR {
+			continue
+		}
+
+		if err != nil {
+			file.Close()
+			mu.Unlock()
+		}
+
+		return err
+	}
+}
+
+// unlockFile releases the lock taken by lockFile and closes the file before another goroutine of
+// the process can lock it
+func unlockFile(file *os.File) error {
+	mu := processLock(file)
+	defer mu.Unlock()
+
+	err := unix.FcntlFlock(file.Fd(), unix.F_SETLK, &unix.Flock_t{Type: unix.F_UNLCK, Whence: io.SeekStart})
+	file.Close()
+
+	return err
+}
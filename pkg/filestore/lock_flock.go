@@ -0,0 +1,37 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package filestore
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an advisory flock on the lock file, shared for readers and exclusive for writers,
+// closing the file when it fails
+func lockFile(file *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+
+	for {
+		err := syscall.Flock(int(file.Fd()), how)
+		if err == syscall.EINTR {
+			continue
+		}
+
+		if err != nil {
+			file.Close()
+		}
+
+		return err
+	}
+}
+
+// unlockFile releases the lock taken by lockFile and closes the file
+func unlockFile(file *os.File) error {
+	defer file.Close()
+
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,25 @@
+//go:build aix || solaris || (!unix && !windows)
+
+package filestore
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// processLocks mutex of every lock file path, for the systems whose file locks do not exclude the
+// goroutines of the process from each other
+var processLocks sync.Map
+
+// processLock gets the mutex of the lock file path
+func processLock(file *os.File) *sync.Mutex {
+	path, err := filepath.Abs(file.Name())
+	if err != nil {
+		path = file.Name()
+	}
+
+	mu, _ := processLocks.LoadOrStore(path, &sync.Mutex{})
+
+	return mu.(*sync.Mutex)
+}
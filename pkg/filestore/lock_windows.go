@@ -0,0 +1,42 @@
+//go:build windows
+
+package filestore
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile takes a LockFileEx lock on the lock file, shared for readers and exclusive for writers,
+// closing the file when it fails
+func lockFile(file *os.File, exclusive bool) error {
+	var flags uint32
+	if exclusive {
+		flags = windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+
+	err := windows.LockFileEx(windows.Handle(file.Fd()), flags, 0, 1, 0, &windows.Overlapped{})
+	if err != nil {
+		file.Close()
+	}
+
+	return err
+}
+
+// unlockFile releases the lock taken by lockFile and closes the file
+func unlockFile(file *os.File) error {
+	defer file.Close()
+
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
+
+// syncDir is a no-op, directories cannot be flushed on windows
+func syncDir(string) error {
+	return nil
+}
+
+// checkPermissions is a no-op, the unix permission bits do not map to windows ACLs
+func checkPermissions(os.FileInfo) error {
+	return nil
+}
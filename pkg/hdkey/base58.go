@@ -0,0 +1,86 @@
+package hdkey
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+)
+
+const (
+	// base58Alphabet bitcoin base58 alphabet, without 0, O, I and l
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+)
+
+var (
+	// base58Radix radix of base58
+	base58Radix = big.NewInt(58)
+)
+
+// base58CheckEncode encodes the data followed by the first 4 bytes of its double SHA-256 in base58,
+// each leading zero byte being encoded as a 1
+func base58CheckEncode(data []byte) string {
+	data = append(append([]byte{}, data...), checksum(data)...)
+
+	n := new(big.Int).SetBytes(data)
+	remainder := new(big.Int)
+
+	var encoded []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, base58Radix, remainder)
+		encoded = append(encoded, base58Alphabet[remainder.Int64()])
+	}
+
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+
+		encoded = append(encoded, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+
+	return string(encoded)
+}
+
+// base58CheckDecode decodes base58 data and checks its trailing 4 bytes checksum
+func base58CheckDecode(encoded string) ([]byte, bool) {
+	n := new(big.Int)
+
+	zeros := 0
+	for zeros < len(encoded) && encoded[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+
+	for i := 0; i < len(encoded); i++ {
+		digit := bytes.IndexByte([]byte(base58Alphabet), encoded[i])
+		if digit < 0 {
+			return nil, false
+		}
+
+		n.Mul(n, base58Radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	data := append(make([]byte, zeros), n.Bytes()...)
+	if len(data) < 4 {
+		return nil, false
+	}
+
+	data, sum := data[:len(data)-4], data[len(data)-4:]
+	if !bytes.Equal(checksum(data), sum) {
+		return nil, false
+	}
+
+	return data, true
+}
+
+// checksum gets the first 4 bytes of the double SHA-256 of the data
+func checksum(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+
+	return second[:4]
+}
@@ -0,0 +1,548 @@
+package hdkey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"filippo.io/bigmod"
+	c "github.com/ELares/crypto/pkg"
+	ec "github.com/ELares/crypto/pkg/ecdsa"
+	"github.com/ELares/crypto/pkg/policy"
+	"golang.org/x/crypto/ripemd160"
+)
+
+const (
+	// Ed25519 SLIP-0010 ed25519 keys, hardened derivation only
+	Ed25519 Curve = iota
+
+	// Secp256k1 BIP32 secp256k1 keys
+	Secp256k1
+
+	// P256 SLIP-0010 NIST P-256 keys
+	P256
+)
+
+const (
+	// HardenedOffset first hardened child index, written i' or ih in paths
+	HardenedOffset uint32 = 0x80000000
+
+	// MinSeedSize minimum size of a master seed (BIP32)
+	MinSeedSize = 16
+
+	// MaxSeedSize maximum size of a master seed (BIP32)
+	MaxSeedSize = 64
+
+	// serializedSize size of a serialized extended key, without its checksum
+	serializedSize = 78
+)
+
+var (
+	// curves seed key, elliptic curve, curve order and serialization versions of each curve,
+	// secp256k1 using the BIP32 mainnet xprv and xpub versions and the others versions of this
+	// library encoding as eprv, epub, nprv and npub
+	curves = map[Curve]struct {
+		name           string
+		seedKey        string
+		curve          elliptic.Curve
+		order          *bigmod.Modulus
+		privateVersion uint32
+		publicVersion  uint32
+	}{
+		Ed25519:   {"Ed25519", "ed25519 seed", nil, nil, 0x03126f7e, 0x031273b9},
+		Secp256k1: {"secp256k1", "Bitcoin seed", ec.Secp256k1(), order(ec.Secp256k1()), 0x0488ade4, 0x0488b21e},
+		P256:      {"P-256", "Nist256p1 seed", elliptic.P256(), order(elliptic.P256()), 0x03b8c41e, 0x03b8c858},
+	}
+)
+
+type (
+	// Curve curve of a hierarchical deterministic key
+	Curve int
+
+	// ExtendedKey hierarchical deterministic key: a private key, or only a public key, and the chain
+	// code deriving its children, PublicKey being the SEC 1 compressed point, or 0x00 followed by
+	// the ed25519 public key
+	ExtendedKey struct {
+		Curve             Curve
+		Depth             uint8
+		ParentFingerprint uint32
+		ChildNumber       uint32
+		ChainCode         []byte
+		PrivateKey        []byte
+		PublicKey         []byte
+	}
+
+	// IHDKey interface for methods to derive hierarchical deterministic keys from a seed (BIP32,
+	// SLIP-0010), serialize them and convert them to the ed25519 and ecdsa keys of the library
+	IHDKey interface {
+		NewMaster(curve Curve, seed []byte) (*ExtendedKey, error)
+
+		Derive(key *ExtendedKey, path string) (*ExtendedKey, error)
+		Child(key *ExtendedKey, index uint32) (*ExtendedKey, error)
+		Neuter(key *ExtendedKey) (*ExtendedKey, error)
+		ParsePath(path string) ([]uint32, error)
+
+		ToExtended(key *ExtendedKey) (string, error)
+		FromExtended(extended string) (*ExtendedKey, error)
+
+		ToPrivateKey(key *ExtendedKey) (crypto.PrivateKey, error)
+		ToPublicKey(key *ExtendedKey) (crypto.PublicKey, error)
+		Fingerprint(key *ExtendedKey) (uint32, error)
+	}
+
+	// HDKey struct to implement the IHDKey methods
+	HDKey struct {
+		policy *policy.Policy
+		ecdsa  ec.IECDSA
+	}
+)
+
+// NewHDKey gets a new HDKey pointer
+func NewHDKey() IHDKey {
+	return NewHDKeyWithPolicy(nil)
+}
+
+// NewHDKeyWithPolicy gets a new HDKey pointer enforcing the policy on the curve of master and
+// parsed extended keys
+func NewHDKeyWithPolicy(policy *policy.Policy) IHDKey {
+	return &HDKey{policy: policy, ecdsa: ec.NewECDSA()}
+}
+
+// String gets the name of the curve
+func (curve Curve) String() string {
+	if params, ok := curves[curve]; ok {
+		return params.name
+	}
+
+	return "unknown"
+}
+
+// IsPrivate reports whether the extended key has its private key
+func (k *ExtendedKey) IsPrivate() bool {
+	return len(k.PrivateKey) != 0
+}
+
+// NewMaster derives the master key of the seed, 16 to 64 bytes long, e.g. a BIP39 seed
+func (h *HDKey) NewMaster(curve Curve, seed []byte) (*ExtendedKey, error) {
+	if err := h.checkCurve(curve); err != nil {
+		return nil, err
+	}
+
+	if len(seed) < MinSeedSize || len(seed) > MaxSeedSize {
+		return nil, c.ErrInvalidSeed
+	}
+
+	digest := hmacSHA512([]byte(curves[curve].seedKey), seed)
+
+	// SLIP-0010: an out of range ecdsa master key is derived again from the digest
+	for curve != Ed25519 && !validScalar(curve, digest[:32]) {
+		digest = hmacSHA512([]byte(curves[curve].seedKey), digest)
+	}
+
+	return h.newPrivate(curve, 0, 0, 0, digest[32:], digest[:32])
+}
+
+// Derive derives the descendant of the path, a path starting with m requiring a master key
+func (h *HDKey) Derive(key *ExtendedKey, path string) (*ExtendedKey, error) {
+	if key == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	indexes, err := h.ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(path, "m") && key.Depth != 0 {
+		return nil, c.ErrInvalidPath
+	}
+
+	for _, index := range indexes {
+		if key, err = h.Child(key, index); err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// Child derives the child of the index, hardened from HardenedOffset on, a public key only deriving
+// non-hardened secp256k1 and P-256 children
+func (h *HDKey) Child(key *ExtendedKey, index uint32) (*ExtendedKey, error) {
+	if err := validate(key); err != nil {
+		return nil, err
+	}
+
+	if key.Depth == 255 {
+		return nil, c.ErrInvalidPath
+	}
+
+	hardened := index >= HardenedOffset
+
+	if key.Curve == Ed25519 && !hardened {
+		return nil, c.ErrHardenedOnly
+	}
+
+	if hardened && !key.IsPrivate() {
+		return nil, c.ErrPrivateDerivation
+	}
+
+	fingerprint, err := h.Fingerprint(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if hardened {
+		data = append([]byte{0}, key.PrivateKey...)
+	} else {
+		data = append([]byte{}, key.PublicKey...)
+	}
+
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	for {
+		digest := hmacSHA512(key.ChainCode, data)
+		left, chainCode := digest[:32], digest[32:]
+
+		if key.Curve == Ed25519 {
+			return h.newPrivate(key.Curve, key.Depth+1, fingerprint, index, chainCode, left)
+		}
+
+		if validScalar(key.Curve, left) {
+			var child *ExtendedKey
+
+			if key.IsPrivate() {
+				child, err = h.privateChild(key, fingerprint, index, chainCode, left)
+			} else {
+				child, err = h.publicChild(key, fingerprint, index, chainCode, left)
+			}
+
+			if err != nil || child != nil {
+				return child, err
+			}
+		}
+
+		// SLIP-0010: an out of range child key is derived again from the right half of the digest
+		data = binary.BigEndian.AppendUint32(append([]byte{1}, chainCode...), index)
+	}
+}
+
+// Neuter gets the public extended key of an extended key, deriving the same non-hardened public
+// children
+func (h *HDKey) Neuter(key *ExtendedKey) (*ExtendedKey, error) {
+	if err := validate(key); err != nil {
+		return nil, err
+	}
+
+	return &ExtendedKey{
+		Curve:             key.Curve,
+		Depth:             key.Depth,
+		ParentFingerprint: key.ParentFingerprint,
+		ChildNumber:       key.ChildNumber,
+		ChainCode:         append([]byte{}, key.ChainCode...),
+		PublicKey:         append([]byte{}, key.PublicKey...),
+	}, nil
+}
+
+// ParsePath parses a derivation path such as m/44'/0'/1' into child indexes, hardened indexes being
+// marked with ', h or H, the leading m being optional for paths relative to the key
+func (h *HDKey) ParsePath(path string) ([]uint32, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "m"), "/")
+	if path == "" {
+		return []uint32{}, nil
+	}
+
+	components := strings.Split(path, "/")
+	if len(components) > 255 {
+		return nil, c.ErrInvalidPath
+	}
+
+	indexes := make([]uint32, len(components))
+	for i, component := range components {
+		offset := uint32(0)
+
+		if trimmed := strings.TrimRight(component, "'hH"); len(trimmed) == len(component)-1 {
+			component, offset = trimmed, HardenedOffset
+		}
+
+		if component == "" || component[0] < '0' || component[0] > '9' {
+			return nil, c.ErrInvalidPath
+		}
+
+		index, err := strconv.ParseUint(component, 10, 31)
+		if err != nil {
+			return nil, c.ErrInvalidPath
+		}
+
+		indexes[i] = uint32(index) + offset
+	}
+
+	return indexes, nil
+}
+
+// ToExtended serializes the extended key in the BIP32 format, base58check of the version, depth,
+// parent fingerprint, child number, chain code and key
+func (h *HDKey) ToExtended(key *ExtendedKey) (string, error) {
+	if err := validate(key); err != nil {
+		return "", err
+	}
+
+	version := curves[key.Curve].publicVersion
+	keyData := key.PublicKey
+
+	if key.IsPrivate() {
+		version = curves[key.Curve].privateVersion
+		keyData = append([]byte{0}, key.PrivateKey...)
+	}
+
+	data := make([]byte, 0, serializedSize)
+	data = binary.BigEndian.AppendUint32(data, version)
+	data = append(data, key.Depth)
+	data = binary.BigEndian.AppendUint32(data, key.ParentFingerprint)
+	data = binary.BigEndian.AppendUint32(data, key.ChildNumber)
+	data = append(data, key.ChainCode...)
+	data = append(data, keyData...)
+
+	return base58CheckEncode(data), nil
+}
+
+// FromExtended parses a BIP32 serialized extended key, checking its checksum, version and key
+func (h *HDKey) FromExtended(extended string) (*ExtendedKey, error) {
+	data, ok := base58CheckDecode(extended)
+	if !ok || len(data) != serializedSize {
+		return nil, c.ErrInvalidExtendedKey
+	}
+
+	version := binary.BigEndian.Uint32(data[0:4])
+	depth := data[4]
+	parentFingerprint := binary.BigEndian.Uint32(data[5:9])
+	childNumber := binary.BigEndian.Uint32(data[9:13])
+	chainCode := data[13:45]
+	keyData := data[45:]
+
+	if depth == 0 && (parentFingerprint != 0 || childNumber != 0) {
+		return nil, c.ErrInvalidExtendedKey
+	}
+
+	for curve, params := range curves {
+		switch version {
+		case params.privateVersion:
+			if err := h.checkCurve(curve); err != nil {
+				return nil, err
+			}
+
+			if keyData[0] != 0 || (curve != Ed25519 && !validScalar(curve, keyData[1:])) {
+				return nil, c.ErrInvalidExtendedKey
+			}
+
+			return h.newPrivate(curve, depth, parentFingerprint, childNumber, chainCode, keyData[1:])
+		case params.publicVersion:
+			if err := h.checkCurve(curve); err != nil {
+				return nil, err
+			}
+
+			key := &ExtendedKey{Curve: curve, Depth: depth, ParentFingerprint: parentFingerprint, ChildNumber: childNumber, ChainCode: append([]byte{}, chainCode...), PublicKey: append([]byte{}, keyData...)}
+
+			if _, err := h.ToPublicKey(key); err != nil {
+				return nil, c.ErrInvalidExtendedKey
+			}
+
+			return key, nil
+		}
+	}
+
+	return nil, c.ErrInvalidExtendedKey
+}
+
+// ToPrivateKey converts the extended key into an ed25519.PrivateKey, or a *ecdsa.PrivateKey on
+// secp256k1 or P-256
+func (h *HDKey) ToPrivateKey(key *ExtendedKey) (crypto.PrivateKey, error) {
+	if err := validate(key); err != nil {
+		return nil, err
+	}
+
+	if !key.IsPrivate() {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if key.Curve == Ed25519 {
+		return ed25519.NewKeyFromSeed(key.PrivateKey), nil
+	}
+
+	return h.ecdsa.FromRawPrivateKey(curves[key.Curve].curve, key.PrivateKey)
+}
+
+// ToPublicKey converts the extended key into an ed25519.PublicKey, or a *ecdsa.PublicKey on
+// secp256k1 or P-256
+func (h *HDKey) ToPublicKey(key *ExtendedKey) (crypto.PublicKey, error) {
+	if err := validate(key); err != nil {
+		return nil, err
+	}
+
+	if key.Curve == Ed25519 {
+		if key.PublicKey[0] != 0 {
+			return nil, c.ErrInvalidPublicKey
+		}
+
+		return ed25519.PublicKey(append([]byte{}, key.PublicKey[1:]...)), nil
+	}
+
+	return h.ecdsa.FromCompressedPoint(curves[key.Curve].curve, key.PublicKey)
+}
+
+// Fingerprint gets the first 4 bytes of the HASH160 of the public key, identifying the key as the
+// parent of its children
+func (h *HDKey) Fingerprint(key *ExtendedKey) (uint32, error) {
+	if err := validate(key); err != nil {
+		return 0, err
+	}
+
+	sha := sha256.Sum256(key.PublicKey)
+	ripemd := ripemd160.New()
+	ripemd.Write(sha[:])
+
+	return binary.BigEndian.Uint32(ripemd.Sum(nil)), nil
+}
+
+// privateChild adds the left half of the digest to the parent private key in constant time, nil
+// when the sum is zero
+func (h *HDKey) privateChild(key *ExtendedKey, fingerprint, index uint32, chainCode, left []byte) (*ExtendedKey, error) {
+	n := curves[key.Curve].order
+
+	scalar, err := bigmod.NewNat().SetBytes(left, n)
+	if err != nil {
+		return nil, c.ErrScalarOutOfRange
+	}
+
+	parent, err := bigmod.NewNat().SetBytes(key.PrivateKey, n)
+	if err != nil {
+		return nil, c.ErrScalarOutOfRange
+	}
+
+	if scalar.Add(parent, n).IsZero() == 1 {
+		return nil, nil
+	}
+
+	return h.newPrivate(key.Curve, key.Depth+1, fingerprint, index, chainCode, scalar.Bytes(n))
+}
+
+// publicChild adds the point of the left half of the digest to the parent public key, nil when the
+// sum is the point at infinity
+func (h *HDKey) publicChild(key *ExtendedKey, fingerprint, index uint32, chainCode, left []byte) (*ExtendedKey, error) {
+	parent, err := h.ToPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey := parent.(*ecdsa.PublicKey)
+	curve := publicKey.Curve
+
+	x, y := curve.ScalarBaseMult(left)
+	x, y = curve.Add(x, y, publicKey.X, publicKey.Y)
+
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, nil
+	}
+
+	compressed, err := h.ecdsa.ToCompressedPoint(&ecdsa.PublicKey{Curve: curve, X: x, Y: y})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExtendedKey{Curve: key.Curve, Depth: key.Depth + 1, ParentFingerprint: fingerprint, ChildNumber: index, ChainCode: append([]byte{}, chainCode...), PublicKey: compressed}, nil
+}
+
+// newPrivate gets a private extended key, computing its public key
+func (h *HDKey) newPrivate(curve Curve, depth uint8, parentFingerprint, childNumber uint32, chainCode, privateKey []byte) (*ExtendedKey, error) {
+	key := &ExtendedKey{
+		Curve:             curve,
+		Depth:             depth,
+		ParentFingerprint: parentFingerprint,
+		ChildNumber:       childNumber,
+		ChainCode:         append([]byte{}, chainCode...),
+		PrivateKey:        append([]byte{}, privateKey...),
+		PublicKey:         make([]byte, 33),
+	}
+
+	if curve == Ed25519 {
+		copy(key.PublicKey[1:], ed25519.NewKeyFromSeed(privateKey).Public().(ed25519.PublicKey))
+
+		return key, nil
+	}
+
+	privKey, err := h.ToPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.PublicKey, err = h.ecdsa.ToCompressedPoint(&privKey.(*ecdsa.PrivateKey).PublicKey); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// checkCurve checks the curve is known and allowed by the policy
+func (h *HDKey) checkCurve(curve Curve) error {
+	params, ok := curves[curve]
+	if !ok {
+		return c.ErrUnsupportedCurve
+	}
+
+	if curve == Ed25519 {
+		return h.policy.CheckAlgorithm(policy.AlgorithmEd25519)
+	}
+
+	return h.policy.CheckCurve(params.curve)
+}
+
+// validate checks the curve, chain code and key sizes of the extended key
+func validate(key *ExtendedKey) error {
+	if key == nil {
+		return c.ErrNilPrivateKey
+	}
+
+	if _, ok := curves[key.Curve]; !ok {
+		return c.ErrUnsupportedCurve
+	}
+
+	if len(key.ChainCode) != 32 || len(key.PublicKey) != 33 || (key.IsPrivate() && len(key.PrivateKey) != 32) {
+		return c.ErrInvalidKeyLength
+	}
+
+	return nil
+}
+
+// validScalar reports whether the 32 bytes are a private key in [1, n-1] of the ecdsa curve, in
+// constant time but for the result
+func validScalar(curve Curve, scalar []byte) bool {
+	k, err := bigmod.NewNat().SetBytes(scalar, curves[curve].order)
+
+	return err == nil && k.IsZero() == 0
+}
+
+// order gets the order of the ecdsa curve as a modulus of the constant-time scalar arithmetic
+func order(curve elliptic.Curve) *bigmod.Modulus {
+	n, err := bigmod.NewModulus(curve.Params().N.Bytes())
+	if err != nil {
+		panic("hdkey: invalid curve order of " + curve.Params().Name)
+	}
+
+	return n
+}
+
+// hmacSHA512 gets the HMAC-SHA512 of the data
+func hmacSHA512(key, data []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,476 @@
+package hdkey
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	c "github.com/ELares/crypto/pkg"
+	ec "github.com/ELares/crypto/pkg/ecdsa"
+	ed "github.com/ELares/crypto/pkg/ed25519"
+	"github.com/ELares/crypto/pkg/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vectorSeed seed of the first BIP32 and SLIP-0010 test vectors
+var vectorSeed, _ = hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+func TestSLIP10(t *testing.T) {
+	h := NewHDKey()
+
+	// SLIP-0010 test vector 1
+	testcases := []struct {
+		name string
+
+		curve Curve
+		path  string
+
+		fingerprint string
+		chainCode   string
+		privateKey  string
+		publicKey   string
+	}{
+		{
+			name:        "Ed25519 m",
+			curve:       Ed25519,
+			path:        "m",
+			fingerprint: "00000000",
+			chainCode:   "90046a93de5380a72b5e45010748567d5ea02bbf6522f979e05c0d8d8ca9fffb",
+			privateKey:  "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7",
+			publicKey:   "00a4b2856bfec510abab89753fac1ac0e1112364e7d250545963f135f2a33188ed",
+		},
+		{
+			name:        "Ed25519 m/0H",
+			curve:       Ed25519,
+			path:        "m/0H",
+			fingerprint: "ddebc675",
+			chainCode:   "8b59aa11380b624e81507a27fedda59fea6d0b779a778918a2fd3590e16e9c69",
+			privateKey:  "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3",
+			publicKey:   "008c8a13df77a28f3445213a0f432fde644acaa215fc72dcdf300d5efaa85d350c",
+		},
+		{
+			name:        "Ed25519 m/0H/1H",
+			curve:       Ed25519,
+			path:        "m/0'/1'",
+			fingerprint: "13dab143",
+			chainCode:   "a320425f77d1b5c2505a6b1b27382b37368ee640e3557c315416801243552f14",
+			privateKey:  "b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2",
+			publicKey:   "001932a5270f335bed617d5b935c80aedb1a35bd9fc1e31acafd5372c30f5c1187",
+		},
+		{
+			name:        "P-256 m",
+			curve:       P256,
+			path:        "m",
+			fingerprint: "00000000",
+			chainCode:   "beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea",
+			privateKey:  "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2",
+			publicKey:   "0266874dc6ade47b3ecd096745ca09bcd29638dd52c2c12117b11ed3e458cfa9e8",
+		},
+		{
+			name:        "P-256 m/0H",
+			curve:       P256,
+			path:        "m/0h",
+			fingerprint: "be6105b5",
+			chainCode:   "3460cea53e6a6bb5fb391eeef3237ffd8724bf0a40e94943c98b83825342ee11",
+			privateKey:  "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c",
+			publicKey:   "0384610f5ecffe8fda089363a41f56a5c7ffc1d81b59a612d0d649b2d22355590c",
+		},
+		{
+			name:        "secp256k1 m",
+			curve:       Secp256k1,
+			path:        "m",
+			fingerprint: "00000000",
+			chainCode:   "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508",
+			privateKey:  "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35",
+			publicKey:   "0339a36013301597daef41fbe593a02cc513d0b55527ec2df1050e2e8ff49c85c2",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			master, err := h.NewMaster(tc.curve, vectorSeed)
+			require.Nil(t, err)
+
+			key, err := h.Derive(master, tc.path)
+			require.Nil(t, err)
+
+			assert.Equal(t, tc.fingerprint, fmt.Sprintf("%08x", key.ParentFingerprint))
+			assert.Equal(t, tc.chainCode, hex.EncodeToString(key.ChainCode))
+			assert.Equal(t, tc.privateKey, hex.EncodeToString(key.PrivateKey))
+			assert.Equal(t, tc.publicKey, hex.EncodeToString(key.PublicKey))
+		})
+	}
+}
+
+func TestBIP32(t *testing.T) {
+	h := NewHDKey()
+
+	master, err := h.NewMaster(Secp256k1, vectorSeed)
+	require.Nil(t, err)
+
+	// BIP32 test vector 1
+	testcases := []struct {
+		name string
+
+		path string
+
+		xprv string
+		xpub string
+	}{
+		{
+			name: "m",
+			path: "m",
+			xprv: "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+			xpub: "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+		},
+		{
+			name: "m/0H",
+			path: "m/0H",
+			xprv: "xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7",
+			xpub: "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+		},
+		{
+			name: "m/0H/1",
+			path: "m/0H/1",
+			xprv: "xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs",
+			xpub: "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := h.Derive(master, tc.path)
+			require.Nil(t, err)
+
+			xprv, err := h.ToExtended(key)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.xprv, xprv)
+
+			public, err := h.Neuter(key)
+			require.Nil(t, err)
+
+			xpub, err := h.ToExtended(public)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.xpub, xpub)
+
+			parsed, err := h.FromExtended(tc.xprv)
+			assert.Nil(t, err)
+			assert.Equal(t, key, parsed)
+
+			parsed, err = h.FromExtended(tc.xpub)
+			assert.Nil(t, err)
+			assert.Equal(t, public, parsed)
+		})
+	}
+}
+
+func TestPublicDerivation(t *testing.T) {
+	h := NewHDKey()
+
+	for _, curve := range []Curve{Secp256k1, P256} {
+		t.Run(curve.String(), func(t *testing.T) {
+			master, err := h.NewMaster(curve, vectorSeed)
+			require.Nil(t, err)
+
+			account, err := h.Derive(master, "m/44'/0'/1'")
+			require.Nil(t, err)
+
+			accountPublic, err := h.Neuter(account)
+			require.Nil(t, err)
+
+			// a watch-only public key derives the public keys of the non-hardened children
+			private, err := h.Derive(account, "0/7")
+			require.Nil(t, err)
+
+			public, err := h.Derive(accountPublic, "0/7")
+			require.Nil(t, err)
+
+			assert.False(t, public.IsPrivate())
+			assert.Equal(t, private.PublicKey, public.PublicKey)
+			assert.Equal(t, private.ChainCode, public.ChainCode)
+			assert.Equal(t, private.ParentFingerprint, public.ParentFingerprint)
+
+			_, err = h.Child(accountPublic, HardenedOffset)
+			assert.Equal(t, c.ErrPrivateDerivation, err)
+
+			_, err = h.ToPrivateKey(public)
+			assert.Equal(t, c.ErrNilPrivateKey, err)
+		})
+	}
+}
+
+func TestNativeKeys(t *testing.T) {
+	h := NewHDKey()
+
+	t.Run("Ed25519", func(t *testing.T) {
+		master, err := h.NewMaster(Ed25519, vectorSeed)
+		require.Nil(t, err)
+
+		key, err := h.Derive(master, "m/44'/0'/1'")
+		require.Nil(t, err)
+
+		privateKey, err := h.ToPrivateKey(key)
+		require.Nil(t, err)
+
+		publicKey, err := h.ToPublicKey(key)
+		require.Nil(t, err)
+		assert.Equal(t, privateKey.(ed25519.PrivateKey).Public(), publicKey)
+
+		// straight through the ed25519 package
+		ied25519 := ed.NewED25519()
+		prvPEM, pubPEM, err := ied25519.ToPEM(privateKey.(ed25519.PrivateKey), publicKey.(ed25519.PublicKey))
+		require.Nil(t, err)
+
+		_, _, err = ied25519.FromPEMValidated(prvPEM, pubPEM)
+		assert.Nil(t, err)
+
+		_, err = h.Child(master, 0)
+		assert.Equal(t, c.ErrHardenedOnly, err)
+	})
+
+	for _, curve := range []Curve{Secp256k1, P256} {
+		t.Run(curve.String(), func(t *testing.T) {
+			master, err := h.NewMaster(curve, vectorSeed)
+			require.Nil(t, err)
+
+			key, err := h.Derive(master, "m/44'/0'/1'/0/3")
+			require.Nil(t, err)
+
+			privateKey, err := h.ToPrivateKey(key)
+			require.Nil(t, err)
+
+			publicKey, err := h.ToPublicKey(key)
+			require.Nil(t, err)
+			assert.True(t, publicKey.(*ecdsa.PublicKey).Equal(privateKey.(*ecdsa.PrivateKey).Public()))
+
+			// straight through the ecdsa package
+			iecdsa := ec.NewECDSA()
+			prvPEM, pubPEM, err := iecdsa.ToPEM(privateKey.(*ecdsa.PrivateKey), publicKey.(*ecdsa.PublicKey))
+			require.Nil(t, err)
+
+			_, _, err = iecdsa.FromPEMValidated(prvPEM, pubPEM)
+			assert.Nil(t, err)
+
+			jwk, err := iecdsa.ToJWKAuto(publicKey.(*ecdsa.PublicKey), "device-3")
+			assert.Nil(t, err)
+			assert.NotEmpty(t, jwk)
+		})
+	}
+}
+
+func TestExtended(t *testing.T) {
+	h := NewHDKey()
+
+	testcases := []struct {
+		name string
+
+		curve Curve
+		path  string
+
+		privatePrefix string
+		publicPrefix  string
+	}{
+		{name: "Ed25519", curve: Ed25519, path: "m/0'/1'", privatePrefix: "eprv", publicPrefix: "epub"},
+		{name: "secp256k1", curve: Secp256k1, path: "m/0'/1", privatePrefix: "xprv", publicPrefix: "xpub"},
+		{name: "P-256", curve: P256, path: "m/0'/1", privatePrefix: "nprv", publicPrefix: "npub"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			master, err := h.NewMaster(tc.curve, vectorSeed)
+			require.Nil(t, err)
+
+			for _, key := range []*ExtendedKey{master, must(h.Derive(master, tc.path))} {
+				extended, err := h.ToExtended(key)
+				require.Nil(t, err)
+				assert.Equal(t, tc.privatePrefix, extended[:4])
+				assert.Len(t, extended, 111)
+
+				parsed, err := h.FromExtended(extended)
+				assert.Nil(t, err)
+				assert.Equal(t, key, parsed)
+
+				public, err := h.Neuter(key)
+				require.Nil(t, err)
+
+				extended, err = h.ToExtended(public)
+				require.Nil(t, err)
+				assert.Equal(t, tc.publicPrefix, extended[:4])
+
+				parsed, err = h.FromExtended(extended)
+				assert.Nil(t, err)
+				assert.Equal(t, public, parsed)
+			}
+		})
+	}
+
+	xprv := "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"
+	chainCode := make([]byte, 32)
+	n := ec.Secp256k1().Params().N.FillBytes(make([]byte, 32))
+
+	invalid := []string{
+		"",
+		"xprv",
+		"xprv0",
+		xprv[:len(xprv)-1] + "j",
+		xprv + "1",
+		serialize(0x0488ade4, 0, 1, 0, chainCode, append([]byte{0}, vectorSeed[:16]...)),
+		serialize(0x0488ade4, 1, 1, 0, chainCode, make([]byte, 33)),
+		serialize(0x0488ade4, 1, 1, 0, chainCode, append([]byte{0}, n...)),
+		serialize(0x0488ade4, 1, 1, 0, chainCode, append([]byte{1}, n[1:]...)),
+		serialize(0x0488b21e, 1, 1, 0, chainCode, append([]byte{2}, n[1:]...)),
+		serialize(0x0488b21e, 1, 1, 0, chainCode, append([]byte{4}, n...)),
+		serialize(0x031273b9, 1, 1, 0, chainCode, append([]byte{2}, n[1:]...)),
+		serialize(0x04358394, 1, 1, 0, chainCode, append([]byte{0}, n[1:]...)),
+	}
+
+	for _, extended := range invalid {
+		_, err := h.FromExtended(extended)
+		assert.Equal(t, c.ErrInvalidExtendedKey, err, extended)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	h := NewHDKey()
+
+	testcases := []struct {
+		name string
+
+		path string
+
+		indexes []uint32
+		err     error
+	}{
+		{name: "Master", path: "m", indexes: []uint32{}},
+		{name: "Apostrophe", path: "m/44'/0'/1'", indexes: []uint32{HardenedOffset + 44, HardenedOffset, HardenedOffset + 1}},
+		{name: "h and H", path: "m/44h/0H/1", indexes: []uint32{HardenedOffset + 44, HardenedOffset, 1}},
+		{name: "Relative", path: "0/2147483647", indexes: []uint32{0, 2147483647}},
+		{name: "Index too large", path: "m/2147483648", err: c.ErrInvalidPath},
+		{name: "Empty component", path: "m//1", err: c.ErrInvalidPath},
+		{name: "Double marker", path: "m/1''", err: c.ErrInvalidPath},
+		{name: "Sign", path: "m/+1", err: c.ErrInvalidPath},
+		{name: "Letters", path: "m/a", err: c.ErrInvalidPath},
+		{name: "Trailing slash", path: "m/1/", err: c.ErrInvalidPath},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			indexes, err := h.ParsePath(tc.path)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.indexes, indexes)
+		})
+	}
+
+	master, err := h.NewMaster(Secp256k1, vectorSeed)
+	require.Nil(t, err)
+
+	child, err := h.Derive(master, "m/1")
+	require.Nil(t, err)
+
+	_, err = h.Derive(child, "m/1")
+	assert.Equal(t, c.ErrInvalidPath, err)
+
+	relative, err := h.Derive(child, "2")
+	assert.Nil(t, err)
+	assert.Equal(t, must(h.Derive(master, "m/1/2")), relative)
+}
+
+func TestInvalid(t *testing.T) {
+	h := NewHDKey()
+
+	_, err := h.NewMaster(Ed25519, vectorSeed[:15])
+	assert.Equal(t, c.ErrInvalidSeed, err)
+
+	_, err = h.NewMaster(Ed25519, make([]byte, 65))
+	assert.Equal(t, c.ErrInvalidSeed, err)
+
+	_, err = h.NewMaster(Curve(9), vectorSeed)
+	assert.Equal(t, c.ErrUnsupportedCurve, err)
+
+	_, err = h.Child(nil, 0)
+	assert.Equal(t, c.ErrNilPrivateKey, err)
+
+	_, err = h.ToExtended(&ExtendedKey{Curve: Secp256k1, ChainCode: make([]byte, 31), PublicKey: make([]byte, 33)})
+	assert.Equal(t, c.ErrInvalidKeyLength, err)
+
+	deepest := must(h.NewMaster(Secp256k1, vectorSeed))
+	deepest.Depth = 255
+
+	_, err = h.Child(deepest, 0)
+	assert.Equal(t, c.ErrInvalidPath, err)
+}
+
+func TestPrivateChild(t *testing.T) {
+	h := NewHDKey().(*HDKey)
+
+	for _, curve := range []Curve{Secp256k1, P256} {
+		t.Run(curves[curve].name, func(t *testing.T) {
+			n := curves[curve].curve.Params().N
+			scalar := func(v *big.Int) []byte {
+				return new(big.Int).Mod(v, n).FillBytes(make([]byte, 32))
+			}
+
+			one, two := big.NewInt(1), big.NewInt(2)
+			nMinusOne := new(big.Int).Sub(n, one)
+
+			assert.True(t, validScalar(curve, scalar(one)))
+			assert.True(t, validScalar(curve, scalar(nMinusOne)))
+			assert.False(t, validScalar(curve, make([]byte, 32)))
+			assert.False(t, validScalar(curve, n.FillBytes(make([]byte, 32))))
+
+			parent := must(h.NewMaster(curve, vectorSeed))
+
+			// (n - 1) + 2 wraps around to 1
+			parent.PrivateKey = scalar(nMinusOne)
+			child, err := h.privateChild(parent, 0, 0, parent.ChainCode, scalar(two))
+			assert.Nil(t, err)
+			assert.Equal(t, scalar(one), child.PrivateKey)
+
+			// (n - 2) + 2 is zero, the child is skipped
+			parent.PrivateKey = scalar(new(big.Int).Sub(n, two))
+			child, err = h.privateChild(parent, 0, 0, parent.ChainCode, scalar(two))
+			assert.Nil(t, err)
+			assert.Nil(t, child)
+		})
+	}
+}
+
+func TestWithPolicy(t *testing.T) {
+	fips, _ := policy.NewPolicy(policy.FIPS1403)
+	h := NewHDKeyWithPolicy(fips)
+
+	_, err := h.NewMaster(P256, vectorSeed)
+	assert.Nil(t, err)
+
+	_, err = h.NewMaster(Secp256k1, vectorSeed)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, err = h.FromExtended("xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8")
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+}
+
+// serialize serializes the fields of an extended key
+func serialize(version uint32, depth uint8, parentFingerprint, childNumber uint32, chainCode, key []byte) string {
+	data := binary.BigEndian.AppendUint32(nil, version)
+	data = append(data, depth)
+	data = binary.BigEndian.AppendUint32(data, parentFingerprint)
+	data = binary.BigEndian.AppendUint32(data, childNumber)
+	data = append(data, chainCode...)
+
+	return base58CheckEncode(append(data, key...))
+}
+
+// must gets the key, failing on error
+func must(key *ExtendedKey, err error) *ExtendedKey {
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
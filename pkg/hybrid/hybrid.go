@@ -0,0 +1,424 @@
+package hybrid
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+
+	c "github.com/ELares/crypto/pkg"
+	"github.com/ELares/crypto/pkg/mlkem"
+	"github.com/ELares/crypto/pkg/policy"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// X25519MLKEM768 X25519 combined with ML-KEM-768, TLS named group 0x11ec
+	// (draft-ietf-tls-ecdhe-mlkem)
+	X25519MLKEM768 Group = 0x11ec
+
+	// SecP256r1MLKEM768 P-256 ECDH combined with ML-KEM-768, TLS named group 0x11eb
+	// (draft-ietf-tls-ecdhe-mlkem)
+	SecP256r1MLKEM768 Group = 0x11eb
+)
+
+const (
+	// SharedSecretSize size of the combined shared secret of every group
+	SharedSecretSize = 32
+
+	// TLSSharedSecretSize size of the concatenated shared secret of every group, the input of the
+	// TLS 1.3 key schedule
+	TLSSharedSecretSize = 64
+
+	// mlkem768EncapsulationKeySize size of a ML-KEM-768 encapsulation key
+	mlkem768EncapsulationKeySize = 1184
+
+	// mlkem768CiphertextSize size of a ML-KEM-768 ciphertext
+	mlkem768CiphertextSize = 1088
+
+	// xwingLabel label of the X-Wing combiner (draft-connolly-cfrg-xwing-kem)
+	xwingLabel = `\.//^\`
+)
+
+var (
+	// groups classical curve, size of its public key, policy algorithm and combiner label of each
+	// group
+	groups = map[Group]struct {
+		name      string
+		curve     ecdh.Curve
+		pointSize int
+		algorithm policy.Algorithm
+		label     string
+	}{
+		X25519MLKEM768:    {"X25519MLKEM768", ecdh.X25519(), 32, policy.AlgorithmX25519MLKEM768, xwingLabel},
+		SecP256r1MLKEM768: {"SecP256r1MLKEM768", ecdh.P256(), 65, policy.AlgorithmSecP256r1MLKEM768, "SecP256r1MLKEM768"},
+	}
+)
+
+type (
+	// Group hybrid key exchange group, valued as its TLS named group code point
+	Group uint16
+
+	// PrivateKey hybrid private key made of a classical ECDH private key and a ML-KEM-768 private key
+	PrivateKey struct {
+		group     Group
+		classical *ecdh.PrivateKey
+		mlkem     *mlkem.PrivateKey
+		publicKey *PublicKey
+	}
+
+	// PublicKey hybrid public key made of a classical ECDH public key and a ML-KEM-768 public key
+	PublicKey struct {
+		group     Group
+		classical *ecdh.PublicKey
+		mlkem     *mlkem.PublicKey
+	}
+
+	// secrets shared secrets of both parts of an encapsulation, with the classical ciphertext and
+	// public key the combiner binds them to
+	secrets struct {
+		group       Group
+		classical   []byte
+		postQuantum []byte
+		ciphertext  []byte
+		publicKey   []byte
+	}
+
+	// IHybrid interface for methods to generate hybrid keys, conversion to the TLS key share format
+	// and key encapsulation
+	IHybrid interface {
+		X25519MLKEM768() (*PrivateKey, *PublicKey, error)
+		SecP256r1MLKEM768() (*PrivateKey, *PublicKey, error)
+
+		FromKeys(classical *ecdh.PrivateKey, postQuantum *mlkem.PrivateKey) (*PrivateKey, error)
+
+		FromRawPublicKey(Group, []byte) (*PublicKey, error)
+		ToRawPublicKey(*PublicKey) ([]byte, error)
+
+		Encapsulate(publicKey *PublicKey) (ciphertext, sharedSecret []byte, err error)
+		Decapsulate(privateKey *PrivateKey, ciphertext []byte) ([]byte, error)
+
+		EncapsulateTLS(publicKey *PublicKey) (ciphertext, sharedSecret []byte, err error)
+		DecapsulateTLS(privateKey *PrivateKey, ciphertext []byte) ([]byte, error)
+	}
+
+	// Hybrid struct to implement the IHybrid methods
+	Hybrid struct {
+		policy *policy.Policy
+		mlkem  mlkem.IMLKEM
+	}
+)
+
+// NewHybrid gets a new Hybrid pointer
+func NewHybrid() IHybrid {
+	return &Hybrid{mlkem: mlkem.NewMLKEM()}
+}
+
+// NewHybridWithPolicy gets a new Hybrid pointer enforcing the policy on generated and parsed keys
+func NewHybridWithPolicy(policy *policy.Policy) IHybrid {
+	return &Hybrid{policy: policy, mlkem: mlkem.NewMLKEMWithPolicy(policy)}
+}
+
+// String gets the group name
+func (g Group) String() string {
+	if params, ok := groups[g]; ok {
+		return params.name
+	}
+
+	return "unknown"
+}
+
+// Group gets the group of the private key
+func (k *PrivateKey) Group() Group {
+	return k.group
+}
+
+// Public gets the public half of the private key
+func (k *PrivateKey) Public() *PublicKey {
+	return k.publicKey
+}
+
+// Group gets the group of the public key
+func (k *PublicKey) Group() Group {
+	return k.group
+}
+
+// Equal reports whether both public keys are the same
+func (k *PublicKey) Equal(other *PublicKey) bool {
+	return other != nil && k.group == other.group && k.classical.Equal(other.classical) && k.mlkem.Equal(other.mlkem)
+}
+
+// X25519MLKEM768 generates new X25519MLKEM768 private/public keys
+func (h *Hybrid) X25519MLKEM768() (*PrivateKey, *PublicKey, error) {
+	return h.generateKeys(X25519MLKEM768)
+}
+
+// SecP256r1MLKEM768 generates new SecP256r1MLKEM768 private/public keys
+func (h *Hybrid) SecP256r1MLKEM768() (*PrivateKey, *PublicKey, error) {
+	return h.generateKeys(SecP256r1MLKEM768)
+}
+
+// FromKeys combines an existing X25519 or P-256 ECDH private key, an ecdsa key converted with its
+// ECDH method, and a ML-KEM-768 private key into a hybrid private key
+func (h *Hybrid) FromKeys(classical *ecdh.PrivateKey, postQuantum *mlkem.PrivateKey) (*PrivateKey, error) {
+	if classical == nil || postQuantum == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if postQuantum.ParameterSet() != mlkem.MLKEM768 {
+		return nil, c.ErrUnsupportedParameterSet
+	}
+
+	group, ok := groupFromCurve(classical.Curve())
+	if !ok {
+		return nil, c.ErrUnsupportedCurve
+	}
+
+	if err := h.policy.CheckAlgorithm(groups[group].algorithm); err != nil {
+		return nil, err
+	}
+
+	return &PrivateKey{
+		group:     group,
+		classical: classical,
+		mlkem:     postQuantum,
+		publicKey: &PublicKey{group: group, classical: classical.PublicKey(), mlkem: postQuantum.Public()},
+	}, nil
+}
+
+// FromRawPublicKey takes a TLS client key share and converts it into a hybrid public key
+func (h *Hybrid) FromRawPublicKey(group Group, data []byte) (*PublicKey, error) {
+	params, ok := groups[group]
+	if !ok {
+		return nil, c.ErrUnsupportedParameterSet
+	}
+
+	if len(data) != params.pointSize+mlkem768EncapsulationKeySize {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	if err := h.policy.CheckAlgorithm(params.algorithm); err != nil {
+		return nil, err
+	}
+
+	point, encapsulationKey := split(group, data, mlkem768EncapsulationKeySize)
+
+	classical, err := params.curve.NewPublicKey(point)
+	if err != nil {
+		return nil, c.ErrInvalidPoint
+	}
+
+	postQuantum, err := h.mlkem.FromRawPublicKey(mlkem.MLKEM768, encapsulationKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublicKey{group: group, classical: classical, mlkem: postQuantum}, nil
+}
+
+// ToRawPublicKey converts a hybrid public key into a TLS client key share, the ML-KEM-768
+// encapsulation key and X25519 public key for X25519MLKEM768 and the uncompressed P-256 point and
+// ML-KEM-768 encapsulation key for SecP256r1MLKEM768
+func (h *Hybrid) ToRawPublicKey(publicKey *PublicKey) ([]byte, error) {
+	if publicKey == nil || publicKey.classical == nil {
+		return nil, c.ErrNilPublicKey
+	}
+
+	encapsulationKey, err := h.mlkem.ToRawPublicKey(publicKey.mlkem)
+	if err != nil {
+		return nil, err
+	}
+
+	return join(publicKey.group, publicKey.classical.Bytes(), encapsulationKey), nil
+}
+
+// Encapsulate generates a 32 bytes shared secret and its ciphertext, the TLS server key share, for
+// the public key, combining the ML-KEM and ECDH secrets with the classical ciphertext and public
+// key; X25519MLKEM768 is then X-Wing (draft-connolly-cfrg-xwing-kem), its key share and ciphertext
+// being the X-Wing public key and ciphertext
+func (h *Hybrid) Encapsulate(publicKey *PublicKey) ([]byte, []byte, error) {
+	ciphertext, secrets, err := h.encapsulate(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ciphertext, secrets.combine(), nil
+}
+
+// Decapsulate recovers the 32 bytes shared secret of the ciphertext, the TLS server key share
+func (h *Hybrid) Decapsulate(privateKey *PrivateKey, ciphertext []byte) ([]byte, error) {
+	secrets, err := h.decapsulate(privateKey, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	return secrets.combine(), nil
+}
+
+// EncapsulateTLS generates the 64 bytes concatenated shared secret of TLS 1.3 and its ciphertext,
+// the TLS server key share, for the public key; the concatenation is only meant as input of the TLS
+// key schedule, which binds it to the handshake transcript, Encapsulate being the standalone KEM
+func (h *Hybrid) EncapsulateTLS(publicKey *PublicKey) ([]byte, []byte, error) {
+	ciphertext, secrets, err := h.encapsulate(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ciphertext, join(secrets.group, secrets.classical, secrets.postQuantum), nil
+}
+
+// DecapsulateTLS recovers the 64 bytes concatenated shared secret of TLS 1.3 of the ciphertext, the
+// TLS server key share
+func (h *Hybrid) DecapsulateTLS(privateKey *PrivateKey, ciphertext []byte) ([]byte, error) {
+	secrets, err := h.decapsulate(privateKey, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	return join(secrets.group, secrets.classical, secrets.postQuantum), nil
+}
+
+// encapsulate generates the ciphertext and the shared secrets of both parts for the public key
+func (h *Hybrid) encapsulate(publicKey *PublicKey) ([]byte, *secrets, error) {
+	if publicKey == nil || publicKey.classical == nil {
+		return nil, nil, c.ErrNilPublicKey
+	}
+
+	if err := h.policy.CheckAlgorithm(groups[publicKey.group].algorithm); err != nil {
+		return nil, nil, err
+	}
+
+	ephemeral, err := groups[publicKey.group].curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	classicalSecret, err := ephemeral.ECDH(publicKey.classical)
+	if err != nil {
+		return nil, nil, c.ErrLowOrderPoint
+	}
+
+	mlkemCiphertext, mlkemSecret, err := h.mlkem.Encapsulate(publicKey.mlkem)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	point := ephemeral.PublicKey().Bytes()
+
+	return join(publicKey.group, point, mlkemCiphertext), &secrets{
+		group:       publicKey.group,
+		classical:   classicalSecret,
+		postQuantum: mlkemSecret,
+		ciphertext:  point,
+		publicKey:   publicKey.classical.Bytes(),
+	}, nil
+}
+
+// decapsulate recovers the shared secrets of both parts of the ciphertext
+func (h *Hybrid) decapsulate(privateKey *PrivateKey, ciphertext []byte) (*secrets, error) {
+	if privateKey == nil || privateKey.classical == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	params := groups[privateKey.group]
+	if len(ciphertext) != params.pointSize+mlkem768CiphertextSize {
+		return nil, c.ErrInvalidCiphertext
+	}
+
+	if err := h.policy.CheckAlgorithm(params.algorithm); err != nil {
+		return nil, err
+	}
+
+	point, mlkemCiphertext := split(privateKey.group, ciphertext, mlkem768CiphertextSize)
+
+	peer, err := params.curve.NewPublicKey(point)
+	if err != nil {
+		return nil, c.ErrInvalidPoint
+	}
+
+	classicalSecret, err := privateKey.classical.ECDH(peer)
+	if err != nil {
+		return nil, c.ErrLowOrderPoint
+	}
+
+	mlkemSecret, err := h.mlkem.Decapsulate(privateKey.mlkem, mlkemCiphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secrets{
+		group:       privateKey.group,
+		classical:   classicalSecret,
+		postQuantum: mlkemSecret,
+		ciphertext:  point,
+		publicKey:   privateKey.classical.PublicKey().Bytes(),
+	}, nil
+}
+
+// generateKeys generates new private/public keys of the group
+func (h *Hybrid) generateKeys(group Group) (*PrivateKey, *PublicKey, error) {
+	if err := h.policy.CheckAlgorithm(groups[group].algorithm); err != nil {
+		return nil, nil, err
+	}
+
+	classical, err := groups[group].curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	postQuantum, _, err := h.mlkem.MLKEM768()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err := h.FromKeys(classical, postQuantum)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return privateKey, privateKey.Public(), nil
+}
+
+// combine hashes SHA3-256(ss_M || ss_X || ct_X || pk_X || label), binding the secrets to the
+// classical ciphertext and public key: the X-Wing combiner with its label for X25519MLKEM768, and
+// the same construction labeled with the group name, specific to this library, for
+// SecP256r1MLKEM768 which X-Wing does not define
+func (s *secrets) combine() []byte {
+	hash := sha3.New256()
+	hash.Write(s.postQuantum)
+	hash.Write(s.classical)
+	hash.Write(s.ciphertext)
+	hash.Write(s.publicKey)
+	hash.Write([]byte(groups[s.group].label))
+
+	return hash.Sum(nil)
+}
+
+// join concatenates the classical and ML-KEM parts in the order of the group, ML-KEM first for
+// X25519MLKEM768 and classical first for SecP256r1MLKEM768
+func join(group Group, classical, postQuantum []byte) []byte {
+	joined := make([]byte, 0, len(classical)+len(postQuantum))
+	if group == X25519MLKEM768 {
+		return append(append(joined, postQuantum...), classical...)
+	}
+
+	return append(append(joined, classical...), postQuantum...)
+}
+
+// split splits data into its classical and ML-KEM parts in the order of the group
+func split(group Group, data []byte, postQuantumSize int) ([]byte, []byte) {
+	if group == X25519MLKEM768 {
+		return data[postQuantumSize:], data[:postQuantumSize]
+	}
+
+	return data[:len(data)-postQuantumSize], data[len(data)-postQuantumSize:]
+}
+
+// groupFromCurve gets the group of the classical curve
+func groupFromCurve(curve ecdh.Curve) (Group, bool) {
+	for group, params := range groups {
+		if params.curve == curve {
+			return group, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,310 @@
+package hybrid
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	c "github.com/ELares/crypto/pkg"
+	"github.com/ELares/crypto/pkg/ed25519"
+	"github.com/ELares/crypto/pkg/mlkem"
+	"github.com/ELares/crypto/pkg/policy"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestKeyExchange(t *testing.T) {
+	h := NewHybrid()
+
+	testcases := []struct {
+		name string
+
+		generate func() (*PrivateKey, *PublicKey, error)
+
+		keyShareSize   int
+		ciphertextSize int
+	}{
+		{name: "X25519MLKEM768", generate: h.X25519MLKEM768, keyShareSize: 1216, ciphertextSize: 1120},
+		{name: "SecP256r1MLKEM768", generate: h.SecP256r1MLKEM768, keyShareSize: 1249, ciphertextSize: 1153},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			prvKey, pubKey, err := tc.generate()
+			assert.Nil(t, err)
+			assert.Equal(t, tc.name, prvKey.Group().String())
+
+			keyShare, err := h.ToRawPublicKey(pubKey)
+			assert.Nil(t, err)
+			assert.Len(t, keyShare, tc.keyShareSize)
+
+			peerPubKey, err := h.FromRawPublicKey(prvKey.Group(), keyShare)
+			assert.Nil(t, err)
+			assert.True(t, pubKey.Equal(peerPubKey))
+
+			ciphertext, sharedSecret, err := h.Encapsulate(peerPubKey)
+			assert.Nil(t, err)
+			assert.Len(t, ciphertext, tc.ciphertextSize)
+			assert.Len(t, sharedSecret, SharedSecretSize)
+
+			decapsulated, err := h.Decapsulate(prvKey, ciphertext)
+			assert.Nil(t, err)
+			assert.Equal(t, sharedSecret, decapsulated)
+
+			ciphertext, tlsSecret, err := h.EncapsulateTLS(peerPubKey)
+			assert.Nil(t, err)
+			assert.Len(t, ciphertext, tc.ciphertextSize)
+			assert.Len(t, tlsSecret, TLSSharedSecretSize)
+
+			decapsulated, err = h.DecapsulateTLS(prvKey, ciphertext)
+			assert.Nil(t, err)
+			assert.Equal(t, tlsSecret, decapsulated)
+
+			_, err = h.Decapsulate(prvKey, ciphertext[1:])
+			assert.Equal(t, c.ErrInvalidCiphertext, err)
+
+			_, err = h.DecapsulateTLS(prvKey, ciphertext[1:])
+			assert.Equal(t, c.ErrInvalidCiphertext, err)
+
+			_, err = h.FromRawPublicKey(prvKey.Group(), keyShare[1:])
+			assert.Equal(t, c.ErrInvalidKeyLength, err)
+		})
+	}
+}
+
+func TestWireFormat(t *testing.T) {
+	h := NewHybrid()
+	m := mlkem.NewMLKEM()
+
+	testcases := []struct {
+		name string
+
+		generate func() (*PrivateKey, *PublicKey, error)
+
+		// offsets of the classical and ML-KEM-768 parts in the key share, ciphertext and secret
+		classicalFirst bool
+		pointSize      int
+
+		label string
+	}{
+		{name: "X25519MLKEM768: ML-KEM first", generate: h.X25519MLKEM768, pointSize: 32, label: `\.//^\`},
+		{name: "SecP256r1MLKEM768: ECDH first", generate: h.SecP256r1MLKEM768, classicalFirst: true, pointSize: 65, label: "SecP256r1MLKEM768"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			prvKey, pubKey, _ := tc.generate()
+
+			keyShare, _ := h.ToRawPublicKey(pubKey)
+			ciphertext, tlsSecret, _ := h.EncapsulateTLS(pubKey)
+
+			encapsulationKey, _ := m.ToRawPublicKey(prvKey.mlkem.Public())
+			point, mlkemCiphertext := ciphertext[len(ciphertext)-tc.pointSize:], ciphertext[:len(ciphertext)-tc.pointSize]
+			if tc.classicalFirst {
+				assert.Equal(t, append(prvKey.classical.PublicKey().Bytes(), encapsulationKey...), keyShare)
+				point, mlkemCiphertext = ciphertext[:tc.pointSize], ciphertext[tc.pointSize:]
+			} else {
+				assert.Equal(t, append(encapsulationKey, prvKey.classical.PublicKey().Bytes()...), keyShare)
+			}
+
+			peer, err := prvKey.classical.Curve().NewPublicKey(point)
+			assert.Nil(t, err)
+			classicalSecret, _ := prvKey.classical.ECDH(peer)
+			mlkemSecret, err := m.Decapsulate(prvKey.mlkem, mlkemCiphertext)
+			assert.Nil(t, err)
+
+			if tc.classicalFirst {
+				assert.Equal(t, append(classicalSecret, mlkemSecret...), tlsSecret)
+			} else {
+				assert.Equal(t, append(mlkemSecret, classicalSecret...), tlsSecret)
+			}
+
+			// SHA3-256(ss_M || ss_X || ct_X || pk_X || label)
+			sharedSecret, err := h.Decapsulate(prvKey, ciphertext)
+			assert.Nil(t, err)
+
+			combiner := sha3.New256()
+			combiner.Write(mlkemSecret)
+			combiner.Write(classicalSecret)
+			combiner.Write(point)
+			combiner.Write(prvKey.classical.PublicKey().Bytes())
+			combiner.Write([]byte(tc.label))
+			assert.Equal(t, combiner.Sum(nil), sharedSecret)
+		})
+	}
+}
+
+func TestXWing(t *testing.T) {
+	h := NewHybrid()
+	m := mlkem.NewMLKEM()
+
+	data, err := os.ReadFile("testdata/xwing.txt")
+	assert.Nil(t, err)
+
+	// SHAKE-128 of spec/test-vectors.txt of draft-connolly-cfrg-xwing-kem
+	checksum := make([]byte, 32)
+	sha3.ShakeSum128(checksum, data)
+	assert.Equal(t, "1bcd0057d861d6b866239936cadcaeee1ec0164dedc181c386e9e54fe46156fe", hex.EncodeToString(checksum))
+
+	vectors := parseXWingVectors(t, string(data))
+	assert.Equal(t, 3, len(vectors))
+
+	for i, vector := range vectors {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			// the X-Wing private key is a seed expanded by SHAKE-256 into the ML-KEM-768 d || z seed
+			// and the X25519 private key
+			expanded := make([]byte, mlkem.SeedSize+32)
+			sha3.ShakeSum256(expanded, vector["sk"])
+
+			postQuantum, err := m.FromRawPrivateKey(mlkem.MLKEM768, expanded[:mlkem.SeedSize])
+			assert.Nil(t, err)
+
+			classical, err := ecdh.X25519().NewPrivateKey(expanded[mlkem.SeedSize:])
+			assert.Nil(t, err)
+
+			prvKey, err := h.FromKeys(classical, postQuantum)
+			assert.Nil(t, err)
+
+			keyShare, err := h.ToRawPublicKey(prvKey.Public())
+			assert.Nil(t, err)
+			assert.Equal(t, vector["pk"], keyShare)
+
+			sharedSecret, err := h.Decapsulate(prvKey, vector["ct"])
+			assert.Nil(t, err)
+			assert.Equal(t, vector["ss"], sharedSecret)
+
+			pubKey, err := h.FromRawPublicKey(X25519MLKEM768, vector["pk"])
+			assert.Nil(t, err)
+
+			ciphertext, sharedSecret, err := h.Encapsulate(pubKey)
+			assert.Nil(t, err)
+
+			decapsulated, err := h.Decapsulate(prvKey, ciphertext)
+			assert.Nil(t, err)
+			assert.Equal(t, sharedSecret, decapsulated)
+		})
+	}
+}
+
+func TestFromKeys(t *testing.T) {
+	h := NewHybrid()
+
+	mlkemKey, _, _ := mlkem.NewMLKEM().MLKEM768()
+	x25519Key, _, _ := ed25519.NewED25519().X25519()
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p256Key, _ := ecdsaKey.ECDH()
+
+	prvKey, err := h.FromKeys(x25519Key, mlkemKey)
+	assert.Nil(t, err)
+	assert.Equal(t, X25519MLKEM768, prvKey.Group())
+
+	prvKey, err = h.FromKeys(p256Key, mlkemKey)
+	assert.Nil(t, err)
+	assert.Equal(t, SecP256r1MLKEM768, prvKey.Group())
+
+	ciphertext, sharedSecret, _ := h.Encapsulate(prvKey.Public())
+	decapsulated, err := h.Decapsulate(prvKey, ciphertext)
+	assert.Nil(t, err)
+	assert.Equal(t, sharedSecret, decapsulated)
+
+	mlkem1024Key, _, _ := mlkem.NewMLKEM().MLKEM1024()
+	_, err = h.FromKeys(x25519Key, mlkem1024Key)
+	assert.Equal(t, c.ErrUnsupportedParameterSet, err)
+
+	p384Key, _ := ecdh.P384().GenerateKey(rand.Reader)
+	_, err = h.FromKeys(p384Key, mlkemKey)
+	assert.Equal(t, c.ErrUnsupportedCurve, err)
+
+	_, err = h.FromKeys(nil, mlkemKey)
+	assert.Equal(t, c.ErrNilPrivateKey, err)
+}
+
+func TestInvalidPeer(t *testing.T) {
+	h := NewHybrid()
+
+	prvKey, pubKey, _ := h.X25519MLKEM768()
+	ciphertext, _, _ := h.Encapsulate(pubKey)
+
+	// an all zero X25519 public key is a low order point
+	lowOrder := append(append([]byte{}, ciphertext[:mlkem768CiphertextSize]...), make([]byte, 32)...)
+	_, err := h.Decapsulate(prvKey, lowOrder)
+	assert.Equal(t, c.ErrLowOrderPoint, err)
+
+	p256PrvKey, p256PubKey, _ := h.SecP256r1MLKEM768()
+	keyShare, _ := h.ToRawPublicKey(p256PubKey)
+
+	// a compressed point prefix is not a valid key share
+	keyShare[0] = 2
+	_, err = h.FromRawPublicKey(SecP256r1MLKEM768, keyShare)
+	assert.Equal(t, c.ErrInvalidPoint, err)
+
+	ciphertext, _, _ = h.Encapsulate(p256PubKey)
+	ciphertext[1] ^= 1
+	_, err = h.Decapsulate(p256PrvKey, ciphertext)
+	assert.Equal(t, c.ErrInvalidPoint, err)
+
+	_, err = h.FromRawPublicKey(Group(0x001d), keyShare)
+	assert.Equal(t, c.ErrUnsupportedParameterSet, err)
+}
+
+func TestWithPolicy(t *testing.T) {
+	_, pubKey, _ := NewHybrid().X25519MLKEM768()
+	keyShare, _ := NewHybrid().ToRawPublicKey(pubKey)
+
+	modern, _ := policy.NewPolicy(policy.Modern)
+	_, err := NewHybridWithPolicy(modern).FromRawPublicKey(X25519MLKEM768, keyShare)
+	assert.Nil(t, err)
+
+	cnsa, _ := policy.NewPolicy(policy.CNSA2)
+	h := NewHybridWithPolicy(cnsa)
+
+	prvKey, pubKey, err := h.SecP256r1MLKEM768()
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+	assert.Nil(t, prvKey)
+	assert.Nil(t, pubKey)
+
+	_, err = h.FromRawPublicKey(X25519MLKEM768, keyShare)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+}
+
+// parseXWingVectors parses the X-Wing test vectors, each field being a name followed by its hex
+// value on the same line or on the indented lines below, vectors being separated by blank lines
+func parseXWingVectors(t *testing.T, data string) []map[string][]byte {
+	var vectors []map[string][]byte
+
+	vector, name := map[string][]byte{}, ""
+	for _, line := range strings.Split(data, "\n") {
+		switch {
+		case line == "":
+			if len(vector) > 0 {
+				vectors = append(vectors, vector)
+			}
+
+			vector = map[string][]byte{}
+		case strings.HasPrefix(line, "  "):
+			value, err := hex.DecodeString(strings.TrimSpace(line))
+			assert.Nil(t, err)
+
+			vector[name] = append(vector[name], value...)
+		default:
+			fields := strings.Fields(line)
+			name = fields[0]
+
+			if len(fields) == 2 {
+				value, err := hex.DecodeString(fields[1])
+				assert.Nil(t, err)
+
+				vector[name] = value
+			}
+		}
+	}
+
+	return vectors
+}
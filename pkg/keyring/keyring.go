@@ -0,0 +1,427 @@
+package keyring
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	c "github.com/ELares/crypto/pkg"
+	ec "github.com/ELares/crypto/pkg/ecdsa"
+	r "github.com/ELares/crypto/pkg/rsa"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	// StatePending generated and published for verification, not signing yet
+	StatePending State = iota
+
+	// StateActive the signing key, published for verification
+	StateActive
+
+	// StateRetiring replaced by a newer active key, published for verification until it expires
+	StateRetiring
+
+	// StateRetired no longer published, the private key is kept until destroyed
+	StateRetired
+
+	// StateDestroyed the private key was dropped
+	StateDestroyed
+)
+
+type (
+	// State lifecycle state of a key version
+	State int
+
+	// Generator generates the private key of a new key version, e.g. wrapping IECDSA.P256 or
+	// IRSA.R2048
+	Generator func() (crypto.Signer, error)
+
+	// Options rotation schedule of a keyring
+	Options struct {
+		// RotationPeriod age of the active key after which Maintain rotates it, zero disables
+		// scheduled rotation
+		RotationPeriod time.Duration
+
+		// GracePeriod time a replaced key stays retiring, and valid for verification, before it is
+		// retired
+		GracePeriod time.Duration
+	}
+
+	// Version a key version of the keyring, ID being its RFC 7638 JWK thumbprint
+	Version struct {
+		ID          string
+		Number      int
+		State       State
+		Signer      crypto.Signer
+		PublicKey   crypto.PublicKey
+		CreatedAt   time.Time
+		ActivatedAt time.Time
+		ExpiresAt   time.Time
+	}
+
+	// IKeyring interface for methods to rotate the key versions of a keyring and get its signing and
+	// verification keys
+	IKeyring interface {
+		Prepare() (Version, error)
+		Rotate() (Version, error)
+		Retire(id string) error
+		Destroy(id string) error
+
+		Maintain() error
+		Run(ctx context.Context, interval time.Duration)
+		Err() error
+
+		Active() (Version, error)
+		Keys() (Version, []Version, error)
+		Versions() []Version
+		JWKS() ([]byte, error)
+	}
+
+	// Keyring struct to implement the IKeyring methods in memory
+	Keyring struct {
+		generator Generator
+		options   Options
+		now       func() time.Time
+
+		mu       sync.RWMutex
+		versions []*Version
+		err      error
+	}
+
+	// jwks JSON web key set (RFC 7517 section 5)
+	jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+)
+
+// NewKeyring gets a new empty Keyring pointer, Rotate or Maintain creating its first active key
+func NewKeyring(generator Generator, options Options) IKeyring {
+	return &Keyring{generator: generator, options: options, now: time.Now}
+}
+
+// String gets the state name
+func (s State) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateActive:
+		return "active"
+	case StateRetiring:
+		return "retiring"
+	case StateRetired:
+		return "retired"
+	case StateDestroyed:
+		return "destroyed"
+	}
+
+	return "unknown"
+}
+
+// Verifiable reports whether keys of the state are published for verification
+func (s State) Verifiable() bool {
+	return s == StatePending || s == StateActive || s == StateRetiring
+}
+
+// Prepare generates a pending key version, published for verification ahead of its activation by
+// the next rotation
+func (k *Keyring) Prepare() (Version, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	version, err := k.generate()
+	if err != nil {
+		return Version{}, err
+	}
+
+	return *version, nil
+}
+
+// Rotate activates the oldest pending key version, or a newly generated one, and moves the active
+// key version to retiring for the grace period
+func (k *Keyring) Rotate() (Version, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.rotate()
+}
+
+// Retire retires a pending or retiring key version immediately, e.g. after a compromise, the active
+// key version has to be rotated first
+func (k *Keyring) Retire(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	version := k.find(id)
+	if version == nil {
+		return c.ErrKeyNotFound
+	}
+
+	if version.State != StatePending && version.State != StateRetiring {
+		return c.ErrInvalidKeyState
+	}
+
+	version.State = StateRetired
+	version.ExpiresAt = k.now()
+
+	return nil
+}
+
+// Destroy drops the private key of a retired key version
+func (k *Keyring) Destroy(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	version := k.find(id)
+	if version == nil {
+		return c.ErrKeyNotFound
+	}
+
+	if version.State != StateRetired {
+		return c.ErrInvalidKeyState
+	}
+
+	version.State = StateDestroyed
+	version.Signer = nil
+
+	return nil
+}
+
+// Maintain retires the retiring key versions past their grace period and rotates the active key
+// version when the keyring has none or it is older than the rotation period
+func (k *Keyring) Maintain() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+
+	for _, version := range k.versions {
+		if version.State == StateRetiring && !now.Before(version.ExpiresAt) {
+			version.State = StateRetired
+		}
+	}
+
+	active := k.active()
+	if active == nil || (k.options.RotationPeriod > 0 && !now.Before(active.ActivatedAt.Add(k.options.RotationPeriod))) {
+		_, err := k.rotate()
+		k.err = err
+
+		return err
+	}
+
+	k.err = nil
+
+	return nil
+}
+
+// Run calls Maintain every interval until the context is done, Err returning the error of the last
+// call
+func (k *Keyring) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = k.Maintain()
+		}
+	}
+}
+
+// Err returns the error of the last Maintain call
+func (k *Keyring) Err() error {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.err
+}
+
+// Active gets the active signing key version
+func (k *Keyring) Active() (Version, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	active := k.active()
+	if active == nil {
+		return Version{}, c.ErrNoActiveKey
+	}
+
+	return *active, nil
+}
+
+// Keys gets the active signing key version and the pending, active and retiring key versions valid
+// for verification, newest first
+func (k *Keyring) Keys() (Version, []Version, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	active := k.active()
+	if active == nil {
+		return Version{}, nil, c.ErrNoActiveKey
+	}
+
+	return *active, k.verifiable(), nil
+}
+
+// Versions gets every key version, oldest first
+func (k *Keyring) Versions() []Version {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	versions := make([]Version, 0, len(k.versions))
+	for _, version := range k.versions {
+		versions = append(versions, *version)
+	}
+
+	return versions
+}
+
+// JWKS converts the public keys of the key versions valid for verification into a JSON web key set
+func (k *Keyring) JWKS() ([]byte, error) {
+	k.mu.RLock()
+	versions := k.verifiable()
+	k.mu.RUnlock()
+
+	set := jwks{Keys: []json.RawMessage{}}
+	for _, version := range versions {
+		key, err := toJWK(version)
+		if err != nil {
+			return nil, err
+		}
+
+		set.Keys = append(set.Keys, key)
+	}
+
+	return json.Marshal(set)
+}
+
+// rotate implements Rotate, the lock being held
+func (k *Keyring) rotate() (Version, error) {
+	var next *Version
+	for _, version := range k.versions {
+		if version.State == StatePending {
+			next = version
+			break
+		}
+	}
+
+	if next == nil {
+		version, err := k.generate()
+		if err != nil {
+			return Version{}, err
+		}
+
+		next = version
+	}
+
+	now := k.now()
+
+	if previous := k.active(); previous != nil {
+		previous.State = StateRetiring
+		previous.ExpiresAt = now.Add(k.options.GracePeriod)
+
+		if k.options.GracePeriod <= 0 {
+			previous.State = StateRetired
+		}
+	}
+
+	next.State = StateActive
+	next.ActivatedAt = now
+	next.ExpiresAt = time.Time{}
+
+	if k.options.RotationPeriod > 0 {
+		next.ExpiresAt = now.Add(k.options.RotationPeriod + k.options.GracePeriod)
+	}
+
+	return *next, nil
+}
+
+// generate generates a new pending key version, the lock being held
+func (k *Keyring) generate() (*Version, error) {
+	signer, err := k.generator()
+	if err != nil {
+		return nil, err
+	}
+
+	if signer == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	thumbprint, err := (&jose.JSONWebKey{Key: signer.Public()}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, c.ErrUnsupportedPublicKey
+	}
+
+	version := &Version{
+		ID:        base64.RawURLEncoding.EncodeToString(thumbprint),
+		Number:    len(k.versions) + 1,
+		State:     StatePending,
+		Signer:    signer,
+		PublicKey: signer.Public(),
+		CreatedAt: k.now(),
+	}
+
+	k.versions = append(k.versions, version)
+
+	return version, nil
+}
+
+// active gets the active key version, nil when there is none
+func (k *Keyring) active() *Version {
+	for _, version := range k.versions {
+		if version.State == StateActive {
+			return version
+		}
+	}
+
+	return nil
+}
+
+// verifiable gets the key versions valid for verification, newest first
+func (k *Keyring) verifiable() []Version {
+	var versions []Version
+	for _, version := range k.versions {
+		if version.State.Verifiable() {
+			versions = append(versions, *version)
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Number > versions[j].Number
+	})
+
+	return versions
+}
+
+// find gets the key version with the id, nil when there is none
+func (k *Keyring) find(id string) *Version {
+	for _, version := range k.versions {
+		if version.ID == id {
+			return version
+		}
+	}
+
+	return nil
+}
+
+// toJWK converts the public key of the key version into a jwk with the key version id
+func toJWK(version Version) (json.RawMessage, error) {
+	switch key := version.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return r.NewRSA().ToJWKAuto(key, version.ID)
+	case *ecdsa.PublicKey:
+		return ec.NewECDSA().ToJWKAuto(key, version.ID)
+	case ed25519.PublicKey:
+		return (&jose.JSONWebKey{Use: c.SIG, Algorithm: string(jose.EdDSA), Key: key, KeyID: version.ID}).MarshalJSON()
+	}
+
+	return nil, c.ErrUnsupportedPublicKey
+}
@@ -0,0 +1,211 @@
+package keyring
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	c "github.com/ELares/crypto/pkg"
+	"github.com/ELares/crypto/pkg/ecdsa"
+	"github.com/ELares/crypto/pkg/rsa"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+// clock fake clock for the keyring tests
+type clock struct {
+	now time.Time
+}
+
+func (cl *clock) Now() time.Time {
+	return cl.now
+}
+
+func p256() (crypto.Signer, error) {
+	prvKey, _, err := ecdsa.NewECDSA().P256()
+	return prvKey, err
+}
+
+func newKeyring(generator Generator, options Options) (*Keyring, *clock) {
+	cl := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	keyring := NewKeyring(generator, options).(*Keyring)
+	keyring.now = cl.Now
+
+	return keyring, cl
+}
+
+func TestRotate(t *testing.T) {
+	keyring, cl := newKeyring(p256, Options{RotationPeriod: 30 * 24 * time.Hour, GracePeriod: 7 * 24 * time.Hour})
+
+	_, err := keyring.Active()
+	assert.Equal(t, c.ErrNoActiveKey, err)
+
+	first, err := keyring.Rotate()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, first.Number)
+	assert.Equal(t, StateActive, first.State)
+	assert.Equal(t, cl.now, first.ActivatedAt)
+	assert.Equal(t, cl.now.Add(37*24*time.Hour), first.ExpiresAt)
+
+	cl.now = cl.now.Add(time.Hour)
+	second, err := keyring.Rotate()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, second.Number)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	active, verification, err := keyring.Keys()
+	assert.Nil(t, err)
+	assert.Equal(t, second.ID, active.ID)
+	assert.Len(t, verification, 2)
+	assert.Equal(t, second.ID, verification[0].ID)
+	assert.Equal(t, first.ID, verification[1].ID)
+	assert.Equal(t, StateRetiring, verification[1].State)
+	assert.Equal(t, cl.now.Add(7*24*time.Hour), verification[1].ExpiresAt)
+}
+
+func TestPrepare(t *testing.T) {
+	keyring, _ := newKeyring(p256, Options{GracePeriod: time.Hour})
+
+	first, _ := keyring.Rotate()
+
+	pending, err := keyring.Prepare()
+	assert.Nil(t, err)
+	assert.Equal(t, StatePending, pending.State)
+
+	// pending keys are published before they sign
+	active, verification, _ := keyring.Keys()
+	assert.Equal(t, first.ID, active.ID)
+	assert.Len(t, verification, 2)
+
+	next, err := keyring.Rotate()
+	assert.Nil(t, err)
+	assert.Equal(t, pending.ID, next.ID)
+	assert.Equal(t, StateActive, next.State)
+	assert.Len(t, keyring.Versions(), 2)
+}
+
+func TestMaintain(t *testing.T) {
+	keyring, cl := newKeyring(p256, Options{RotationPeriod: 30 * 24 * time.Hour, GracePeriod: 7 * 24 * time.Hour})
+
+	// an empty keyring gets its first active key
+	assert.Nil(t, keyring.Maintain())
+	first, err := keyring.Active()
+	assert.Nil(t, err)
+
+	cl.now = cl.now.Add(29 * 24 * time.Hour)
+	assert.Nil(t, keyring.Maintain())
+	active, _ := keyring.Active()
+	assert.Equal(t, first.ID, active.ID)
+
+	cl.now = cl.now.Add(24 * time.Hour)
+	assert.Nil(t, keyring.Maintain())
+	second, _ := keyring.Active()
+	assert.NotEqual(t, first.ID, second.ID)
+
+	_, verification, _ := keyring.Keys()
+	assert.Len(t, verification, 2)
+
+	cl.now = cl.now.Add(7 * 24 * time.Hour)
+	assert.Nil(t, keyring.Maintain())
+
+	_, verification, _ = keyring.Keys()
+	assert.Len(t, verification, 1)
+	assert.Equal(t, StateRetired, keyring.Versions()[0].State)
+	assert.NotNil(t, keyring.Versions()[0].Signer)
+}
+
+func TestRetireDestroy(t *testing.T) {
+	keyring, _ := newKeyring(p256, Options{GracePeriod: time.Hour})
+
+	first, _ := keyring.Rotate()
+	second, _ := keyring.Rotate()
+
+	testcases := []struct {
+		name string
+
+		run func() error
+
+		expectError error
+	}{
+		{name: "Invalid Retire: Active", run: func() error { return keyring.Retire(second.ID) }, expectError: c.ErrInvalidKeyState},
+		{name: "Invalid Destroy: Retiring", run: func() error { return keyring.Destroy(first.ID) }, expectError: c.ErrInvalidKeyState},
+		{name: "Invalid Retire: Unknown", run: func() error { return keyring.Retire("unknown") }, expectError: c.ErrKeyNotFound},
+		{name: "Valid Retire: Retiring", run: func() error { return keyring.Retire(first.ID) }},
+		{name: "Valid Destroy: Retired", run: func() error { return keyring.Destroy(first.ID) }},
+		{name: "Invalid Destroy: Destroyed", run: func() error { return keyring.Destroy(first.ID) }, expectError: c.ErrInvalidKeyState},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectError, tc.run())
+		})
+	}
+
+	destroyed := keyring.Versions()[0]
+	assert.Equal(t, StateDestroyed, destroyed.State)
+	assert.Nil(t, destroyed.Signer)
+	assert.NotNil(t, destroyed.PublicKey)
+}
+
+func TestJWKS(t *testing.T) {
+	generators := []Generator{
+		p256,
+		func() (crypto.Signer, error) {
+			prvKey, _, err := rsa.NewRSA().R2048()
+			return prvKey, err
+		},
+		func() (crypto.Signer, error) {
+			_, prvKey, err := ed25519.GenerateKey(rand.Reader)
+			return prvKey, err
+		},
+	}
+
+	for _, generator := range generators {
+		keyring, _ := newKeyring(generator, Options{GracePeriod: time.Hour})
+
+		first, _ := keyring.Rotate()
+		second, _ := keyring.Rotate()
+
+		data, err := keyring.JWKS()
+		assert.Nil(t, err)
+
+		var set jose.JSONWebKeySet
+		assert.Nil(t, json.Unmarshal(data, &set))
+		assert.Len(t, set.Keys, 2)
+		assert.Equal(t, second.ID, set.Keys[0].KeyID)
+		assert.Equal(t, first.ID, set.Keys[1].KeyID)
+		assert.Equal(t, c.SIG, set.Keys[0].Use)
+		assert.NotEmpty(t, set.Keys[0].Algorithm)
+
+		// the kid is the RFC 7638 thumbprint of the key
+		thumbprint, _ := set.Keys[0].Thumbprint(crypto.SHA256)
+		assert.Equal(t, second.ID, base64.RawURLEncoding.EncodeToString(thumbprint))
+	}
+
+	empty, _ := newKeyring(p256, Options{})
+	data, err := empty.JWKS()
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"keys":[]}`, string(data))
+}
+
+func TestRun(t *testing.T) {
+	failure := errors.New("generator failure")
+	keyring, _ := newKeyring(func() (crypto.Signer, error) { return nil, failure }, Options{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	keyring.Run(ctx, 10*time.Millisecond)
+	assert.Equal(t, failure, keyring.Err())
+
+	keyring.generator = p256
+	assert.Nil(t, keyring.Maintain())
+	assert.Nil(t, keyring.Err())
+}
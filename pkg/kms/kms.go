@@ -0,0 +1,109 @@
+package kms
+
+import (
+	"context"
+	"crypto"
+	"time"
+)
+
+const (
+	// RSA2048 RSA-2048 key, for signing or decryption
+	RSA2048 KeySpec = iota
+
+	// RSA4096 RSA-4096 key, for signing or decryption
+	RSA4096
+
+	// ECDSAP256 ECDSA P-256 key, for signing
+	ECDSAP256
+
+	// ECDSAP384 ECDSA P-384 key, for signing
+	ECDSAP384
+
+	// ECDSAP521 ECDSA P-521 key, for signing
+	ECDSAP521
+
+	// Ed25519 Ed25519 key, for signing
+	Ed25519
+)
+
+const (
+	// UsageSign key for Sign
+	UsageSign KeyUsage = iota
+
+	// UsageDecrypt key for Decrypt, rsa only
+	UsageDecrypt
+)
+
+type (
+	// KeySpec key type and size of a managed key
+	KeySpec int
+
+	// KeyUsage operation a managed key is restricted to
+	KeyUsage int
+
+	// Key description of a managed key, Version being its current version, incremented by Rotate
+	Key struct {
+		ID        string
+		Spec      KeySpec
+		Usage     KeyUsage
+		Version   int
+		Enabled   bool
+		CreatedAt time.Time
+		RotatedAt time.Time
+	}
+
+	// KeyManager interface for methods to create and use keys whose private part never leaves the
+	// key manager, implemented by the local software backend and by KMS or HSM backends
+	//
+	// Sign follows crypto.Signer: rsa and ecdsa keys sign a digest of opts.HashFunc(), rsa keys
+	// using PSS when opts is a *rsa.PSSOptions, and ed25519 keys sign the message with crypto.Hash(0).
+	// Decrypt uses RSA-OAEP with the *rsa.OAEPOptions hash, SHA-256 when opts is nil, and accepts
+	// ciphertexts of every version of the key. Every method fails with c.ErrKeyNotFound for unknown
+	// ids, Sign, Decrypt and Rotate with c.ErrKeyDisabled for disabled keys
+	KeyManager interface {
+		CreateKey(ctx context.Context, spec KeySpec, usage KeyUsage) (Key, error)
+		GetKey(ctx context.Context, id string) (Key, error)
+		GetPublicKey(ctx context.Context, id string) (crypto.PublicKey, error)
+		Sign(ctx context.Context, id string, digest []byte, opts crypto.SignerOpts) ([]byte, error)
+		Decrypt(ctx context.Context, id string, ciphertext []byte, opts crypto.DecrypterOpts) ([]byte, error)
+		Rotate(ctx context.Context, id string) (Key, error)
+		Disable(ctx context.Context, id string) error
+	}
+)
+
+// String gets the key spec name
+func (s KeySpec) String() string {
+	switch s {
+	case RSA2048:
+		return "RSA-2048"
+	case RSA4096:
+		return "RSA-4096"
+	case ECDSAP256:
+		return "ECDSA-P256"
+	case ECDSAP384:
+		return "ECDSA-P384"
+	case ECDSAP521:
+		return "ECDSA-P521"
+	case Ed25519:
+		return "Ed25519"
+	}
+
+	return "unknown"
+}
+
+// RSA reports whether the key spec is a rsa key, the only keys supporting UsageDecrypt
+func (s KeySpec) RSA() bool {
+	return s == RSA2048 || s == RSA4096
+}
+
+// String gets the key usage name
+func (u KeyUsage) String() string {
+	switch u {
+	case UsageSign:
+		return "sign"
+	case UsageDecrypt:
+		return "decrypt"
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,313 @@
+// Package kmstest contract test suite of the kms.KeyManager interface, every backend has to pass
+//
+//	func TestKeyManager(t *testing.T) {
+//		kmstest.TestKeyManager(t, func(t *testing.T) kms.KeyManager { return newBackend(t) })
+//	}
+package kmstest
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"testing"
+
+	c "github.com/ELares/crypto/pkg"
+	"github.com/ELares/crypto/pkg/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AllSpecs every key spec of the kms package
+var AllSpecs = []kms.KeySpec{kms.RSA2048, kms.RSA4096, kms.ECDSAP256, kms.ECDSAP384, kms.ECDSAP521, kms.Ed25519}
+
+// TestKeyManager runs the contract tests against key managers of the factory, a new one per test,
+// for the key specs supported by the backend, every spec when none is given
+func TestKeyManager(t *testing.T, newKeyManager func(t *testing.T) kms.KeyManager, specs ...kms.KeySpec) {
+	if len(specs) == 0 {
+		specs = AllSpecs
+	}
+
+	var rsaSpecs []kms.KeySpec
+	for _, spec := range specs {
+		if spec.RSA() {
+			rsaSpecs = append(rsaSpecs, spec)
+		}
+	}
+
+	t.Run("CreateKey", func(t *testing.T) { testCreateKey(t, newKeyManager(t), specs) })
+	t.Run("Sign", func(t *testing.T) { testSign(t, newKeyManager(t), specs) })
+	t.Run("Decrypt", func(t *testing.T) { testDecrypt(t, newKeyManager(t), rsaSpecs) })
+	t.Run("Rotate", func(t *testing.T) { testRotate(t, newKeyManager(t), specs[0], rsaSpecs) })
+	t.Run("Disable", func(t *testing.T) { testDisable(t, newKeyManager(t), specs[0], rsaSpecs) })
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, newKeyManager(t)) })
+	t.Run("Context", func(t *testing.T) { testContext(t, newKeyManager(t), specs[0]) })
+}
+
+func testCreateKey(t *testing.T, km kms.KeyManager, specs []kms.KeySpec) {
+	ctx := context.Background()
+
+	for _, spec := range specs {
+		t.Run(spec.String(), func(t *testing.T) {
+			key, err := km.CreateKey(ctx, spec, kms.UsageSign)
+			require.Nil(t, err)
+			assert.NotEmpty(t, key.ID)
+			assert.Equal(t, spec, key.Spec)
+			assert.Equal(t, kms.UsageSign, key.Usage)
+			assert.Equal(t, 1, key.Version)
+			assert.True(t, key.Enabled)
+			assert.False(t, key.CreatedAt.IsZero())
+
+			got, err := km.GetKey(ctx, key.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, key.ID, got.ID)
+			assert.Equal(t, key.Version, got.Version)
+
+			publicKey, err := km.GetPublicKey(ctx, key.ID)
+			require.Nil(t, err)
+
+			switch spec {
+			case kms.RSA2048, kms.RSA4096:
+				assert.IsType(t, &rsa.PublicKey{}, publicKey)
+			case kms.ECDSAP256, kms.ECDSAP384, kms.ECDSAP521:
+				assert.IsType(t, &ecdsa.PublicKey{}, publicKey)
+			case kms.Ed25519:
+				assert.IsType(t, ed25519.PublicKey{}, publicKey)
+			}
+
+			if !spec.RSA() {
+				_, err := km.CreateKey(ctx, spec, kms.UsageDecrypt)
+				assert.True(t, errors.Is(err, c.ErrUnsupportedKeySpec))
+			}
+		})
+	}
+
+	first, err := km.CreateKey(ctx, specs[0], kms.UsageSign)
+	require.Nil(t, err)
+
+	second, err := km.CreateKey(ctx, specs[0], kms.UsageSign)
+	require.Nil(t, err)
+	assert.NotEqual(t, first.ID, second.ID)
+}
+
+func testSign(t *testing.T, km kms.KeyManager, specs []kms.KeySpec) {
+	ctx := context.Background()
+	message := []byte("message")
+
+	for _, spec := range specs {
+		t.Run(spec.String(), func(t *testing.T) {
+			key, err := km.CreateKey(ctx, spec, kms.UsageSign)
+			require.Nil(t, err)
+
+			publicKey, err := km.GetPublicKey(ctx, key.ID)
+			require.Nil(t, err)
+
+			assert.True(t, sign(t, km, key.ID, publicKey, message))
+
+			if spec == kms.Ed25519 {
+				return
+			}
+
+			_, err = km.Sign(ctx, key.ID, message, crypto.SHA256)
+			assert.True(t, errors.Is(err, c.ErrInvalidDigest))
+		})
+	}
+}
+
+func testDecrypt(t *testing.T, km kms.KeyManager, specs []kms.KeySpec) {
+	if len(specs) == 0 {
+		t.Skip("no rsa key spec")
+	}
+
+	ctx := context.Background()
+	plaintext := []byte("data key")
+
+	for _, spec := range specs {
+		t.Run(spec.String(), func(t *testing.T) {
+			key, err := km.CreateKey(ctx, spec, kms.UsageDecrypt)
+			require.Nil(t, err)
+			assert.Equal(t, kms.UsageDecrypt, key.Usage)
+
+			publicKey, err := km.GetPublicKey(ctx, key.ID)
+			require.Nil(t, err)
+
+			rsaPublicKey := publicKey.(*rsa.PublicKey)
+
+			// SHA-256 is the default
+			ciphertext, _ := rsa.EncryptOAEP(sha256.New(), rand.Reader, rsaPublicKey, plaintext, nil)
+			decrypted, err := km.Decrypt(ctx, key.ID, ciphertext, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, plaintext, decrypted)
+
+			ciphertext, _ = rsa.EncryptOAEP(sha512.New(), rand.Reader, rsaPublicKey, plaintext, []byte("label"))
+			decrypted, err = km.Decrypt(ctx, key.ID, ciphertext, &rsa.OAEPOptions{Hash: crypto.SHA512, Label: []byte("label")})
+			assert.Nil(t, err)
+			assert.Equal(t, plaintext, decrypted)
+
+			_, err = km.Decrypt(ctx, key.ID, ciphertext, nil)
+			assert.NotNil(t, err)
+
+			ciphertext, _ = rsa.EncryptPKCS1v15(rand.Reader, rsaPublicKey, plaintext)
+			_, err = km.Decrypt(ctx, key.ID, ciphertext, &rsa.PKCS1v15DecryptOptions{})
+			assert.True(t, errors.Is(err, c.ErrUnsupportedAlgorithm))
+
+			_, err = km.Sign(ctx, key.ID, make([]byte, 32), crypto.SHA256)
+			assert.True(t, errors.Is(err, c.ErrInvalidKeyUsage))
+
+			signing, err := km.CreateKey(ctx, spec, kms.UsageSign)
+			require.Nil(t, err)
+
+			_, err = km.Decrypt(ctx, signing.ID, ciphertext, nil)
+			assert.True(t, errors.Is(err, c.ErrInvalidKeyUsage))
+		})
+	}
+}
+
+func testRotate(t *testing.T, km kms.KeyManager, spec kms.KeySpec, rsaSpecs []kms.KeySpec) {
+	ctx := context.Background()
+	message := []byte("message")
+
+	key, err := km.CreateKey(ctx, spec, kms.UsageSign)
+	require.Nil(t, err)
+
+	previous, _ := km.GetPublicKey(ctx, key.ID)
+
+	rotated, err := km.Rotate(ctx, key.ID)
+	require.Nil(t, err)
+	assert.Equal(t, key.ID, rotated.ID)
+	assert.Equal(t, 2, rotated.Version)
+
+	current, _ := km.GetPublicKey(ctx, key.ID)
+	assert.False(t, current.(interface{ Equal(crypto.PublicKey) bool }).Equal(previous))
+
+	// signatures are made with the current version
+	assert.True(t, sign(t, km, key.ID, current, message))
+	assert.False(t, sign(t, km, key.ID, previous, message))
+
+	if len(rsaSpecs) == 0 {
+		return
+	}
+
+	// ciphertexts of previous versions still decrypt
+	decryption, err := km.CreateKey(ctx, rsaSpecs[0], kms.UsageDecrypt)
+	require.Nil(t, err)
+
+	publicKey, _ := km.GetPublicKey(ctx, decryption.ID)
+	ciphertext, _ := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey.(*rsa.PublicKey), message, nil)
+
+	_, err = km.Rotate(ctx, decryption.ID)
+	require.Nil(t, err)
+
+	decrypted, err := km.Decrypt(ctx, decryption.ID, ciphertext, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, message, decrypted)
+}
+
+func testDisable(t *testing.T, km kms.KeyManager, spec kms.KeySpec, rsaSpecs []kms.KeySpec) {
+	ctx := context.Background()
+
+	key, err := km.CreateKey(ctx, spec, kms.UsageSign)
+	require.Nil(t, err)
+	require.Nil(t, km.Disable(ctx, key.ID))
+
+	disabled, err := km.GetKey(ctx, key.ID)
+	assert.Nil(t, err)
+	assert.False(t, disabled.Enabled)
+
+	_, err = km.Sign(ctx, key.ID, make([]byte, 32), crypto.SHA256)
+	assert.True(t, errors.Is(err, c.ErrKeyDisabled))
+
+	_, err = km.Rotate(ctx, key.ID)
+	assert.True(t, errors.Is(err, c.ErrKeyDisabled))
+
+	// the public key stays available to verify existing signatures
+	_, err = km.GetPublicKey(ctx, key.ID)
+	assert.Nil(t, err)
+
+	if len(rsaSpecs) == 0 {
+		return
+	}
+
+	decryption, err := km.CreateKey(ctx, rsaSpecs[0], kms.UsageDecrypt)
+	require.Nil(t, err)
+	require.Nil(t, km.Disable(ctx, decryption.ID))
+
+	_, err = km.Decrypt(ctx, decryption.ID, make([]byte, 256), nil)
+	assert.True(t, errors.Is(err, c.ErrKeyDisabled))
+}
+
+func testNotFound(t *testing.T, km kms.KeyManager) {
+	ctx := context.Background()
+
+	testcases := []struct {
+		name string
+
+		run func() error
+	}{
+		{name: "GetKey", run: func() error { _, err := km.GetKey(ctx, "unknown"); return err }},
+		{name: "GetPublicKey", run: func() error { _, err := km.GetPublicKey(ctx, "unknown"); return err }},
+		{name: "Sign", run: func() error { _, err := km.Sign(ctx, "unknown", make([]byte, 32), crypto.SHA256); return err }},
+		{name: "Decrypt", run: func() error { _, err := km.Decrypt(ctx, "unknown", make([]byte, 256), nil); return err }},
+		{name: "Rotate", run: func() error { _, err := km.Rotate(ctx, "unknown"); return err }},
+		{name: "Disable", run: func() error { return km.Disable(ctx, "unknown") }},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.True(t, errors.Is(tc.run(), c.ErrKeyNotFound))
+		})
+	}
+}
+
+func testContext(t *testing.T, km kms.KeyManager, spec kms.KeySpec) {
+	key, err := km.CreateKey(context.Background(), spec, kms.UsageSign)
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = km.CreateKey(ctx, spec, kms.UsageSign)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	_, err = km.Sign(ctx, key.ID, make([]byte, 32), crypto.SHA256)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+// sign signs the message with the key, with PKCS#1 v1.5 and PSS for rsa keys, and reports whether
+// the signatures verify with the public key
+func sign(t *testing.T, km kms.KeyManager, id string, publicKey crypto.PublicKey, message []byte) bool {
+	ctx := context.Background()
+	digest := sha256.Sum256(message)
+
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		signature, err := km.Sign(ctx, id, digest[:], crypto.SHA256)
+		require.Nil(t, err)
+
+		pss := &rsa.PSSOptions{Hash: crypto.SHA256, SaltLength: rsa.PSSSaltLengthEqualsHash}
+		pssSignature, err := km.Sign(ctx, id, digest[:], pss)
+		require.Nil(t, err)
+
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) == nil &&
+			rsa.VerifyPSS(publicKey, crypto.SHA256, digest[:], pssSignature, pss) == nil
+	case *ecdsa.PublicKey:
+		signature, err := km.Sign(ctx, id, digest[:], crypto.SHA256)
+		require.Nil(t, err)
+
+		return ecdsa.VerifyASN1(publicKey, digest[:], signature)
+	case ed25519.PublicKey:
+		signature, err := km.Sign(ctx, id, message, crypto.Hash(0))
+		require.Nil(t, err)
+
+		return ed25519.Verify(publicKey, message, signature)
+	}
+
+	t.Fatalf("unsupported public key type %T", publicKey)
+
+	return false
+}
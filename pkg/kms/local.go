@@ -0,0 +1,285 @@
+package kms
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	c "github.com/ELares/crypto/pkg"
+	ec "github.com/ELares/crypto/pkg/ecdsa"
+	ed "github.com/ELares/crypto/pkg/ed25519"
+	"github.com/ELares/crypto/pkg/policy"
+	r "github.com/ELares/crypto/pkg/rsa"
+)
+
+type (
+	// LocalKeyManager struct to implement the KeyManager methods in memory with the rsa, ecdsa and
+	// ed25519 generators, for tests and single process deployments
+	LocalKeyManager struct {
+		rsa     r.IRSA
+		ecdsa   ec.IECDSA
+		ed25519 ed.IED25519
+		now     func() time.Time
+
+		mu   sync.RWMutex
+		keys map[string]*localKey
+	}
+
+	// localKey managed key and the signer of each of its versions, oldest first
+	localKey struct {
+		key      Key
+		versions []crypto.Signer
+	}
+)
+
+// NewLocalKeyManager gets a new empty LocalKeyManager pointer
+func NewLocalKeyManager() KeyManager {
+	return NewLocalKeyManagerWithPolicy(nil)
+}
+
+// NewLocalKeyManagerWithPolicy gets a new empty LocalKeyManager pointer enforcing the policy on
+// generated keys
+func NewLocalKeyManagerWithPolicy(policy *policy.Policy) KeyManager {
+	return &LocalKeyManager{
+		rsa:     r.NewRSAWithPolicy(policy),
+		ecdsa:   ec.NewECDSAWithPolicy(policy),
+		ed25519: ed.NewED25519WithPolicy(policy),
+		now:     time.Now,
+		keys:    map[string]*localKey{},
+	}
+}
+
+// CreateKey generates a new enabled key at version 1
+func (l *LocalKeyManager) CreateKey(ctx context.Context, spec KeySpec, usage KeyUsage) (Key, error) {
+	if err := ctx.Err(); err != nil {
+		return Key{}, err
+	}
+
+	if usage != UsageSign && (usage != UsageDecrypt || !spec.RSA()) {
+		return Key{}, c.ErrUnsupportedKeySpec
+	}
+
+	signer, err := l.generate(spec)
+	if err != nil {
+		return Key{}, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Key{}, err
+	}
+
+	now := l.now()
+	key := Key{ID: hex.EncodeToString(id), Spec: spec, Usage: usage, Version: 1, Enabled: true, CreatedAt: now, RotatedAt: now}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.keys[key.ID] = &localKey{key: key, versions: []crypto.Signer{signer}}
+
+	return key, nil
+}
+
+// GetKey gets the description of a key
+func (l *LocalKeyManager) GetKey(ctx context.Context, id string) (Key, error) {
+	if err := ctx.Err(); err != nil {
+		return Key{}, err
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	key, ok := l.keys[id]
+	if !ok {
+		return Key{}, c.ErrKeyNotFound
+	}
+
+	return key.key, nil
+}
+
+// GetPublicKey gets the public key of the current version of a key, disabled keys included
+func (l *LocalKeyManager) GetPublicKey(ctx context.Context, id string) (crypto.PublicKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	key, ok := l.keys[id]
+	if !ok {
+		return nil, c.ErrKeyNotFound
+	}
+
+	return key.current().Public(), nil
+}
+
+// Sign signs with the current version of a signing key
+func (l *LocalKeyManager) Sign(ctx context.Context, id string, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	key, err := l.usable(ctx, id, UsageSign)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.key.Spec == Ed25519 {
+		if opts == nil {
+			opts = crypto.Hash(0)
+		}
+
+		return key.current().Sign(rand.Reader, digest, opts)
+	}
+
+	if opts == nil || opts.HashFunc() == 0 || !opts.HashFunc().Available() {
+		return nil, c.ErrUnsupportedAlgorithm
+	}
+
+	if len(digest) != opts.HashFunc().Size() {
+		return nil, c.ErrInvalidDigest
+	}
+
+	return key.current().Sign(rand.Reader, digest, opts)
+}
+
+// Decrypt decrypts a RSA-OAEP ciphertext with the version of a decryption key it was encrypted to,
+// trying the versions newest first
+func (l *LocalKeyManager) Decrypt(ctx context.Context, id string, ciphertext []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	key, err := l.usable(ctx, id, UsageDecrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &rsa.OAEPOptions{Hash: crypto.SHA256}
+	}
+
+	if _, ok := opts.(*rsa.OAEPOptions); !ok {
+		return nil, c.ErrUnsupportedAlgorithm
+	}
+
+	for i := len(key.versions) - 1; i >= 0; i-- {
+		plaintext, err := key.versions[i].(crypto.Decrypter).Decrypt(nil, ciphertext, opts)
+		if err == nil {
+			return plaintext, nil
+		}
+	}
+
+	return nil, rsa.ErrDecryption
+}
+
+// Rotate generates a new current version of a key, previous versions staying available to
+// Decrypt
+func (l *LocalKeyManager) Rotate(ctx context.Context, id string) (Key, error) {
+	if err := ctx.Err(); err != nil {
+		return Key{}, err
+	}
+
+	l.mu.RLock()
+	key, ok := l.keys[id]
+	l.mu.RUnlock()
+
+	if !ok {
+		return Key{}, c.ErrKeyNotFound
+	}
+
+	signer, err := l.generate(key.key.Spec)
+	if err != nil {
+		return Key{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !key.key.Enabled {
+		return Key{}, c.ErrKeyDisabled
+	}
+
+	key.versions = append(key.versions, signer)
+	key.key.Version = len(key.versions)
+	key.key.RotatedAt = l.now()
+
+	return key.key, nil
+}
+
+// Disable disables a key, Sign, Decrypt and Rotate failing from then on
+func (l *LocalKeyManager) Disable(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key, ok := l.keys[id]
+	if !ok {
+		return c.ErrKeyNotFound
+	}
+
+	key.key.Enabled = false
+
+	return nil
+}
+
+// usable gets an enabled key with the usage
+func (l *LocalKeyManager) usable(ctx context.Context, id string, usage KeyUsage) (localKey, error) {
+	if err := ctx.Err(); err != nil {
+		return localKey{}, err
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	key, ok := l.keys[id]
+	if !ok {
+		return localKey{}, c.ErrKeyNotFound
+	}
+
+	if !key.key.Enabled {
+		return localKey{}, c.ErrKeyDisabled
+	}
+
+	if key.key.Usage != usage {
+		return localKey{}, c.ErrInvalidKeyUsage
+	}
+
+	return localKey{key: key.key, versions: append([]crypto.Signer{}, key.versions...)}, nil
+}
+
+// generate generates the private key of a new key version
+func (l *LocalKeyManager) generate(spec KeySpec) (crypto.Signer, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+
+	switch spec {
+	case RSA2048:
+		signer, _, err = l.rsa.R2048()
+	case RSA4096:
+		signer, _, err = l.rsa.R4096()
+	case ECDSAP256:
+		signer, _, err = l.ecdsa.P256()
+	case ECDSAP384:
+		signer, _, err = l.ecdsa.P384()
+	case ECDSAP521:
+		signer, _, err = l.ecdsa.P521()
+	case Ed25519:
+		signer, _, err = l.ed25519.Ed25519()
+	default:
+		return nil, c.ErrUnsupportedKeySpec
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return signer, nil
+}
+
+// current gets the signer of the current version
+func (k *localKey) current() crypto.Signer {
+	return k.versions[len(k.versions)-1]
+}
@@ -0,0 +1,46 @@
+package kms_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	c "github.com/ELares/crypto/pkg"
+	"github.com/ELares/crypto/pkg/kms"
+	"github.com/ELares/crypto/pkg/kms/kmstest"
+	"github.com/ELares/crypto/pkg/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalKeyManager(t *testing.T) {
+	kmstest.TestKeyManager(t, func(t *testing.T) kms.KeyManager {
+		return kms.NewLocalKeyManager()
+	})
+}
+
+func TestLocalKeyManagerWithPolicy(t *testing.T) {
+	cnsa, _ := policy.NewPolicy(policy.CNSA2)
+	km := kms.NewLocalKeyManagerWithPolicy(cnsa)
+
+	kmstest.TestKeyManager(t, func(t *testing.T) kms.KeyManager { return km }, kms.ECDSAP384)
+
+	testcases := []struct {
+		name string
+
+		spec kms.KeySpec
+	}{
+		{name: "RSA-2048", spec: kms.RSA2048},
+		{name: "ECDSA-P256", spec: kms.ECDSAP256},
+		{name: "Ed25519", spec: kms.Ed25519},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := km.CreateKey(context.Background(), tc.spec, kms.UsageSign)
+			assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+		})
+	}
+
+	_, err := km.CreateKey(context.Background(), kms.KeySpec(42), kms.UsageSign)
+	assert.Equal(t, c.ErrUnsupportedKeySpec, err)
+}
@@ -0,0 +1,624 @@
+package mldsa
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+
+	c "github.com/ELares/crypto/pkg"
+	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/policy"
+	"github.com/cloudflare/circl/sign"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa87"
+)
+
+const (
+	// MLDSA44 ML-DSA-44 parameter set (FIPS 204), NIST security category 2
+	MLDSA44 ParameterSet = iota
+
+	// MLDSA65 ML-DSA-65 parameter set (FIPS 204), NIST security category 3
+	MLDSA65
+
+	// MLDSA87 ML-DSA-87 parameter set (FIPS 204), NIST security category 5
+	MLDSA87
+)
+
+const (
+	// SeedSize size of the seed private keys are expanded from
+	SeedSize = 32
+
+	// ContextMaxSize maximum size of the context string
+	ContextMaxSize = 255
+)
+
+var (
+	// parameterSets scheme, signing function, policy algorithm and OID of each parameter set, OIDs
+	// from the NIST computer security objects register
+	parameterSets = map[ParameterSet]struct {
+		scheme    sign.Scheme
+		signTo    func(privateKey sign.PrivateKey, message, context []byte, randomized bool, signature []byte) error
+		algorithm policy.Algorithm
+		oid       asn1.ObjectIdentifier
+	}{
+		MLDSA44: {
+			scheme: mldsa44.Scheme(),
+			signTo: func(privateKey sign.PrivateKey, message, context []byte, randomized bool, signature []byte) error {
+				return mldsa44.SignTo(privateKey.(*mldsa44.PrivateKey), message, context, randomized, signature)
+			},
+			algorithm: policy.AlgorithmMLDSA44,
+			oid:       asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 17},
+		},
+		MLDSA65: {
+			scheme: mldsa65.Scheme(),
+			signTo: func(privateKey sign.PrivateKey, message, context []byte, randomized bool, signature []byte) error {
+				return mldsa65.SignTo(privateKey.(*mldsa65.PrivateKey), message, context, randomized, signature)
+			},
+			algorithm: policy.AlgorithmMLDSA65,
+			oid:       asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 18},
+		},
+		MLDSA87: {
+			scheme: mldsa87.Scheme(),
+			signTo: func(privateKey sign.PrivateKey, message, context []byte, randomized bool, signature []byte) error {
+				return mldsa87.SignTo(privateKey.(*mldsa87.PrivateKey), message, context, randomized, signature)
+			},
+			algorithm: policy.AlgorithmMLDSA87,
+			oid:       asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 19},
+		},
+	}
+)
+
+type (
+	// ParameterSet ML-DSA parameter set
+	ParameterSet int
+
+	// PrivateKey ML-DSA signing key, keeping the seed it was expanded from when known
+	PrivateKey struct {
+		parameterSet ParameterSet
+		seed         []byte
+		key          sign.PrivateKey
+		publicKey    *PublicKey
+	}
+
+	// PublicKey ML-DSA verification key
+	PublicKey struct {
+		parameterSet ParameterSet
+		key          sign.PublicKey
+	}
+
+	// IMLDSA interface for methods to generate ML-DSA keys, conversion to PEM, raw and JWK format
+	// and signatures
+	IMLDSA interface {
+		MLDSA44() (*PrivateKey, *PublicKey, error)
+		MLDSA65() (*PrivateKey, *PublicKey, error)
+		MLDSA87() (*PrivateKey, *PublicKey, error)
+
+		MLDSA44PEM() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error)
+		MLDSA65PEM() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error)
+		MLDSA87PEM() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error)
+
+		FromPEMPrivateKey(p.PrivatePEM) (*PrivateKey, error)
+		FromPEMPublicKey(p.PublicPEM) (*PublicKey, error)
+		FromPEM(p.PrivatePEM, p.PublicPEM) (*PrivateKey, *PublicKey, error)
+
+		ToPEMPrivateKey(*PrivateKey) (p.PrivatePEM, error)
+		ToPEMPublicKey(*PublicKey) (p.PublicPEM, error)
+		ToPEM(*PrivateKey, *PublicKey) (p.PrivatePEM, p.PublicPEM, error)
+
+		FromRawPrivateKey(ParameterSet, []byte) (*PrivateKey, error)
+		FromRawPublicKey(ParameterSet, []byte) (*PublicKey, error)
+		ToRawPrivateKey(*PrivateKey) ([]byte, error)
+		ToRawPublicKey(*PublicKey) ([]byte, error)
+
+		ToJWK(publicKey *PublicKey, id string) ([]byte, error)
+
+		Sign(privateKey *PrivateKey, message []byte, context string) ([]byte, error)
+		Verify(publicKey *PublicKey, message, signature []byte, context string) bool
+	}
+
+	// MLDSA struct to implement the IMLDSA methods
+	MLDSA struct {
+		policy *policy.Policy
+	}
+
+	// pkcs8 PKCS#8 OneAsymmetricKey structure (RFC 5958)
+	pkcs8 struct {
+		Version    int
+		Algo       pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}
+
+	// publicKeyInfo PKIX SubjectPublicKeyInfo structure (RFC 5280)
+	publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+
+	// bothPrivateKey private key holding both the seed and the expanded key
+	bothPrivateKey struct {
+		Seed        []byte
+		ExpandedKey []byte
+	}
+
+	// jwk algorithm key pair JWK (draft-ietf-cose-dilithium)
+	jwk struct {
+		Use       string `json:"use,omitempty"`
+		KeyType   string `json:"kty"`
+		KeyID     string `json:"kid,omitempty"`
+		Algorithm string `json:"alg"`
+		Public    string `json:"pub"`
+	}
+)
+
+// NewMLDSA gets a new MLDSA pointer
+func NewMLDSA() IMLDSA {
+	return &MLDSA{}
+}
+
+// NewMLDSAWithPolicy gets a new MLDSA pointer enforcing the policy on generated, parsed and exported
+// keys
+func NewMLDSAWithPolicy(policy *policy.Policy) IMLDSA {
+	return &MLDSA{policy: policy}
+}
+
+// String gets the parameter set name
+func (ps ParameterSet) String() string {
+	if params, ok := parameterSets[ps]; ok {
+		return params.scheme.Name()
+	}
+
+	return "unknown"
+}
+
+// ParameterSet gets the parameter set of the private key
+func (k *PrivateKey) ParameterSet() ParameterSet {
+	return k.parameterSet
+}
+
+// Public gets the public half of the private key
+func (k *PrivateKey) Public() *PublicKey {
+	return k.publicKey
+}
+
+// Seed gets the seed of the private key, nil when it was loaded from an expanded key
+func (k *PrivateKey) Seed() []byte {
+	return append([]byte(nil), k.seed...)
+}
+
+// Equal reports whether both private keys are the same
+func (k *PrivateKey) Equal(other *PrivateKey) bool {
+	return other != nil && k.parameterSet == other.parameterSet && k.key.Equal(other.key)
+}
+
+// ParameterSet gets the parameter set of the public key
+func (k *PublicKey) ParameterSet() ParameterSet {
+	return k.parameterSet
+}
+
+// Equal reports whether both public keys are the same
+func (k *PublicKey) Equal(other *PublicKey) bool {
+	return other != nil && k.parameterSet == other.parameterSet && k.key.Equal(other.key)
+}
+
+// MLDSA44 generates new ML-DSA-44 private/public keys
+func (m *MLDSA) MLDSA44() (*PrivateKey, *PublicKey, error) {
+	return m.generateKeys(MLDSA44)
+}
+
+// MLDSA65 generates new ML-DSA-65 private/public keys
+func (m *MLDSA) MLDSA65() (*PrivateKey, *PublicKey, error) {
+	return m.generateKeys(MLDSA65)
+}
+
+// MLDSA87 generates new ML-DSA-87 private/public keys
+func (m *MLDSA) MLDSA87() (*PrivateKey, *PublicKey, error) {
+	return m.generateKeys(MLDSA87)
+}
+
+// MLDSA44PEM generates new ML-DSA-44 private/public pem keys
+func (m *MLDSA) MLDSA44PEM() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error) {
+	return m.generatePEMKeys(MLDSA44)
+}
+
+// MLDSA65PEM generates new ML-DSA-65 private/public pem keys
+func (m *MLDSA) MLDSA65PEM() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error) {
+	return m.generatePEMKeys(MLDSA65)
+}
+
+// MLDSA87PEM generates new ML-DSA-87 private/public pem keys
+func (m *MLDSA) MLDSA87PEM() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error) {
+	return m.generatePEMKeys(MLDSA87)
+}
+
+// FromPEMPrivateKey takes a PKCS#8 private pem key, in the seed, expanded key or both form, and
+// converts it into a ML-DSA private key
+func (m *MLDSA) FromPEMPrivateKey(privatePEM p.PrivatePEM) (*PrivateKey, error) {
+	block, _ := pem.Decode(privatePEM)
+
+	if block == nil || block.Type != c.PRIVATEKEY {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	if err := m.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	var info pkcs8
+	if rest, err := asn1.Unmarshal(block.Bytes, &info); err != nil || len(rest) != 0 {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	parameterSet, ok := parameterSetFromOID(info.Algo.Algorithm)
+	if !ok {
+		return nil, c.ErrUnsupportedPrivateKey
+	}
+
+	var raw asn1.RawValue
+	if rest, err := asn1.Unmarshal(info.PrivateKey, &raw); err != nil || len(rest) != 0 {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	switch {
+	case raw.Class == asn1.ClassContextSpecific && raw.Tag == 0 && !raw.IsCompound:
+		return m.FromRawPrivateKey(parameterSet, raw.Bytes)
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagOctetString:
+		return m.fromExpandedKey(parameterSet, raw.Bytes)
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagSequence:
+		var both bothPrivateKey
+		if rest, err := asn1.Unmarshal(raw.FullBytes, &both); err != nil || len(rest) != 0 {
+			return nil, c.ErrDecodePEMPrivateKey
+		}
+
+		privateKey, err := m.FromRawPrivateKey(parameterSet, both.Seed)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded, err := privateKey.key.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+
+		if subtle.ConstantTimeCompare(expanded, both.ExpandedKey) != 1 {
+			return nil, c.ErrKeyMismatch
+		}
+
+		return privateKey, nil
+	}
+
+	return nil, c.ErrDecodePEMPrivateKey
+}
+
+// FromPEMPublicKey takes a PKIX public pem key and converts it into a ML-DSA public key
+func (m *MLDSA) FromPEMPublicKey(publicPEM p.PublicPEM) (*PublicKey, error) {
+	block, _ := pem.Decode(publicPEM)
+
+	if block == nil || block.Type != c.PUBLICKEY {
+		return nil, c.ErrDecodePEMPublicKey
+	}
+
+	if err := m.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	var info publicKeyInfo
+	if rest, err := asn1.Unmarshal(block.Bytes, &info); err != nil || len(rest) != 0 {
+		return nil, c.ErrDecodePEMPublicKey
+	}
+
+	parameterSet, ok := parameterSetFromOID(info.Algorithm.Algorithm)
+	if !ok {
+		return nil, c.ErrUnsupportedPublicKey
+	}
+
+	return m.FromRawPublicKey(parameterSet, info.PublicKey.RightAlign())
+}
+
+// FromPEM takes pem keys and converts them into ML-DSA keys, failing when the public key is not the
+// public half of the private key
+func (m *MLDSA) FromPEM(privatePEM p.PrivatePEM, publicPEM p.PublicPEM) (*PrivateKey, *PublicKey, error) {
+	privateKey, err := m.FromPEMPrivateKey(privatePEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicKey, err := m.FromPEMPublicKey(publicPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !publicKey.Equal(privateKey.Public()) {
+		return nil, nil, c.ErrKeyMismatch
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// ToPEMPrivateKey converts a ML-DSA private key into a PKCS#8 private PEM key, in the seed form when
+// the seed is known and in the expanded key form otherwise
+func (m *MLDSA) ToPEMPrivateKey(privateKey *PrivateKey) (p.PrivatePEM, error) {
+	if privateKey == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if err := m.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	var (
+		inner []byte
+		err   error
+	)
+
+	if privateKey.seed != nil {
+		inner, err = asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: privateKey.seed})
+	} else {
+		var expanded []byte
+		if expanded, err = privateKey.key.MarshalBinary(); err == nil {
+			inner, err = asn1.Marshal(expanded)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := asn1.Marshal(pkcs8{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: parameterSets[privateKey.parameterSet].oid},
+		PrivateKey: inner,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: encoded}), nil
+}
+
+// ToPEMPublicKey converts a ML-DSA public key into a PKIX public PEM key
+func (m *MLDSA) ToPEMPublicKey(publicKey *PublicKey) (p.PublicPEM, error) {
+	raw, err := m.ToRawPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	encoded, err := asn1.Marshal(publicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: parameterSets[publicKey.parameterSet].oid},
+		PublicKey: asn1.BitString{Bytes: raw, BitLength: 8 * len(raw)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: c.PUBLICKEY, Bytes: encoded}), nil
+}
+
+// ToPEM converts ML-DSA private & public keys, into private & public PEM keys
+func (m *MLDSA) ToPEM(privateKey *PrivateKey, publicKey *PublicKey) (p.PrivatePEM, p.PublicPEM, error) {
+	prvPEM, err := m.ToPEMPrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pubPEM, err := m.ToPEMPublicKey(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return prvPEM, pubPEM, nil
+}
+
+// FromRawPrivateKey takes a 32 bytes seed and expands it into a ML-DSA private key
+func (m *MLDSA) FromRawPrivateKey(parameterSet ParameterSet, seed []byte) (*PrivateKey, error) {
+	params, ok := parameterSets[parameterSet]
+	if !ok {
+		return nil, c.ErrUnsupportedParameterSet
+	}
+
+	if len(seed) != SeedSize {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	if err := m.policy.CheckAlgorithm(params.algorithm); err != nil {
+		return nil, err
+	}
+
+	publicKey, privateKey := params.scheme.DeriveKey(seed)
+
+	return &PrivateKey{
+		parameterSet: parameterSet,
+		seed:         append([]byte(nil), seed...),
+		key:          privateKey,
+		publicKey:    &PublicKey{parameterSet: parameterSet, key: publicKey},
+	}, nil
+}
+
+// FromRawPublicKey takes a raw verification key and converts it into a ML-DSA public key
+func (m *MLDSA) FromRawPublicKey(parameterSet ParameterSet, data []byte) (*PublicKey, error) {
+	params, ok := parameterSets[parameterSet]
+	if !ok {
+		return nil, c.ErrUnsupportedParameterSet
+	}
+
+	if len(data) != params.scheme.PublicKeySize() {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	if err := m.policy.CheckAlgorithm(params.algorithm); err != nil {
+		return nil, err
+	}
+
+	publicKey, err := params.scheme.UnmarshalBinaryPublicKey(data)
+	if err != nil {
+		return nil, c.ErrInvalidPublicKey
+	}
+
+	return &PublicKey{parameterSet: parameterSet, key: publicKey}, nil
+}
+
+// ToRawPrivateKey converts a ML-DSA private key into its 32 bytes seed
+func (m *MLDSA) ToRawPrivateKey(privateKey *PrivateKey) ([]byte, error) {
+	if privateKey == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if privateKey.seed == nil {
+		return nil, c.ErrUnsupportedPrivateKey
+	}
+
+	return privateKey.Seed(), nil
+}
+
+// ToRawPublicKey converts a ML-DSA public key into its raw verification key
+func (m *MLDSA) ToRawPublicKey(publicKey *PublicKey) ([]byte, error) {
+	if publicKey == nil || publicKey.key == nil {
+		return nil, c.ErrNilPublicKey
+	}
+
+	return publicKey.key.MarshalBinary()
+}
+
+// ToJWK converts a ML-DSA public key into an AKP JWK (draft-ietf-cose-dilithium)
+func (m *MLDSA) ToJWK(publicKey *PublicKey, id string) ([]byte, error) {
+	raw, err := m.ToRawPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.policy.CheckEncoding(policy.EncodingJWK); err != nil {
+		return nil, err
+	}
+
+	if err := m.policy.CheckAlgorithm(parameterSets[publicKey.parameterSet].algorithm); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jwk{
+		Use:       c.SIG,
+		KeyType:   "AKP",
+		KeyID:     id,
+		Algorithm: publicKey.parameterSet.String(),
+		Public:    base64.RawURLEncoding.EncodeToString(raw),
+	})
+}
+
+// Sign signs the message with an optional context of at most 255 bytes, using the hedged variant
+// of FIPS 204 ML-DSA.Sign
+func (m *MLDSA) Sign(privateKey *PrivateKey, message []byte, context string) ([]byte, error) {
+	return m.sign(privateKey, message, context, true)
+}
+
+// Verify verifies the signature of the message with the context it was signed with
+func (m *MLDSA) Verify(publicKey *PublicKey, message, signature []byte, context string) bool {
+	if publicKey == nil || publicKey.key == nil || len(context) > ContextMaxSize {
+		return false
+	}
+
+	params := parameterSets[publicKey.parameterSet]
+	if len(signature) != params.scheme.SignatureSize() {
+		return false
+	}
+
+	return params.scheme.Verify(publicKey.key, message, signature, &sign.SignatureOpts{Context: context})
+}
+
+// sign signs the message with the context, randomized selecting the hedged variant over the
+// deterministic one
+func (m *MLDSA) sign(privateKey *PrivateKey, message []byte, context string, randomized bool) ([]byte, error) {
+	if privateKey == nil || privateKey.key == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if len(context) > ContextMaxSize {
+		return nil, c.ErrContextTooLong
+	}
+
+	params := parameterSets[privateKey.parameterSet]
+	if err := m.policy.CheckAlgorithm(params.algorithm); err != nil {
+		return nil, err
+	}
+
+	signature := make([]byte, params.scheme.SignatureSize())
+	if err := params.signTo(privateKey.key, message, []byte(context), randomized, signature); err != nil {
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+// fromExpandedKey converts a raw expanded signing key into a ML-DSA private key without seed
+func (m *MLDSA) fromExpandedKey(parameterSet ParameterSet, data []byte) (*PrivateKey, error) {
+	params := parameterSets[parameterSet]
+	if len(data) != params.scheme.PrivateKeySize() {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	if err := m.policy.CheckAlgorithm(params.algorithm); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := params.scheme.UnmarshalBinaryPrivateKey(data)
+	if err != nil {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	publicKey, ok := privateKey.Public().(sign.PublicKey)
+	if !ok {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	return &PrivateKey{
+		parameterSet: parameterSet,
+		key:          privateKey,
+		publicKey:    &PublicKey{parameterSet: parameterSet, key: publicKey},
+	}, nil
+}
+
+// generateKeys generates new private/public keys of the parameter set
+func (m *MLDSA) generateKeys(parameterSet ParameterSet) (*PrivateKey, *PublicKey, error) {
+	seed := make([]byte, SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err := m.FromRawPrivateKey(parameterSet, seed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return privateKey, privateKey.Public(), nil
+}
+
+// generatePEMKeys generates new private/public keys of the parameter set and their PEM keys
+func (m *MLDSA) generatePEMKeys(parameterSet ParameterSet) (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error) {
+	privateKey, publicKey, err := m.generateKeys(parameterSet)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	prvPEM, pubPEM, err := m.ToPEM(privateKey, publicKey)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return privateKey, prvPEM, publicKey, pubPEM, nil
+}
+
+// parameterSetFromOID gets the parameter set of the OID
+func parameterSetFromOID(oid asn1.ObjectIdentifier) (ParameterSet, bool) {
+	for parameterSet, params := range parameterSets {
+		if params.oid.Equal(oid) {
+			return parameterSet, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,331 @@
+package mldsa
+
+import (
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	c "github.com/ELares/crypto/pkg"
+	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+// acvpVectors NIST ACVP ML-DSA vectors (FIPS 204) of the external pure interface, one test case per
+// parameter set
+type acvpVectors struct {
+	KeyGen []struct {
+		ParameterSet string `json:"parameterSet"`
+		Seed         string `json:"seed"`
+		PK           string `json:"pk"`
+		SK           string `json:"sk"`
+	} `json:"keyGen"`
+	SigGen []struct {
+		ParameterSet string `json:"parameterSet"`
+		SK           string `json:"sk"`
+		Message      string `json:"message"`
+		Context      string `json:"context"`
+		Signature    string `json:"signature"`
+	} `json:"sigGen"`
+	SigVer []struct {
+		ParameterSet string `json:"parameterSet"`
+		PK           string `json:"pk"`
+		Message      string `json:"message"`
+		Context      string `json:"context"`
+		Signature    string `json:"signature"`
+		TestPassed   bool   `json:"testPassed"`
+	} `json:"sigVer"`
+}
+
+func loadVectors(t *testing.T) *acvpVectors {
+	data, err := os.ReadFile("testdata/acvp.json")
+	assert.Nil(t, err)
+
+	var vectors acvpVectors
+	assert.Nil(t, json.Unmarshal(data, &vectors))
+
+	return &vectors
+}
+
+func parameterSetFromName(t *testing.T, name string) ParameterSet {
+	for _, parameterSet := range []ParameterSet{MLDSA44, MLDSA65, MLDSA87} {
+		if parameterSet.String() == name {
+			return parameterSet
+		}
+	}
+
+	t.Fatalf("unknown parameter set %s", name)
+
+	return 0
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	assert.Nil(t, err)
+
+	return b
+}
+
+func TestKeyGen(t *testing.T) {
+	m := NewMLDSA()
+
+	for _, tc := range loadVectors(t).KeyGen {
+		t.Run(tc.ParameterSet, func(t *testing.T) {
+			prvKey, err := m.FromRawPrivateKey(parameterSetFromName(t, tc.ParameterSet), decodeHex(t, tc.Seed))
+			assert.Nil(t, err)
+
+			pk, err := m.ToRawPublicKey(prvKey.Public())
+			assert.Nil(t, err)
+			assert.Equal(t, tc.PK, hex.EncodeToString(pk))
+
+			sk, err := prvKey.key.MarshalBinary()
+			assert.Nil(t, err)
+			assert.Equal(t, tc.SK, hex.EncodeToString(sk))
+		})
+	}
+}
+
+func TestSigGen(t *testing.T) {
+	m := &MLDSA{}
+
+	for _, tc := range loadVectors(t).SigGen {
+		t.Run(tc.ParameterSet, func(t *testing.T) {
+			prvKey, err := m.fromExpandedKey(parameterSetFromName(t, tc.ParameterSet), decodeHex(t, tc.SK))
+			assert.Nil(t, err)
+
+			message, context := decodeHex(t, tc.Message), string(decodeHex(t, tc.Context))
+
+			signature, err := m.sign(prvKey, message, context, false)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.Signature, hex.EncodeToString(signature))
+
+			assert.True(t, m.Verify(prvKey.Public(), message, signature, context))
+			assert.False(t, m.Verify(prvKey.Public(), message, signature, context+"x"))
+		})
+	}
+}
+
+func TestSigVer(t *testing.T) {
+	m := NewMLDSA()
+
+	for _, tc := range loadVectors(t).SigVer {
+		t.Run(tc.ParameterSet, func(t *testing.T) {
+			pubKey, err := m.FromRawPublicKey(parameterSetFromName(t, tc.ParameterSet), decodeHex(t, tc.PK))
+			assert.Nil(t, err)
+
+			valid := m.Verify(pubKey, decodeHex(t, tc.Message), decodeHex(t, tc.Signature), string(decodeHex(t, tc.Context)))
+			assert.Equal(t, tc.TestPassed, valid)
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	m := NewMLDSA()
+
+	for _, generate := range []func() (*PrivateKey, *PublicKey, error){m.MLDSA44, m.MLDSA65, m.MLDSA87} {
+		prvKey, pubKey, err := generate()
+		assert.Nil(t, err)
+
+		t.Run(prvKey.ParameterSet().String(), func(t *testing.T) {
+			signature, err := m.Sign(prvKey, []byte("firmware"), "context")
+			assert.Nil(t, err)
+
+			assert.True(t, m.Verify(pubKey, []byte("firmware"), signature, "context"))
+			assert.False(t, m.Verify(pubKey, []byte("firmware"), signature, ""))
+			assert.False(t, m.Verify(pubKey, []byte("tampered"), signature, "context"))
+			assert.False(t, m.Verify(pubKey, []byte("firmware"), signature[1:], "context"))
+
+			// hedged signatures differ for the same message
+			other, err := m.Sign(prvKey, []byte("firmware"), "context")
+			assert.Nil(t, err)
+			assert.NotEqual(t, signature, other)
+		})
+	}
+
+	prvKey, pubKey, _ := m.MLDSA65()
+	context := strings.Repeat("x", ContextMaxSize+1)
+
+	_, err := m.Sign(prvKey, []byte("firmware"), context)
+	assert.Equal(t, c.ErrContextTooLong, err)
+	assert.False(t, m.Verify(pubKey, []byte("firmware"), nil, context))
+
+	_, err = m.Sign(nil, []byte("firmware"), "")
+	assert.Equal(t, c.ErrNilPrivateKey, err)
+	assert.False(t, m.Verify(nil, []byte("firmware"), nil, ""))
+}
+
+func TestPEM(t *testing.T) {
+	m := NewMLDSA()
+
+	for _, generate := range []func() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error){m.MLDSA44PEM, m.MLDSA65PEM, m.MLDSA87PEM} {
+		prvKey, prvPEM, pubKey, pubPEM, err := generate()
+		assert.Nil(t, err)
+
+		t.Run(prvKey.ParameterSet().String(), func(t *testing.T) {
+			parsedPrvKey, parsedPubKey, err := m.FromPEM(prvPEM, pubPEM)
+			assert.Nil(t, err)
+			assert.True(t, prvKey.Equal(parsedPrvKey))
+			assert.True(t, pubKey.Equal(parsedPubKey))
+			assert.Equal(t, prvKey.Seed(), parsedPrvKey.Seed())
+
+			// seed [0] IMPLICIT OCTET STRING (SIZE (32))
+			block, _ := pem.Decode(prvPEM)
+			var info pkcs8
+			_, err = asn1.Unmarshal(block.Bytes, &info)
+			assert.Nil(t, err)
+			assert.Equal(t, parameterSets[prvKey.ParameterSet()].oid, info.Algo.Algorithm)
+			assert.Equal(t, append([]byte{0x80, SeedSize}, prvKey.Seed()...), info.PrivateKey)
+
+			expanded, _ := prvKey.key.MarshalBinary()
+
+			// expandedKey OCTET STRING
+			info.PrivateKey, _ = asn1.Marshal(expanded)
+			encoded, _ := asn1.Marshal(info)
+			expandedPrvKey, err := m.FromPEMPrivateKey(pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: encoded}))
+			assert.Nil(t, err)
+			assert.True(t, prvKey.Equal(expandedPrvKey))
+			assert.True(t, pubKey.Equal(expandedPrvKey.Public()))
+			assert.Nil(t, expandedPrvKey.Seed())
+
+			_, err = m.ToRawPrivateKey(expandedPrvKey)
+			assert.Equal(t, c.ErrUnsupportedPrivateKey, err)
+
+			// both SEQUENCE { seed, expandedKey }
+			info.PrivateKey, _ = asn1.Marshal(bothPrivateKey{Seed: prvKey.Seed(), ExpandedKey: expanded})
+			encoded, _ = asn1.Marshal(info)
+			bothPrvKey, err := m.FromPEMPrivateKey(pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: encoded}))
+			assert.Nil(t, err)
+			assert.True(t, prvKey.Equal(bothPrvKey))
+
+			expanded[0] ^= 1
+			info.PrivateKey, _ = asn1.Marshal(bothPrivateKey{Seed: prvKey.Seed(), ExpandedKey: expanded})
+			encoded, _ = asn1.Marshal(info)
+			_, err = m.FromPEMPrivateKey(pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: encoded}))
+			assert.Equal(t, c.ErrKeyMismatch, err)
+		})
+	}
+
+	_, prvPEM, _, _, _ := m.MLDSA65PEM()
+	_, _, _, otherPubPEM, _ := m.MLDSA65PEM()
+
+	_, _, err := m.FromPEM(prvPEM, otherPubPEM)
+	assert.Equal(t, c.ErrKeyMismatch, err)
+
+	_, err = m.FromPEMPrivateKey(p.PrivatePEM(otherPubPEM))
+	assert.Equal(t, c.ErrDecodePEMPrivateKey, err)
+
+	_, err = m.FromPEMPublicKey(p.PublicPEM(prvPEM))
+	assert.Equal(t, c.ErrDecodePEMPublicKey, err)
+}
+
+func TestRaw(t *testing.T) {
+	m := NewMLDSA()
+
+	prvKey, pubKey, _ := m.MLDSA44()
+
+	seed, err := m.ToRawPrivateKey(prvKey)
+	assert.Nil(t, err)
+
+	fromSeed, err := m.FromRawPrivateKey(MLDSA44, seed)
+	assert.Nil(t, err)
+	assert.True(t, prvKey.Equal(fromSeed))
+
+	pk, err := m.ToRawPublicKey(pubKey)
+	assert.Nil(t, err)
+
+	testcases := []struct {
+		name string
+
+		decode func() error
+
+		expectError error
+	}{
+		{
+			name:        "Invalid Private Key: Long Seed",
+			decode:      func() error { _, err := m.FromRawPrivateKey(MLDSA44, make([]byte, 64)); return err },
+			expectError: c.ErrInvalidKeyLength,
+		},
+		{
+			name:        "Invalid Private Key: Unknown Parameter Set",
+			decode:      func() error { _, err := m.FromRawPrivateKey(ParameterSet(42), seed); return err },
+			expectError: c.ErrUnsupportedParameterSet,
+		},
+		{
+			name:        "Invalid Public Key: Wrong Parameter Set",
+			decode:      func() error { _, err := m.FromRawPublicKey(MLDSA87, pk); return err },
+			expectError: c.ErrInvalidKeyLength,
+		},
+		{
+			name:        "Invalid Public Key: Nil",
+			decode:      func() error { _, err := m.ToRawPublicKey(nil); return err },
+			expectError: c.ErrNilPublicKey,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectError, tc.decode())
+		})
+	}
+}
+
+func TestToJWK(t *testing.T) {
+	m := NewMLDSA()
+
+	_, pubKey, _ := m.MLDSA65()
+	pk, _ := m.ToRawPublicKey(pubKey)
+
+	data, err := m.ToJWK(pubKey, "firmware")
+	assert.Nil(t, err)
+
+	var key map[string]string
+	assert.Nil(t, json.Unmarshal(data, &key))
+	assert.Equal(t, map[string]string{
+		"use": c.SIG,
+		"kty": "AKP",
+		"kid": "firmware",
+		"alg": "ML-DSA-65",
+		"pub": base64.RawURLEncoding.EncodeToString(pk),
+	}, key)
+
+	_, err = m.ToJWK(nil, "firmware")
+	assert.Equal(t, c.ErrNilPublicKey, err)
+
+	pemOnly := &policy.Policy{Name: "pem only", Algorithms: []policy.Algorithm{policy.AlgorithmMLDSA65}, Encodings: []policy.Encoding{policy.EncodingPEM}}
+	_, err = NewMLDSAWithPolicy(pemOnly).ToJWK(pubKey, "firmware")
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+}
+
+func TestWithPolicy(t *testing.T) {
+	_, prvPEM, _, pubPEM, _ := NewMLDSA().MLDSA65PEM()
+
+	modern, _ := policy.NewPolicy(policy.Modern)
+	_, _, err := NewMLDSAWithPolicy(modern).FromPEM(prvPEM, pubPEM)
+	assert.Nil(t, err)
+
+	cnsa, _ := policy.NewPolicy(policy.CNSA2)
+	m := NewMLDSAWithPolicy(cnsa)
+
+	_, _, err = m.MLDSA87()
+	assert.Nil(t, err)
+
+	prvKey, pubKey, err := m.MLDSA65()
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+	assert.Nil(t, prvKey)
+	assert.Nil(t, pubKey)
+
+	_, err = m.FromPEMPrivateKey(prvPEM)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, err = m.FromPEMPublicKey(pubPEM)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, _, err = NewMLDSAWithPolicy(modern).MLDSA44()
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+}
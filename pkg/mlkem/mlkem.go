@@ -0,0 +1,561 @@
+package mlkem
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+
+	c "github.com/ELares/crypto/pkg"
+	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/policy"
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem1024"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem512"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+)
+
+const (
+	// MLKEM512 ML-KEM-512 parameter set (FIPS 203), NIST security category 1
+	MLKEM512 ParameterSet = iota
+
+	// MLKEM768 ML-KEM-768 parameter set (FIPS 203), NIST security category 3
+	MLKEM768
+
+	// MLKEM1024 ML-KEM-1024 parameter set (FIPS 203), NIST security category 5
+	MLKEM1024
+)
+
+const (
+	// SeedSize size of the d || z seed private keys are expanded from
+	SeedSize = 64
+
+	// SharedKeySize size of the shared key of every parameter set
+	SharedKeySize = 32
+)
+
+var (
+	// parameterSets scheme, policy algorithm and OID of each parameter set, OIDs from
+	// draft-ietf-lamps-kyber-certificates
+	parameterSets = map[ParameterSet]struct {
+		scheme    kem.Scheme
+		algorithm policy.Algorithm
+		oid       asn1.ObjectIdentifier
+	}{
+		MLKEM512:  {mlkem512.Scheme(), policy.AlgorithmMLKEM512, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 1}},
+		MLKEM768:  {mlkem768.Scheme(), policy.AlgorithmMLKEM768, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 2}},
+		MLKEM1024: {mlkem1024.Scheme(), policy.AlgorithmMLKEM1024, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 3}},
+	}
+)
+
+type (
+	// ParameterSet ML-KEM parameter set
+	ParameterSet int
+
+	// PrivateKey ML-KEM decapsulation key, keeping the seed it was expanded from when known
+	PrivateKey struct {
+		parameterSet ParameterSet
+		seed         []byte
+		key          kem.PrivateKey
+		publicKey    *PublicKey
+	}
+
+	// PublicKey ML-KEM encapsulation key
+	PublicKey struct {
+		parameterSet ParameterSet
+		key          kem.PublicKey
+	}
+
+	// IMLKEM interface for methods to generate ML-KEM keys, conversion to PEM and raw format and
+	// key encapsulation
+	IMLKEM interface {
+		MLKEM512() (*PrivateKey, *PublicKey, error)
+		MLKEM768() (*PrivateKey, *PublicKey, error)
+		MLKEM1024() (*PrivateKey, *PublicKey, error)
+
+		MLKEM512PEM() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error)
+		MLKEM768PEM() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error)
+		MLKEM1024PEM() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error)
+
+		FromPEMPrivateKey(p.PrivatePEM) (*PrivateKey, error)
+		FromPEMPublicKey(p.PublicPEM) (*PublicKey, error)
+		FromPEM(p.PrivatePEM, p.PublicPEM) (*PrivateKey, *PublicKey, error)
+
+		ToPEMPrivateKey(*PrivateKey) (p.PrivatePEM, error)
+		ToPEMPublicKey(*PublicKey) (p.PublicPEM, error)
+		ToPEM(*PrivateKey, *PublicKey) (p.PrivatePEM, p.PublicPEM, error)
+
+		FromRawPrivateKey(ParameterSet, []byte) (*PrivateKey, error)
+		FromRawPublicKey(ParameterSet, []byte) (*PublicKey, error)
+		ToRawPrivateKey(*PrivateKey) ([]byte, error)
+		ToRawPublicKey(*PublicKey) ([]byte, error)
+
+		Encapsulate(publicKey *PublicKey) (ciphertext, sharedKey []byte, err error)
+		Decapsulate(privateKey *PrivateKey, ciphertext []byte) ([]byte, error)
+	}
+
+	// MLKEM struct to implement the IMLKEM methods
+	MLKEM struct {
+		policy *policy.Policy
+	}
+
+	// pkcs8 PKCS#8 OneAsymmetricKey structure (RFC 5958)
+	pkcs8 struct {
+		Version    int
+		Algo       pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}
+
+	// publicKeyInfo PKIX SubjectPublicKeyInfo structure (RFC 5280)
+	publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+
+	// bothPrivateKey private key holding both the seed and the expanded key
+	bothPrivateKey struct {
+		Seed        []byte
+		ExpandedKey []byte
+	}
+)
+
+// NewMLKEM gets a new MLKEM pointer
+func NewMLKEM() IMLKEM {
+	return &MLKEM{}
+}
+
+// NewMLKEMWithPolicy gets a new MLKEM pointer enforcing the policy on generated, parsed and exported
+// keys
+func NewMLKEMWithPolicy(policy *policy.Policy) IMLKEM {
+	return &MLKEM{policy: policy}
+}
+
+// String gets the parameter set name
+func (ps ParameterSet) String() string {
+	if params, ok := parameterSets[ps]; ok {
+		return params.scheme.Name()
+	}
+
+	return "unknown"
+}
+
+// ParameterSet gets the parameter set of the private key
+func (k *PrivateKey) ParameterSet() ParameterSet {
+	return k.parameterSet
+}
+
+// Public gets the public half of the private key
+func (k *PrivateKey) Public() *PublicKey {
+	return k.publicKey
+}
+
+// Seed gets the d || z seed of the private key, nil when it was loaded from an expanded key
+func (k *PrivateKey) Seed() []byte {
+	return append([]byte(nil), k.seed...)
+}
+
+// Equal reports whether both private keys are the same
+func (k *PrivateKey) Equal(other *PrivateKey) bool {
+	return other != nil && k.parameterSet == other.parameterSet && k.key.Equal(other.key)
+}
+
+// ParameterSet gets the parameter set of the public key
+func (k *PublicKey) ParameterSet() ParameterSet {
+	return k.parameterSet
+}
+
+// Equal reports whether both public keys are the same
+func (k *PublicKey) Equal(other *PublicKey) bool {
+	return other != nil && k.parameterSet == other.parameterSet && k.key.Equal(other.key)
+}
+
+// MLKEM512 generates new ML-KEM-512 private/public keys
+func (m *MLKEM) MLKEM512() (*PrivateKey, *PublicKey, error) {
+	return m.generateKeys(MLKEM512)
+}
+
+// MLKEM768 generates new ML-KEM-768 private/public keys
+func (m *MLKEM) MLKEM768() (*PrivateKey, *PublicKey, error) {
+	return m.generateKeys(MLKEM768)
+}
+
+// MLKEM1024 generates new ML-KEM-1024 private/public keys
+func (m *MLKEM) MLKEM1024() (*PrivateKey, *PublicKey, error) {
+	return m.generateKeys(MLKEM1024)
+}
+
+// MLKEM512PEM generates new ML-KEM-512 private/public pem keys
+func (m *MLKEM) MLKEM512PEM() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error) {
+	return m.generatePEMKeys(MLKEM512)
+}
+
+// MLKEM768PEM generates new ML-KEM-768 private/public pem keys
+func (m *MLKEM) MLKEM768PEM() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error) {
+	return m.generatePEMKeys(MLKEM768)
+}
+
+// MLKEM1024PEM generates new ML-KEM-1024 private/public pem keys
+func (m *MLKEM) MLKEM1024PEM() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error) {
+	return m.generatePEMKeys(MLKEM1024)
+}
+
+// FromPEMPrivateKey takes a PKCS#8 private pem key, in the seed, expanded key or both form, and
+// converts it into a ML-KEM private key
+func (m *MLKEM) FromPEMPrivateKey(privatePEM p.PrivatePEM) (*PrivateKey, error) {
+	block, _ := pem.Decode(privatePEM)
+
+	if block == nil || block.Type != c.PRIVATEKEY {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	if err := m.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	var info pkcs8
+	if rest, err := asn1.Unmarshal(block.Bytes, &info); err != nil || len(rest) != 0 {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	parameterSet, ok := parameterSetFromOID(info.Algo.Algorithm)
+	if !ok {
+		return nil, c.ErrUnsupportedPrivateKey
+	}
+
+	var raw asn1.RawValue
+	if rest, err := asn1.Unmarshal(info.PrivateKey, &raw); err != nil || len(rest) != 0 {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	switch {
+	case raw.Class == asn1.ClassContextSpecific && raw.Tag == 0 && !raw.IsCompound:
+		return m.FromRawPrivateKey(parameterSet, raw.Bytes)
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagOctetString:
+		return m.fromExpandedKey(parameterSet, raw.Bytes)
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagSequence:
+		var both bothPrivateKey
+		if rest, err := asn1.Unmarshal(raw.FullBytes, &both); err != nil || len(rest) != 0 {
+			return nil, c.ErrDecodePEMPrivateKey
+		}
+
+		privateKey, err := m.FromRawPrivateKey(parameterSet, both.Seed)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded, err := privateKey.key.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+
+		if subtle.ConstantTimeCompare(expanded, both.ExpandedKey) != 1 {
+			return nil, c.ErrKeyMismatch
+		}
+
+		return privateKey, nil
+	}
+
+	return nil, c.ErrDecodePEMPrivateKey
+}
+
+// FromPEMPublicKey takes a PKIX public pem key and converts it into a ML-KEM public key
+func (m *MLKEM) FromPEMPublicKey(publicPEM p.PublicPEM) (*PublicKey, error) {
+	block, _ := pem.Decode(publicPEM)
+
+	if block == nil || block.Type != c.PUBLICKEY {
+		return nil, c.ErrDecodePEMPublicKey
+	}
+
+	if err := m.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	var info publicKeyInfo
+	if rest, err := asn1.Unmarshal(block.Bytes, &info); err != nil || len(rest) != 0 {
+		return nil, c.ErrDecodePEMPublicKey
+	}
+
+	parameterSet, ok := parameterSetFromOID(info.Algorithm.Algorithm)
+	if !ok {
+		return nil, c.ErrUnsupportedPublicKey
+	}
+
+	return m.FromRawPublicKey(parameterSet, info.PublicKey.RightAlign())
+}
+
+// FromPEM takes pem keys and converts them into ML-KEM keys, failing when the public key is not the
+// public half of the private key
+func (m *MLKEM) FromPEM(privatePEM p.PrivatePEM, publicPEM p.PublicPEM) (*PrivateKey, *PublicKey, error) {
+	privateKey, err := m.FromPEMPrivateKey(privatePEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicKey, err := m.FromPEMPublicKey(publicPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !publicKey.Equal(privateKey.Public()) {
+		return nil, nil, c.ErrKeyMismatch
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// ToPEMPrivateKey converts a ML-KEM private key into a PKCS#8 private PEM key, in the seed form when
+// the seed is known and in the expanded key form otherwise
+func (m *MLKEM) ToPEMPrivateKey(privateKey *PrivateKey) (p.PrivatePEM, error) {
+	if privateKey == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if err := m.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	var (
+		inner []byte
+		err   error
+	)
+
+	if privateKey.seed != nil {
+		inner, err = asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: privateKey.seed})
+	} else {
+		var expanded []byte
+		if expanded, err = privateKey.key.MarshalBinary(); err == nil {
+			inner, err = asn1.Marshal(expanded)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := asn1.Marshal(pkcs8{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: parameterSets[privateKey.parameterSet].oid},
+		PrivateKey: inner,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: encoded}), nil
+}
+
+// ToPEMPublicKey converts a ML-KEM public key into a PKIX public PEM key
+func (m *MLKEM) ToPEMPublicKey(publicKey *PublicKey) (p.PublicPEM, error) {
+	raw, err := m.ToRawPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	encoded, err := asn1.Marshal(publicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: parameterSets[publicKey.parameterSet].oid},
+		PublicKey: asn1.BitString{Bytes: raw, BitLength: 8 * len(raw)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: c.PUBLICKEY, Bytes: encoded}), nil
+}
+
+// ToPEM converts ML-KEM private & public keys, into private & public PEM keys
+func (m *MLKEM) ToPEM(privateKey *PrivateKey, publicKey *PublicKey) (p.PrivatePEM, p.PublicPEM, error) {
+	prvPEM, err := m.ToPEMPrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pubPEM, err := m.ToPEMPublicKey(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return prvPEM, pubPEM, nil
+}
+
+// FromRawPrivateKey takes a 64 bytes d || z seed and expands it into a ML-KEM private key
+func (m *MLKEM) FromRawPrivateKey(parameterSet ParameterSet, seed []byte) (*PrivateKey, error) {
+	params, ok := parameterSets[parameterSet]
+	if !ok {
+		return nil, c.ErrUnsupportedParameterSet
+	}
+
+	if len(seed) != SeedSize {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	if err := m.policy.CheckAlgorithm(params.algorithm); err != nil {
+		return nil, err
+	}
+
+	publicKey, privateKey := params.scheme.DeriveKeyPair(seed)
+
+	return &PrivateKey{
+		parameterSet: parameterSet,
+		seed:         append([]byte(nil), seed...),
+		key:          privateKey,
+		publicKey:    &PublicKey{parameterSet: parameterSet, key: publicKey},
+	}, nil
+}
+
+// FromRawPublicKey takes a raw encapsulation key and converts it into a ML-KEM public key, failing
+// when its coefficients are not reduced modulo q
+func (m *MLKEM) FromRawPublicKey(parameterSet ParameterSet, data []byte) (*PublicKey, error) {
+	params, ok := parameterSets[parameterSet]
+	if !ok {
+		return nil, c.ErrUnsupportedParameterSet
+	}
+
+	if len(data) != params.scheme.PublicKeySize() {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	if err := m.policy.CheckAlgorithm(params.algorithm); err != nil {
+		return nil, err
+	}
+
+	publicKey, err := params.scheme.UnmarshalBinaryPublicKey(data)
+	if err != nil {
+		return nil, c.ErrInvalidPublicKey
+	}
+
+	return &PublicKey{parameterSet: parameterSet, key: publicKey}, nil
+}
+
+// ToRawPrivateKey converts a ML-KEM private key into its 64 bytes d || z seed
+func (m *MLKEM) ToRawPrivateKey(privateKey *PrivateKey) ([]byte, error) {
+	if privateKey == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if privateKey.seed == nil {
+		return nil, c.ErrUnsupportedPrivateKey
+	}
+
+	return privateKey.Seed(), nil
+}
+
+// ToRawPublicKey converts a ML-KEM public key into its raw encapsulation key
+func (m *MLKEM) ToRawPublicKey(publicKey *PublicKey) ([]byte, error) {
+	if publicKey == nil || publicKey.key == nil {
+		return nil, c.ErrNilPublicKey
+	}
+
+	return publicKey.key.MarshalBinary()
+}
+
+// Encapsulate generates a shared key and its ciphertext for the public key
+func (m *MLKEM) Encapsulate(publicKey *PublicKey) ([]byte, []byte, error) {
+	message := make([]byte, 32)
+	if _, err := rand.Read(message); err != nil {
+		return nil, nil, err
+	}
+
+	return m.encapsulate(publicKey, message)
+}
+
+// Decapsulate recovers the shared key of the ciphertext, a tampered ciphertext giving an unrelated
+// shared key (implicit rejection)
+func (m *MLKEM) Decapsulate(privateKey *PrivateKey, ciphertext []byte) ([]byte, error) {
+	if privateKey == nil || privateKey.key == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	params := parameterSets[privateKey.parameterSet]
+	if len(ciphertext) != params.scheme.CiphertextSize() {
+		return nil, c.ErrInvalidCiphertext
+	}
+
+	if err := m.policy.CheckAlgorithm(params.algorithm); err != nil {
+		return nil, err
+	}
+
+	return params.scheme.Decapsulate(privateKey.key, ciphertext)
+}
+
+// encapsulate generates the shared key and ciphertext of the 32 bytes message m (FIPS 203
+// ML-KEM.Encaps_internal)
+func (m *MLKEM) encapsulate(publicKey *PublicKey, message []byte) ([]byte, []byte, error) {
+	if publicKey == nil || publicKey.key == nil {
+		return nil, nil, c.ErrNilPublicKey
+	}
+
+	params := parameterSets[publicKey.parameterSet]
+	if err := m.policy.CheckAlgorithm(params.algorithm); err != nil {
+		return nil, nil, err
+	}
+
+	return params.scheme.EncapsulateDeterministically(publicKey.key, message)
+}
+
+// fromExpandedKey converts a raw expanded decapsulation key into a ML-KEM private key without seed
+func (m *MLKEM) fromExpandedKey(parameterSet ParameterSet, data []byte) (*PrivateKey, error) {
+	params := parameterSets[parameterSet]
+	if len(data) != params.scheme.PrivateKeySize() {
+		return nil, c.ErrInvalidKeyLength
+	}
+
+	if err := m.policy.CheckAlgorithm(params.algorithm); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := params.scheme.UnmarshalBinaryPrivateKey(data)
+	if err != nil {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	return &PrivateKey{
+		parameterSet: parameterSet,
+		key:          privateKey,
+		publicKey:    &PublicKey{parameterSet: parameterSet, key: privateKey.Public()},
+	}, nil
+}
+
+// generateKeys generates new private/public keys of the parameter set
+func (m *MLKEM) generateKeys(parameterSet ParameterSet) (*PrivateKey, *PublicKey, error) {
+	seed := make([]byte, SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err := m.FromRawPrivateKey(parameterSet, seed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return privateKey, privateKey.Public(), nil
+}
+
+// generatePEMKeys generates new private/public keys of the parameter set and their PEM keys
+func (m *MLKEM) generatePEMKeys(parameterSet ParameterSet) (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error) {
+	privateKey, publicKey, err := m.generateKeys(parameterSet)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	prvPEM, pubPEM, err := m.ToPEM(privateKey, publicKey)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return privateKey, prvPEM, publicKey, pubPEM, nil
+}
+
+// parameterSetFromOID gets the parameter set of the OID
+func parameterSetFromOID(oid asn1.ObjectIdentifier) (ParameterSet, bool) {
+	for parameterSet, params := range parameterSets {
+		if params.oid.Equal(oid) {
+			return parameterSet, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,301 @@
+package mlkem
+
+import (
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"testing"
+
+	c "github.com/ELares/crypto/pkg"
+	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+// acvpVectors NIST ACVP ML-KEM vectors (FIPS 203), one test case per parameter set
+type acvpVectors struct {
+	KeyGen []struct {
+		ParameterSet string `json:"parameterSet"`
+		D            string `json:"d"`
+		Z            string `json:"z"`
+		EK           string `json:"ek"`
+		DK           string `json:"dk"`
+	} `json:"keyGen"`
+	Encapsulation []struct {
+		ParameterSet string `json:"parameterSet"`
+		EK           string `json:"ek"`
+		M            string `json:"m"`
+		C            string `json:"c"`
+		K            string `json:"k"`
+	} `json:"encapsulation"`
+	Decapsulation []struct {
+		ParameterSet string `json:"parameterSet"`
+		DK           string `json:"dk"`
+		C            string `json:"c"`
+		K            string `json:"k"`
+	} `json:"decapsulation"`
+}
+
+func loadVectors(t *testing.T) *acvpVectors {
+	data, err := os.ReadFile("testdata/acvp.json")
+	assert.Nil(t, err)
+
+	var vectors acvpVectors
+	assert.Nil(t, json.Unmarshal(data, &vectors))
+
+	return &vectors
+}
+
+func parameterSetFromName(t *testing.T, name string) ParameterSet {
+	for _, parameterSet := range []ParameterSet{MLKEM512, MLKEM768, MLKEM1024} {
+		if parameterSet.String() == name {
+			return parameterSet
+		}
+	}
+
+	t.Fatalf("unknown parameter set %s", name)
+
+	return 0
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	assert.Nil(t, err)
+
+	return b
+}
+
+func TestKeyGen(t *testing.T) {
+	m := NewMLKEM()
+
+	for _, tc := range loadVectors(t).KeyGen {
+		t.Run(tc.ParameterSet, func(t *testing.T) {
+			seed := append(decodeHex(t, tc.D), decodeHex(t, tc.Z)...)
+
+			prvKey, err := m.FromRawPrivateKey(parameterSetFromName(t, tc.ParameterSet), seed)
+			assert.Nil(t, err)
+
+			ek, err := m.ToRawPublicKey(prvKey.Public())
+			assert.Nil(t, err)
+			assert.Equal(t, tc.EK, hex.EncodeToString(ek))
+
+			dk, err := prvKey.key.MarshalBinary()
+			assert.Nil(t, err)
+			assert.Equal(t, tc.DK, hex.EncodeToString(dk))
+
+			raw, err := m.ToRawPrivateKey(prvKey)
+			assert.Nil(t, err)
+			assert.Equal(t, seed, raw)
+		})
+	}
+}
+
+func TestEncapsulation(t *testing.T) {
+	m := &MLKEM{}
+
+	for _, tc := range loadVectors(t).Encapsulation {
+		t.Run(tc.ParameterSet, func(t *testing.T) {
+			pubKey, err := m.FromRawPublicKey(parameterSetFromName(t, tc.ParameterSet), decodeHex(t, tc.EK))
+			assert.Nil(t, err)
+
+			ciphertext, sharedKey, err := m.encapsulate(pubKey, decodeHex(t, tc.M))
+			assert.Nil(t, err)
+			assert.Equal(t, tc.C, hex.EncodeToString(ciphertext))
+			assert.Equal(t, tc.K, hex.EncodeToString(sharedKey))
+		})
+	}
+}
+
+func TestDecapsulation(t *testing.T) {
+	m := &MLKEM{}
+
+	for _, tc := range loadVectors(t).Decapsulation {
+		t.Run(tc.ParameterSet, func(t *testing.T) {
+			prvKey, err := m.fromExpandedKey(parameterSetFromName(t, tc.ParameterSet), decodeHex(t, tc.DK))
+			assert.Nil(t, err)
+
+			sharedKey, err := m.Decapsulate(prvKey, decodeHex(t, tc.C))
+			assert.Nil(t, err)
+			assert.Equal(t, tc.K, hex.EncodeToString(sharedKey))
+		})
+	}
+}
+
+func TestEncapsulate(t *testing.T) {
+	m := NewMLKEM()
+
+	for _, generate := range []func() (*PrivateKey, *PublicKey, error){m.MLKEM512, m.MLKEM768, m.MLKEM1024} {
+		prvKey, pubKey, err := generate()
+		assert.Nil(t, err)
+
+		t.Run(prvKey.ParameterSet().String(), func(t *testing.T) {
+			ciphertext, sharedKey, err := m.Encapsulate(pubKey)
+			assert.Nil(t, err)
+			assert.Len(t, sharedKey, SharedKeySize)
+
+			decapsulated, err := m.Decapsulate(prvKey, ciphertext)
+			assert.Nil(t, err)
+			assert.Equal(t, sharedKey, decapsulated)
+
+			// implicit rejection: a tampered ciphertext decapsulates to an unrelated key
+			ciphertext[0] ^= 1
+			decapsulated, err = m.Decapsulate(prvKey, ciphertext)
+			assert.Nil(t, err)
+			assert.NotEqual(t, sharedKey, decapsulated)
+
+			_, err = m.Decapsulate(prvKey, ciphertext[1:])
+			assert.Equal(t, c.ErrInvalidCiphertext, err)
+		})
+	}
+
+	_, _, err := m.Encapsulate(nil)
+	assert.Equal(t, c.ErrNilPublicKey, err)
+
+	_, err = m.Decapsulate(nil, nil)
+	assert.Equal(t, c.ErrNilPrivateKey, err)
+}
+
+func TestPEM(t *testing.T) {
+	m := NewMLKEM()
+
+	for _, generate := range []func() (*PrivateKey, p.PrivatePEM, *PublicKey, p.PublicPEM, error){m.MLKEM512PEM, m.MLKEM768PEM, m.MLKEM1024PEM} {
+		prvKey, prvPEM, pubKey, pubPEM, err := generate()
+		assert.Nil(t, err)
+
+		t.Run(prvKey.ParameterSet().String(), func(t *testing.T) {
+			parsedPrvKey, parsedPubKey, err := m.FromPEM(prvPEM, pubPEM)
+			assert.Nil(t, err)
+			assert.True(t, prvKey.Equal(parsedPrvKey))
+			assert.True(t, pubKey.Equal(parsedPubKey))
+			assert.Equal(t, prvKey.Seed(), parsedPrvKey.Seed())
+
+			// seed [0] IMPLICIT OCTET STRING (SIZE (64))
+			block, _ := pem.Decode(prvPEM)
+			var info pkcs8
+			_, err = asn1.Unmarshal(block.Bytes, &info)
+			assert.Nil(t, err)
+			assert.Equal(t, parameterSets[prvKey.ParameterSet()].oid, info.Algo.Algorithm)
+			assert.Equal(t, append([]byte{0x80, SeedSize}, prvKey.Seed()...), info.PrivateKey)
+
+			expanded, _ := prvKey.key.MarshalBinary()
+
+			// expandedKey OCTET STRING
+			info.PrivateKey, _ = asn1.Marshal(expanded)
+			encoded, _ := asn1.Marshal(info)
+			expandedPrvKey, err := m.FromPEMPrivateKey(pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: encoded}))
+			assert.Nil(t, err)
+			assert.True(t, prvKey.Equal(expandedPrvKey))
+			assert.Nil(t, expandedPrvKey.Seed())
+
+			_, err = m.ToRawPrivateKey(expandedPrvKey)
+			assert.Equal(t, c.ErrUnsupportedPrivateKey, err)
+
+			// both SEQUENCE { seed, expandedKey }
+			info.PrivateKey, _ = asn1.Marshal(bothPrivateKey{Seed: prvKey.Seed(), ExpandedKey: expanded})
+			encoded, _ = asn1.Marshal(info)
+			bothPrvKey, err := m.FromPEMPrivateKey(pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: encoded}))
+			assert.Nil(t, err)
+			assert.True(t, prvKey.Equal(bothPrvKey))
+
+			expanded[0] ^= 1
+			info.PrivateKey, _ = asn1.Marshal(bothPrivateKey{Seed: prvKey.Seed(), ExpandedKey: expanded})
+			encoded, _ = asn1.Marshal(info)
+			_, err = m.FromPEMPrivateKey(pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: encoded}))
+			assert.Equal(t, c.ErrKeyMismatch, err)
+		})
+	}
+
+	_, prvPEM, _, _, _ := m.MLKEM768PEM()
+	_, _, _, otherPubPEM, _ := m.MLKEM768PEM()
+
+	_, _, err := m.FromPEM(prvPEM, otherPubPEM)
+	assert.Equal(t, c.ErrKeyMismatch, err)
+
+	_, err = m.FromPEMPrivateKey(p.PrivatePEM(otherPubPEM))
+	assert.Equal(t, c.ErrDecodePEMPrivateKey, err)
+
+	_, err = m.FromPEMPublicKey(p.PublicPEM(prvPEM))
+	assert.Equal(t, c.ErrDecodePEMPublicKey, err)
+}
+
+func TestRaw(t *testing.T) {
+	m := NewMLKEM()
+
+	_, pubKey, _ := m.MLKEM768()
+	ek, _ := m.ToRawPublicKey(pubKey)
+
+	// a coefficient of 0xfff is not reduced modulo q = 3329
+	unreduced := append([]byte{}, ek...)
+	unreduced[0], unreduced[1] = 0xff, 0x0f
+
+	testcases := []struct {
+		name string
+
+		decode func() error
+
+		expectError error
+	}{
+		{
+			name:        "Invalid Private Key: Short Seed",
+			decode:      func() error { _, err := m.FromRawPrivateKey(MLKEM768, make([]byte, 32)); return err },
+			expectError: c.ErrInvalidKeyLength,
+		},
+		{
+			name:        "Invalid Private Key: Unknown Parameter Set",
+			decode:      func() error { _, err := m.FromRawPrivateKey(ParameterSet(42), make([]byte, SeedSize)); return err },
+			expectError: c.ErrUnsupportedParameterSet,
+		},
+		{
+			name:        "Invalid Public Key: Wrong Parameter Set",
+			decode:      func() error { _, err := m.FromRawPublicKey(MLKEM1024, ek); return err },
+			expectError: c.ErrInvalidKeyLength,
+		},
+		{
+			name:        "Invalid Public Key: Unreduced Coefficient",
+			decode:      func() error { _, err := m.FromRawPublicKey(MLKEM768, unreduced); return err },
+			expectError: c.ErrInvalidPublicKey,
+		},
+		{
+			name:        "Invalid Public Key: Nil",
+			decode:      func() error { _, err := m.ToRawPublicKey(nil); return err },
+			expectError: c.ErrNilPublicKey,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectError, tc.decode())
+		})
+	}
+}
+
+func TestWithPolicy(t *testing.T) {
+	_, prvPEM, _, pubPEM, _ := NewMLKEM().MLKEM768PEM()
+
+	modern, _ := policy.NewPolicy(policy.Modern)
+	_, _, err := NewMLKEMWithPolicy(modern).FromPEM(prvPEM, pubPEM)
+	assert.Nil(t, err)
+
+	cnsa, _ := policy.NewPolicy(policy.CNSA2)
+	m := NewMLKEMWithPolicy(cnsa)
+
+	_, _, err = m.MLKEM1024()
+	assert.Nil(t, err)
+
+	prvKey, pubKey, err := m.MLKEM768()
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+	assert.Nil(t, prvKey)
+	assert.Nil(t, pubKey)
+
+	_, err = m.FromPEMPrivateKey(prvPEM)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, err = m.FromPEMPublicKey(pubPEM)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, _, err = NewMLKEMWithPolicy(modern).MLKEM512()
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+}
@@ -0,0 +1,236 @@
+package mnemonic
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"sort"
+	"strings"
+
+	c "github.com/ELares/crypto/pkg"
+	ec "github.com/ELares/crypto/pkg/ecdsa"
+	ed "github.com/ELares/crypto/pkg/ed25519"
+	"github.com/ELares/crypto/pkg/policy"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MinEntropySize minimum size of the entropy, encoded as 12 words
+	MinEntropySize = 16
+
+	// MaxEntropySize maximum size of the entropy, encoded as 24 words
+	MaxEntropySize = 32
+
+	// SeedSize size of the BIP39 seed
+	SeedSize = 64
+
+	// SeedIterations PBKDF2 iteration count of the BIP39 seed
+	SeedIterations = 2048
+
+	// MaskIterations PBKDF2 iteration count of the passphrase mask of private keys
+	MaskIterations = 600000
+
+	// wordBits bits encoded by each word
+	wordBits = 11
+
+	// maskSalt salt prefix of the passphrase mask, followed by the key algorithm or curve name
+	maskSalt = "github.com/ELares/crypto/pkg/mnemonic "
+)
+
+type (
+	// IMnemonic interface for methods to encode entropy and private keys as BIP39 mnemonics, derive
+	// BIP39 seeds and restore the ed25519 and ecdsa keys of the library
+	IMnemonic interface {
+		FromEntropy(entropy []byte) (string, error)
+		ToEntropy(mnemonic string) ([]byte, error)
+		Seed(mnemonic, passphrase string) ([]byte, error)
+
+		FromEd25519(privateKey ed25519.PrivateKey, passphrase string) (string, error)
+		ToEd25519(mnemonic, passphrase string) (ed25519.PrivateKey, ed25519.PublicKey, error)
+
+		FromECDSA(privateKey *ecdsa.PrivateKey, passphrase string) (string, error)
+		ToECDSA(curve elliptic.Curve, mnemonic, passphrase string) (*ecdsa.PrivateKey, *ecdsa.PublicKey, error)
+	}
+
+	// Mnemonic struct to implement the IMnemonic methods
+	Mnemonic struct {
+		policy  *policy.Policy
+		ed25519 ed.IED25519
+		ecdsa   ec.IECDSA
+	}
+)
+
+// NewMnemonic gets a new Mnemonic pointer
+func NewMnemonic() IMnemonic {
+	return NewMnemonicWithPolicy(nil)
+}
+
+// NewMnemonicWithPolicy gets a new Mnemonic pointer enforcing the policy on the algorithm or curve
+// of the encoded and restored private keys
+func NewMnemonicWithPolicy(policy *policy.Policy) IMnemonic {
+	return &Mnemonic{
+		policy:  policy,
+		ed25519: ed.NewED25519WithPolicy(policy),
+		ecdsa:   ec.NewECDSAWithPolicy(policy),
+	}
+}
+
+// FromEntropy encodes 16 to 32 bytes of entropy, in steps of 4, as 12 to 24 words of the English
+// wordlist, the last word carrying the first bits of the SHA-256 of the entropy as checksum
+func (m *Mnemonic) FromEntropy(entropy []byte) (string, error) {
+	if len(entropy) < MinEntropySize || len(entropy) > MaxEntropySize || len(entropy)%4 != 0 {
+		return "", c.ErrInvalidEntropy
+	}
+
+	hash := sha256.Sum256(entropy)
+	data := append(append([]byte{}, entropy...), hash[0])
+
+	words := make([]string, len(entropy)*8*33/32/wordBits)
+	for i := range words {
+		index := 0
+		for bit := i * wordBits; bit < (i+1)*wordBits; bit++ {
+			index = index<<1 | int(data[bit/8]>>(7-bit%8)&1)
+		}
+
+		words[i] = english[index]
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+// ToEntropy decodes the entropy of a mnemonic, words being NFKD normalized, separated by any
+// whitespace and matched case-insensitively, and checks its checksum
+func (m *Mnemonic) ToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(normalize(mnemonic))
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, c.ErrInvalidMnemonic
+	}
+
+	checksumBits := len(words) / 3
+	data := make([]byte, (len(words)*wordBits+7)/8)
+	for i, word := range words {
+		index := sort.SearchStrings(english[:], word)
+		if index == len(english) || english[index] != word {
+			return nil, c.ErrInvalidMnemonic
+		}
+
+		for bit := 0; bit < wordBits; bit++ {
+			if index>>(wordBits-1-bit)&1 == 1 {
+				position := i*wordBits + bit
+				data[position/8] |= 1 << (7 - position%8)
+			}
+		}
+	}
+
+	entropy := data[:checksumBits*4]
+	hash := sha256.Sum256(entropy)
+	if hash[0]>>(8-checksumBits) != data[len(entropy)]>>(8-checksumBits) {
+		return nil, c.ErrMnemonicChecksum
+	}
+
+	return entropy, nil
+}
+
+// Seed derives the 64 bytes BIP39 seed of a valid mnemonic and an optional passphrase, e.g. the
+// seed of a hdkey master key, both being NFKD normalized as BIP39 requires
+func (m *Mnemonic) Seed(mnemonic, passphrase string) ([]byte, error) {
+	if _, err := m.ToEntropy(mnemonic); err != nil {
+		return nil, err
+	}
+
+	normalized := strings.Join(strings.Fields(normalize(mnemonic)), " ")
+
+	return pbkdf2.Key([]byte(normalized), []byte("mnemonic"+norm.NFKD.String(passphrase)), SeedIterations, SeedSize, sha512.New), nil
+}
+
+// FromEd25519 encodes the 32 bytes seed of a ed25519 private key as a 24 words mnemonic, the seed
+// being masked by the optional passphrase
+func (m *Mnemonic) FromEd25519(privateKey ed25519.PrivateKey, passphrase string) (string, error) {
+	if err := m.policy.CheckAlgorithm(policy.AlgorithmEd25519); err != nil {
+		return "", err
+	}
+
+	seed, err := m.ed25519.ToRawPrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return m.FromEntropy(mask(seed, string(policy.AlgorithmEd25519), passphrase))
+}
+
+// ToEd25519 restores the ed25519 key pair of a 24 words mnemonic and its passphrase; a wrong
+// passphrase restores another valid key pair, so its public key must be checked against the
+// expected one
+func (m *Mnemonic) ToEd25519(mnemonic, passphrase string) (ed25519.PrivateKey, ed25519.PublicKey, error) {
+	entropy, err := m.ToEntropy(mnemonic)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err := m.ed25519.FromRawPrivateKey(mask(entropy, string(policy.AlgorithmEd25519), passphrase))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return privateKey, privateKey.Public().(ed25519.PublicKey), nil
+}
+
+// FromECDSA encodes the scalar of a ecdsa private key as a mnemonic, the scalar being masked by the
+// optional passphrase; the curve order must be 128 to 256 bits long in steps of 32, e.g. P-256 as
+// 24 words or P-224 as 21 words
+func (m *Mnemonic) FromECDSA(privateKey *ecdsa.PrivateKey, passphrase string) (string, error) {
+	scalar, err := m.ecdsa.ToRawPrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	if err := m.policy.CheckCurve(privateKey.Curve); err != nil {
+		return "", err
+	}
+
+	return m.FromEntropy(mask(scalar, privateKey.Curve.Params().Name, passphrase))
+}
+
+// ToECDSA restores the ecdsa key pair on the curve of a mnemonic and its passphrase; a wrong
+// passphrase restores another key pair, so its public key must be checked against the expected one
+func (m *Mnemonic) ToECDSA(curve elliptic.Curve, mnemonic, passphrase string) (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
+	if curve == nil {
+		return nil, nil, c.ErrNilPublicKeyCurve
+	}
+
+	entropy, err := m.ToEntropy(mnemonic)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err := m.ecdsa.FromRawPrivateKey(curve, mask(entropy, curve.Params().Name, passphrase))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return privateKey, &privateKey.PublicKey, nil
+}
+
+// mask XORs the data with a PBKDF2-HMAC-SHA256 stream of the NFKD normalized passphrase salted by
+// the key name, leaving the data unchanged when there is no passphrase so that the mnemonic stays
+// plain BIP39 entropy
+func mask(data []byte, name, passphrase string) []byte {
+	if passphrase == "" {
+		return append([]byte{}, data...)
+	}
+
+	masked := pbkdf2.Key([]byte(norm.NFKD.String(passphrase)), []byte(maskSalt+name), MaskIterations, len(data), sha256.New)
+	subtle.XORBytes(masked, masked, data)
+
+	return masked
+}
+
+// normalize lower cases the NFKD form of the mnemonic, e.g. turning full-width letters and
+// ideographic spaces into their ASCII forms
+func normalize(mnemonic string) string {
+	return strings.ToLower(norm.NFKD.String(mnemonic))
+}
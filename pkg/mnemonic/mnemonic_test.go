@@ -0,0 +1,339 @@
+package mnemonic
+
+import (
+	"crypto/elliptic"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	c "github.com/ELares/crypto/pkg"
+	ec "github.com/ELares/crypto/pkg/ecdsa"
+	ed "github.com/ELares/crypto/pkg/ed25519"
+	"github.com/ELares/crypto/pkg/hdkey"
+	"github.com/ELares/crypto/pkg/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordlist(t *testing.T) {
+	assert.Equal(t, "abandon", english[0])
+	assert.Equal(t, "zoo", english[len(english)-1])
+	assert.True(t, sort.StringsAreSorted(english[:]))
+
+	prefixes := map[string]bool{}
+	for _, word := range english {
+		prefix := word[:min(4, len(word))]
+		assert.False(t, prefixes[prefix], word)
+		prefixes[prefix] = true
+	}
+}
+
+func TestVectors(t *testing.T) {
+	m := NewMnemonic()
+
+	// BIP39 reference vectors, the seed passphrase being TREZOR
+	testcases := []struct {
+		name string
+
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			name:     "12 Words Zero",
+			entropy:  "00000000000000000000000000000000",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			name:     "12 Words 7f",
+			entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+			seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			name:     "12 Words 80",
+			entropy:  "80808080808080808080808080808080",
+			mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+			seed:     "d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+		},
+		{
+			name:     "12 Words ff",
+			entropy:  "ffffffffffffffffffffffffffffffff",
+			mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+			seed:     "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+		},
+		{
+			name:     "18 Words Zero",
+			entropy:  "000000000000000000000000000000000000000000000000",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon agent",
+		},
+		{
+			name:     "24 Words 7f",
+			entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			mnemonic: "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth title",
+		},
+		{
+			name:     "24 Words ff",
+			entropy:  "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			entropy, _ := hex.DecodeString(tc.entropy)
+
+			mnemonic, err := m.FromEntropy(entropy)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.mnemonic, mnemonic)
+
+			decoded, err := m.ToEntropy(tc.mnemonic)
+			assert.Nil(t, err)
+			assert.Equal(t, entropy, decoded)
+
+			if tc.seed != "" {
+				seed, err := m.Seed(tc.mnemonic, "TREZOR")
+				assert.Nil(t, err)
+				assert.Equal(t, tc.seed, hex.EncodeToString(seed))
+			}
+		})
+	}
+}
+
+func TestToEntropy(t *testing.T) {
+	m := NewMnemonic()
+
+	about := strings.Repeat("abandon ", 11) + "about"
+
+	testcases := []struct {
+		name string
+
+		mnemonic string
+
+		expectError error
+	}{
+		{name: "Valid", mnemonic: about},
+		{name: "Valid Case And Whitespace", mnemonic: "  " + strings.ToUpper(strings.ReplaceAll(about, " ", "\n\t")) + " "},
+		{name: "Invalid Checksum", mnemonic: strings.Repeat("abandon ", 12), expectError: c.ErrMnemonicChecksum},
+		{name: "Invalid Unknown Word", mnemonic: strings.Repeat("abandon ", 11) + "abcd", expectError: c.ErrInvalidMnemonic},
+		{name: "Invalid Prefix", mnemonic: strings.Repeat("aban ", 11) + "abou", expectError: c.ErrInvalidMnemonic},
+		{name: "Invalid Word Count", mnemonic: strings.Repeat("abandon ", 10) + "about", expectError: c.ErrInvalidMnemonic},
+		{name: "Invalid Too Many Words", mnemonic: strings.Repeat("abandon ", 26) + "about", expectError: c.ErrInvalidMnemonic},
+		{name: "Invalid Empty", mnemonic: "", expectError: c.ErrInvalidMnemonic},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			entropy, err := m.ToEntropy(tc.mnemonic)
+			assert.Equal(t, tc.expectError, err)
+
+			if tc.expectError == nil {
+				assert.Equal(t, make([]byte, 16), entropy)
+			} else {
+				assert.Nil(t, entropy)
+			}
+		})
+	}
+
+	for _, size := range []int{0, 12, 17, 36} {
+		_, err := m.FromEntropy(make([]byte, size))
+		assert.Equal(t, c.ErrInvalidEntropy, err, size)
+	}
+
+	_, err := m.Seed(strings.Repeat("abandon ", 12), "")
+	assert.Equal(t, c.ErrMnemonicChecksum, err)
+
+	seed, err := m.Seed(" ABANDON "+strings.Repeat("abandon ", 10)+"about", "TREZOR")
+	assert.Nil(t, err)
+	expected, _ := m.Seed(about, "TREZOR")
+	assert.Equal(t, expected, seed)
+}
+
+func TestEd25519(t *testing.T) {
+	m := NewMnemonic()
+
+	privateKey, publicKey, err := ed.NewED25519().Ed25519()
+	require.Nil(t, err)
+
+	mnemonic, err := m.FromEd25519(privateKey, "")
+	assert.Nil(t, err)
+	assert.Len(t, strings.Fields(mnemonic), 24)
+
+	entropy, err := m.ToEntropy(mnemonic)
+	assert.Nil(t, err)
+	assert.Equal(t, privateKey.Seed(), entropy)
+
+	restored, restoredPub, err := m.ToEd25519(mnemonic, "")
+	assert.Nil(t, err)
+	assert.True(t, privateKey.Equal(restored))
+	assert.True(t, publicKey.Equal(restoredPub))
+
+	protected, err := m.FromEd25519(privateKey, "correct horse")
+	assert.Nil(t, err)
+	assert.NotEqual(t, mnemonic, protected)
+
+	restored, restoredPub, err = m.ToEd25519(protected, "correct horse")
+	assert.Nil(t, err)
+	assert.True(t, privateKey.Equal(restored))
+	assert.True(t, publicKey.Equal(restoredPub))
+
+	_, restoredPub, err = m.ToEd25519(protected, "wrong horse")
+	assert.Nil(t, err)
+	assert.False(t, publicKey.Equal(restoredPub))
+
+	_, _, err = m.ToEd25519(strings.Repeat("abandon ", 11)+"about", "")
+	assert.Equal(t, c.ErrInvalidKeyLength, err)
+
+	_, err = m.FromEd25519(privateKey[:10], "")
+	assert.NotNil(t, err)
+}
+
+func TestECDSA(t *testing.T) {
+	m := NewMnemonic()
+	ecdsa := ec.NewECDSA()
+
+	testcases := []struct {
+		name string
+
+		curve elliptic.Curve
+		words int
+
+		expectError error
+	}{
+		{name: "Valid P-256", curve: elliptic.P256(), words: 24},
+		{name: "Valid P-224", curve: elliptic.P224(), words: 21},
+		{name: "Valid K-256", curve: ec.Secp256k1(), words: 24},
+		{name: "Valid BP-256", curve: ec.BrainpoolP256r1(), words: 24},
+		{name: "Invalid P-384", curve: elliptic.P384(), expectError: c.ErrInvalidEntropy},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			privateKey, err := ecdsa.FromRawPrivateKey(tc.curve, testScalar(tc.curve))
+			require.Nil(t, err)
+
+			for _, passphrase := range []string{"", "correct horse"} {
+				mnemonic, err := m.FromECDSA(privateKey, passphrase)
+				assert.Equal(t, tc.expectError, err)
+
+				if tc.expectError != nil {
+					continue
+				}
+
+				assert.Len(t, strings.Fields(mnemonic), tc.words)
+
+				restored, publicKey, err := m.ToECDSA(tc.curve, mnemonic, passphrase)
+				assert.Nil(t, err)
+				assert.True(t, privateKey.Equal(restored))
+				assert.True(t, privateKey.PublicKey.Equal(publicKey))
+			}
+		})
+	}
+
+	privateKey, _ := ecdsa.P256PrivateKey()
+	mnemonic, err := m.FromECDSA(privateKey, "")
+	assert.Nil(t, err)
+
+	_, _, err = m.ToECDSA(elliptic.P224(), mnemonic, "")
+	assert.Equal(t, c.ErrInvalidKeyLength, err)
+
+	_, _, err = m.ToECDSA(nil, mnemonic, "")
+	assert.Equal(t, c.ErrNilPublicKeyCurve, err)
+
+	_, err = m.FromECDSA(nil, "")
+	assert.Equal(t, c.ErrNilPrivateKey, err)
+}
+
+func TestSeed(t *testing.T) {
+	m := NewMnemonic()
+
+	seed, err := m.Seed(strings.Repeat("abandon ", 11)+"about", "TREZOR")
+	require.Nil(t, err)
+	assert.Len(t, seed, SeedSize)
+
+	h := hdkey.NewHDKey()
+	master, err := h.NewMaster(hdkey.Ed25519, seed)
+	assert.Nil(t, err)
+
+	key, err := h.Derive(master, "m/44'/501'/0'/0'")
+	assert.Nil(t, err)
+	assert.True(t, key.IsPrivate())
+}
+
+func TestSeedNormalization(t *testing.T) {
+	m := NewMnemonic()
+	about := strings.Repeat("abandon ", 11) + "about"
+
+	// the passphrase of the Japanese BIP39 vectors, its NFKD form being spelled out, and the seed of
+	// PBKDF2-HMAC-SHA512 over that form
+	passphrase := "㍍ガバヴァぱばぐゞちぢ十人十色"
+	decomposed := "\u30e1\u30fc\u30c8\u30eb\u30ab\u3099\u30cf\u3099\u30a6\u3099\u30a1\u306f\u309a\u306f\u3099\u304f\u3099\u309d\u3099\u3061\u3061\u3099\u5341\u4eba\u5341\u8272"
+	expected := "ba553eedefe76e67e2602dc20184c564010859faada929a090dd2c57aacb204ceefd15404ab50ef3e8dbeae5195aeae64b0def4d2eead1cdc728a33ced520ffd"
+
+	testcases := []struct {
+		name string
+
+		mnemonic   string
+		passphrase string
+	}{
+		{name: "Composed Passphrase", mnemonic: about, passphrase: passphrase},
+		{name: "Decomposed Passphrase", mnemonic: about, passphrase: decomposed},
+		{name: "Full-width Mnemonic", mnemonic: strings.Repeat("ａｂａｎｄｏｎ\u3000", 11) + "ａｂｏｕｔ", passphrase: passphrase},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			seed, err := m.Seed(tc.mnemonic, tc.passphrase)
+			assert.Nil(t, err)
+			assert.Equal(t, expected, hex.EncodeToString(seed))
+		})
+	}
+
+	// "é" composed as U+00E9 or decomposed as "e" U+0301 masks the same key
+	privateKey, _, _ := ed.NewED25519().Ed25519()
+	mnemonic, err := m.FromEd25519(privateKey, "caf\u00e9")
+	assert.Nil(t, err)
+
+	restored, _, err := m.ToEd25519(mnemonic, "cafe\u0301")
+	assert.Nil(t, err)
+	assert.True(t, privateKey.Equal(restored))
+}
+
+func TestWithPolicy(t *testing.T) {
+	cnsa, _ := policy.NewPolicy(policy.CNSA2)
+	m := NewMnemonicWithPolicy(cnsa)
+
+	edPrivateKey, _, _ := ed.NewED25519().Ed25519()
+	mnemonic, _ := NewMnemonic().FromEd25519(edPrivateKey, "")
+
+	_, err := m.FromEd25519(edPrivateKey, "")
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, _, err = m.ToEd25519(mnemonic, "")
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	ecPrivateKey, _ := ec.NewECDSA().P256PrivateKey()
+	mnemonic, _ = NewMnemonic().FromECDSA(ecPrivateKey, "")
+
+	_, err = m.FromECDSA(ecPrivateKey, "")
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, _, err = m.ToECDSA(elliptic.P256(), mnemonic, "")
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, err = m.FromEntropy(make([]byte, 16))
+	assert.Nil(t, err)
+}
+
+// testScalar gets a valid scalar of the size of the curve order
+func testScalar(curve elliptic.Curve) []byte {
+	scalar := make([]byte, (curve.Params().N.BitLen()+7)/8)
+	for i := range scalar {
+		scalar[i] = byte(i + 1)
+	}
+
+	return scalar
+}
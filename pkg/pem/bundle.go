@@ -0,0 +1,189 @@
+package pem
+
+import (
+	"bytes"
+	"encoding/pem"
+	"sort"
+	"strings"
+
+	c "github.com/ELares/crypto/pkg"
+)
+
+const (
+	// KindUnknown block type not recognized
+	KindUnknown Kind = "unknown"
+
+	// KindPrivateKey unencrypted private key block
+	KindPrivateKey Kind = "private-key"
+
+	// KindEncryptedPrivateKey PKCS#8 encrypted or legacy Proc-Type encrypted private key block
+	KindEncryptedPrivateKey Kind = "encrypted-private-key"
+
+	// KindPublicKey public key block
+	KindPublicKey Kind = "public-key"
+
+	// KindCertificate certificate block
+	KindCertificate Kind = "certificate"
+
+	// KindCSR certificate signing request block
+	KindCSR Kind = "csr"
+
+	// KindCRL certificate revocation list block
+	KindCRL Kind = "crl"
+)
+
+var (
+	// kindOrder position of each kind when writing a bundle
+	kindOrder = map[Kind]int{
+		KindPrivateKey:          0,
+		KindEncryptedPrivateKey: 1,
+		KindPublicKey:           2,
+		KindCertificate:         3,
+		KindCSR:                 4,
+		KindCRL:                 5,
+		KindUnknown:             6,
+	}
+
+	// kindTypes classification of the well known block types
+	kindTypes = map[string]Kind{
+		c.PRIVATEKEY:              KindPrivateKey,
+		"RSA PRIVATE KEY":         KindPrivateKey,
+		"EC PRIVATE KEY":          KindPrivateKey,
+		c.ENCRYPTEDPRIVATEKEY:     KindEncryptedPrivateKey,
+		c.PUBLICKEY:               KindPublicKey,
+		"RSA PUBLIC KEY":          KindPublicKey,
+		c.CERTIFICATE:             KindCertificate,
+		"TRUSTED CERTIFICATE":     KindCertificate,
+		c.CERTIFICATEREQUEST:      KindCSR,
+		"NEW CERTIFICATE REQUEST": KindCSR,
+		c.X509CRL:                 KindCRL,
+	}
+)
+
+type (
+	// Kind classification of a PEM block
+	Kind string
+
+	// Block a PEM block of a bundle with its classification
+	Block struct {
+		Kind    Kind
+		Type    string
+		Headers map[string]string
+		Bytes   []byte
+	}
+
+	// IBundle interface for methods to read and write PEM files holding several blocks
+	IBundle interface {
+		FromPEM([]byte) ([]Block, error)
+		ToPEM([]Block) ([]byte, error)
+		Filter([]Block, Kind) []Block
+	}
+
+	// Bundle struct to implement the IBundle methods
+	Bundle struct{}
+)
+
+// NewBundle gets a new Bundle pointer
+func NewBundle() IBundle {
+	return &Bundle{}
+}
+
+// FromPEM takes a pem file and converts every block into a classified Block, headers included
+func (b *Bundle) FromPEM(data []byte) ([]Block, error) {
+	var blocks []Block
+
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		blocks = append(blocks, Block{
+			Kind:    classify(block),
+			Type:    block.Type,
+			Headers: block.Headers,
+			Bytes:   block.Bytes,
+		})
+	}
+
+	if len(blocks) == 0 {
+		return nil, c.ErrEmptyPEMBundle
+	}
+
+	return blocks, nil
+}
+
+// ToPEM converts blocks into a pem file, ordered private keys, encrypted private keys, public keys,
+// certificates, csrs, crls then blocks of other kinds, keeping the relative order of each kind so
+// certificate chains stay leaf first
+func (b *Bundle) ToPEM(blocks []Block) ([]byte, error) {
+	if len(blocks) == 0 {
+		return nil, c.ErrEmptyPEMBundle
+	}
+
+	ordered := append([]Block{}, blocks...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].order() < ordered[j].order()
+	})
+
+	var buf bytes.Buffer
+	for _, block := range ordered {
+		if err := pem.Encode(&buf, &pem.Block{Type: block.Type, Headers: block.Headers, Bytes: block.Bytes}); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Filter returns the blocks of the kind
+func (b *Bundle) Filter(blocks []Block, kind Kind) []Block {
+	var filtered []Block
+	for _, block := range blocks {
+		if block.kind() == kind {
+			filtered = append(filtered, block)
+		}
+	}
+
+	return filtered
+}
+
+// PEM converts the single block into a pem, ready for the FromPEM methods of the key packages
+func (b Block) PEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: b.Type, Headers: b.Headers, Bytes: b.Bytes})
+}
+
+// kind returns the block kind, classifying blocks built without one
+func (b Block) kind() Kind {
+	if b.Kind != "" {
+		return b.Kind
+	}
+
+	return classify(&pem.Block{Type: b.Type, Headers: b.Headers})
+}
+
+// order returns the position of the block kind when writing a bundle, kinds this package does not
+// define going last with the unknown blocks
+func (b Block) order() int {
+	if order, ok := kindOrder[b.kind()]; ok {
+		return order
+	}
+
+	return kindOrder[KindUnknown]
+}
+
+// classify finds the kind of a pem block
+func classify(block *pem.Block) Kind {
+	kind, ok := kindTypes[block.Type]
+	if !ok {
+		return KindUnknown
+	}
+
+	if kind == KindPrivateKey && strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") {
+		return KindEncryptedPrivateKey
+	}
+
+	return kind
+}
@@ -0,0 +1,169 @@
+package pem
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBlocks(t *testing.T) (privateKey, publicKey, certificate, csr, crl []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	privateDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(certDER)
+	assert.Nil(t, err)
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: template.Subject}, key)
+	assert.Nil(t, err)
+
+	crlDER, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{Number: big.NewInt(1), ThisUpdate: time.Now(), NextUpdate: time.Now().Add(time.Hour)}, cert, key)
+	assert.Nil(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crlDER})
+}
+
+func TestBundleFromPEM(t *testing.T) {
+	bundle := NewBundle()
+	privateKey, publicKey, certificate, csr, crl := newTestBlocks(t)
+
+	encrypted := pem.EncodeToMemory(&pem.Block{
+		Type:    "RSA PRIVATE KEY",
+		Headers: map[string]string{"Proc-Type": "4,ENCRYPTED", "DEK-Info": "AES-256-CBC,00112233445566778899AABBCCDDEEFF"},
+		Bytes:   []byte{1, 2, 3},
+	})
+	pkcs8Encrypted := pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte{4, 5, 6}})
+	unknown := pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: []byte{7, 8, 9}})
+
+	var data []byte
+	for _, part := range [][]byte{crl, certificate, []byte("comment between blocks\n"), privateKey, csr, encrypted, publicKey, certificate, unknown, pkcs8Encrypted} {
+		data = append(data, part...)
+	}
+
+	blocks, err := bundle.FromPEM(data)
+	assert.Nil(t, err)
+	assert.Len(t, blocks, 9)
+
+	kinds := []Kind{KindCRL, KindCertificate, KindPrivateKey, KindCSR, KindEncryptedPrivateKey, KindPublicKey, KindCertificate, KindUnknown, KindEncryptedPrivateKey}
+	for i, kind := range kinds {
+		assert.Equal(t, kind, blocks[i].Kind, blocks[i].Type)
+	}
+
+	assert.Equal(t, "4,ENCRYPTED", blocks[4].Headers["Proc-Type"])
+	assert.Len(t, bundle.Filter(blocks, KindCertificate), 2)
+	assert.Equal(t, privateKey, bundle.Filter(blocks, KindPrivateKey)[0].PEM())
+
+	out, err := bundle.ToPEM(blocks)
+	assert.Nil(t, err)
+
+	reordered, err := bundle.FromPEM(out)
+	assert.Nil(t, err)
+
+	ordered := []Kind{KindPrivateKey, KindEncryptedPrivateKey, KindEncryptedPrivateKey, KindPublicKey, KindCertificate, KindCertificate, KindCSR, KindCRL, KindUnknown}
+	for i, kind := range ordered {
+		assert.Equal(t, kind, reordered[i].Kind)
+	}
+
+	assert.Equal(t, "4,ENCRYPTED", reordered[1].Headers["Proc-Type"])
+	assert.Equal(t, "ENCRYPTED PRIVATE KEY", reordered[2].Type)
+
+	again, err := bundle.ToPEM(reordered)
+	assert.Nil(t, err)
+	assert.Equal(t, out, again)
+}
+
+func TestBundleInvalid(t *testing.T) {
+	bundle := NewBundle()
+
+	testcases := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "Invalid Blank/Empty",
+			data: "",
+		},
+		{
+			name: "Invalid no block",
+			data: "just some text",
+		},
+		{
+			name: "Invalid footer",
+			data: "-----BEGIN PUBLIC KEY-----\nAAAA\n-----END BLAH-----\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			blocks, err := bundle.FromPEM([]byte(tc.data))
+			assert.Nil(t, blocks)
+			assert.NotNil(t, err)
+		})
+	}
+
+	_, err := bundle.ToPEM(nil)
+	assert.NotNil(t, err)
+
+	out, err := bundle.ToPEM([]Block{{Type: "CERTIFICATE", Bytes: []byte{1}}, {Type: "PRIVATE KEY", Bytes: []byte{2}}})
+	assert.Nil(t, err)
+
+	blocks, err := bundle.FromPEM(out)
+	assert.Nil(t, err)
+	assert.Equal(t, KindPrivateKey, blocks[0].Kind)
+}
+
+func TestBundleUnknownKind(t *testing.T) {
+	bundle := NewBundle()
+
+	blocks := []Block{
+		{Kind: "custom", Type: "CUSTOM", Bytes: []byte{1}},
+		{Kind: KindUnknown, Type: "OTHER", Bytes: []byte{2}},
+		{Kind: KindCertificate, Type: "CERTIFICATE", Bytes: []byte{3}},
+		{Kind: KindCertificate, Type: "CERTIFICATE", Bytes: []byte{4}},
+		{Kind: KindPrivateKey, Type: "PRIVATE KEY", Bytes: []byte{5}},
+	}
+
+	out, err := bundle.ToPEM(blocks)
+	assert.Nil(t, err)
+
+	parsed, err := bundle.FromPEM(out)
+	assert.Nil(t, err)
+
+	var order []byte
+	for _, block := range parsed {
+		order = append(order, block.Bytes[0])
+	}
+
+	// the leaf certificate stays ahead of its chain and of the blocks of other kinds
+	assert.Equal(t, []byte{5, 3, 4, 1, 2}, order)
+}
@@ -6,4 +6,13 @@ type (
 
 	// PublicPEM byte array of PEM string
 	PublicPEM []byte
+
+	// CertificatePEM byte array of PEM string
+	CertificatePEM []byte
+
+	// CRLPEM byte array of PEM string
+	CRLPEM []byte
+
+	// SharePEM byte array of PEM string
+	SharePEM []byte
 )
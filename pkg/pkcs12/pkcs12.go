@@ -0,0 +1,226 @@
+package pkcs12
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+
+	c "github.com/ELares/crypto/pkg"
+	gopkcs12 "software.sslmate.com/src/go-pkcs12"
+)
+
+var (
+	oidDataContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+	oidPKCS8ShroudedKeyBag      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+
+	// legacyAlgorithms PKCS#12 PBE algorithms (RFC 7292 appendix C) with 3DES and RC2
+	legacyAlgorithms = []asn1.ObjectIdentifier{
+		{1, 2, 840, 113549, 1, 12, 1, 3},
+		{1, 2, 840, 113549, 1, 12, 1, 4},
+		{1, 2, 840, 113549, 1, 12, 1, 5},
+		{1, 2, 840, 113549, 1, 12, 1, 6},
+	}
+)
+
+type (
+	// IPKCS12 interface for methods to export and import PKCS#12 / PFX bundles
+	IPKCS12 interface {
+		ToPKCS12(privateKey crypto.PrivateKey, certificate *x509.Certificate, chain []*x509.Certificate, password string) ([]byte, error)
+
+		FromPKCS12(pfx []byte, password string) (crypto.PrivateKey, *x509.Certificate, []*x509.Certificate, error)
+		FromPKCS12Legacy(pfx []byte, password string) (crypto.PrivateKey, *x509.Certificate, []*x509.Certificate, error)
+	}
+
+	// PKCS12 struct to implement the IPKCS12 methods
+	PKCS12 struct{}
+
+	pfxPDU struct {
+		Version  int
+		AuthSafe contentInfo
+		MacData  asn1.RawValue `asn1:"optional"`
+	}
+
+	contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+	}
+
+	encryptedData struct {
+		Version              int
+		EncryptedContentInfo encryptedContentInfo
+	}
+
+	encryptedContentInfo struct {
+		ContentType                asn1.ObjectIdentifier
+		ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+		EncryptedContent           asn1.RawValue `asn1:"tag:0,optional"`
+	}
+
+	safeBag struct {
+		ID         asn1.ObjectIdentifier
+		Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+		Attributes []pkcs12Attribute `asn1:"set,optional"`
+	}
+
+	pkcs12Attribute struct {
+		ID    asn1.ObjectIdentifier
+		Value asn1.RawValue `asn1:"set"`
+	}
+
+	encryptedPrivateKeyInfo struct {
+		AlgorithmIdentifier pkix.AlgorithmIdentifier
+		EncryptedData       []byte
+	}
+)
+
+// NewPKCS12 gets a new PKCS12 pointer
+func NewPKCS12() IPKCS12 {
+	return &PKCS12{}
+}
+
+// ToPKCS12 converts a rsa or ecdsa private key, its certificate and the CA chain into a PKCS#12
+// bundle encrypted with PBES2 (PBKDF2-HMAC-SHA256, AES-256-CBC)
+func (p *PKCS12) ToPKCS12(privateKey crypto.PrivateKey, certificate *x509.Certificate, chain []*x509.Certificate, password string) ([]byte, error) {
+	if err := p.checkPrivateKey(privateKey); err != nil {
+		return nil, err
+	}
+
+	if certificate == nil {
+		return nil, c.ErrNilCertificate
+	}
+
+	return gopkcs12.Modern.Encode(privateKey, certificate, chain, password)
+}
+
+// FromPKCS12 takes a PBES2 encrypted PKCS#12 bundle and converts it into a rsa or ecdsa private
+// key, its certificate and the CA chain, bundles using legacy 3DES/RC2 encryption are rejected
+func (p *PKCS12) FromPKCS12(pfx []byte, password string) (crypto.PrivateKey, *x509.Certificate, []*x509.Certificate, error) {
+	legacy, err := p.isLegacy(pfx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if legacy {
+		return nil, nil, nil, c.ErrLegacyPKCS12
+	}
+
+	return p.decode(pfx, password)
+}
+
+// FromPKCS12Legacy same as FromPKCS12 but also accepts bundles using legacy 3DES/RC2 encryption,
+// as exported by older Windows and Java versions
+func (p *PKCS12) FromPKCS12Legacy(pfx []byte, password string) (crypto.PrivateKey, *x509.Certificate, []*x509.Certificate, error) {
+	return p.decode(pfx, password)
+}
+
+// decode decodes the bundle and checks the private key type
+func (p *PKCS12) decode(pfx []byte, password string) (crypto.PrivateKey, *x509.Certificate, []*x509.Certificate, error) {
+	privateKey, certificate, chain, err := gopkcs12.DecodeChain(pfx, password)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := p.checkPrivateKey(privateKey); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return privateKey, certificate, chain, nil
+}
+
+// checkPrivateKey checks the private key is a rsa or ecdsa key
+func (p *PKCS12) checkPrivateKey(privateKey crypto.PrivateKey) error {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if key == nil {
+			return c.ErrNilPrivateKey
+		}
+	case *ecdsa.PrivateKey:
+		if key == nil {
+			return c.ErrNilPrivateKey
+		}
+	case nil:
+		return c.ErrNilPrivateKey
+	default:
+		return c.ErrUnsupportedPrivateKey
+	}
+
+	return nil
+}
+
+// isLegacy reports whether the bundle encrypts its certificates or keys with a legacy algorithm
+func (p *PKCS12) isLegacy(pfx []byte) (bool, error) {
+	var pdu pfxPDU
+	if _, err := asn1.Unmarshal(pfx, &pdu); err != nil {
+		return false, err
+	}
+
+	if !pdu.AuthSafe.ContentType.Equal(oidDataContentType) {
+		return false, gopkcs12.NotImplementedError("only password-protected PFX is implemented")
+	}
+
+	var authSafeData []byte
+	if _, err := asn1.Unmarshal(pdu.AuthSafe.Content.Bytes, &authSafeData); err != nil {
+		return false, err
+	}
+
+	var authenticatedSafe []contentInfo
+	if _, err := asn1.Unmarshal(authSafeData, &authenticatedSafe); err != nil {
+		return false, err
+	}
+
+	for _, ci := range authenticatedSafe {
+		switch {
+		case ci.ContentType.Equal(oidEncryptedDataContentType):
+			var data encryptedData
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &data); err != nil {
+				return false, err
+			}
+
+			if isLegacyAlgorithm(data.EncryptedContentInfo.ContentEncryptionAlgorithm.Algorithm) {
+				return true, nil
+			}
+		case ci.ContentType.Equal(oidDataContentType):
+			var safeContentsData []byte
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &safeContentsData); err != nil {
+				return false, err
+			}
+
+			var bags []safeBag
+			if _, err := asn1.Unmarshal(safeContentsData, &bags); err != nil {
+				return false, err
+			}
+
+			for _, bag := range bags {
+				if !bag.ID.Equal(oidPKCS8ShroudedKeyBag) {
+					continue
+				}
+
+				var keyInfo encryptedPrivateKeyInfo
+				if _, err := asn1.Unmarshal(bag.Value.Bytes, &keyInfo); err != nil {
+					return false, err
+				}
+
+				if isLegacyAlgorithm(keyInfo.AlgorithmIdentifier.Algorithm) {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// isLegacyAlgorithm reports whether the algorithm is a legacy PKCS#12 PBE algorithm
+func isLegacyAlgorithm(algorithm asn1.ObjectIdentifier) bool {
+	for _, legacy := range legacyAlgorithms {
+		if algorithm.Equal(legacy) {
+			return true
+		}
+	}
+
+	return false
+}
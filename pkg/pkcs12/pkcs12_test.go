@@ -0,0 +1,133 @@
+package pkcs12
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	c "github.com/ELares/crypto/pkg"
+	"github.com/ELares/crypto/pkg/ecdsa"
+	"github.com/ELares/crypto/pkg/ed25519"
+	"github.com/ELares/crypto/pkg/rsa"
+	"github.com/stretchr/testify/assert"
+	gopkcs12 "software.sslmate.com/src/go-pkcs12"
+)
+
+func newTestCertificates(t *testing.T, publicKey crypto.PublicKey) (*x509.Certificate, *x509.Certificate) {
+	caKey, _, _ := ecdsa.NewECDSA().P384()
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	caDER, err := x509.CreateCertificate(nil, caTemplate, caTemplate, caKey.Public(), caKey)
+	assert.Nil(t, err)
+
+	ca, err := x509.ParseCertificate(caDER)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(nil, template, ca, publicKey, caKey)
+	assert.Nil(t, err)
+
+	leaf, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	return leaf, ca
+}
+
+func TestPKCS12(t *testing.T) {
+	pkcs12 := NewPKCS12()
+
+	rsaKey, rsaPub, _ := rsa.NewRSA().R2048()
+	ecKey, ecPub, _ := ecdsa.NewECDSA().P256()
+
+	testcases := []struct {
+		name string
+		key  crypto.PrivateKey
+		pub  crypto.PublicKey
+	}{
+		{
+			name: "Valid RSA",
+			key:  rsaKey,
+			pub:  rsaPub,
+		},
+		{
+			name: "Valid ECDSA",
+			key:  ecKey,
+			pub:  ecPub,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			leaf, ca := newTestCertificates(t, tc.pub)
+
+			pfx, err := pkcs12.ToPKCS12(tc.key, leaf, []*x509.Certificate{ca}, "secret")
+			assert.Nil(t, err)
+			assert.NotEmpty(t, pfx)
+
+			key, cert, chain, err := pkcs12.FromPKCS12(pfx, "secret")
+			assert.Nil(t, err)
+			assert.Equal(t, tc.key, key)
+			assert.Equal(t, leaf.Raw, cert.Raw)
+			assert.Len(t, chain, 1)
+			assert.Equal(t, ca.Raw, chain[0].Raw)
+
+			_, _, _, err = pkcs12.FromPKCS12(pfx, "wrong")
+			assert.NotNil(t, err)
+
+			for name, encoder := range map[string]*gopkcs12.Encoder{"3DES": gopkcs12.LegacyDES, "RC2": gopkcs12.LegacyRC2} {
+				legacy, err := encoder.Encode(tc.key, leaf, []*x509.Certificate{ca}, "secret")
+				assert.Nil(t, err, name)
+
+				_, _, _, err = pkcs12.FromPKCS12(legacy, "secret")
+				assert.Equal(t, c.ErrLegacyPKCS12, err, name)
+
+				key, cert, _, err = pkcs12.FromPKCS12Legacy(legacy, "secret")
+				assert.Nil(t, err, name)
+				assert.Equal(t, tc.key, key, name)
+				assert.Equal(t, leaf.Raw, cert.Raw, name)
+			}
+		})
+	}
+}
+
+func TestPKCS12Invalid(t *testing.T) {
+	pkcs12 := NewPKCS12()
+
+	edKey, edPub, _ := ed25519.NewED25519().Ed25519()
+	ecKey, ecPub, _ := ecdsa.NewECDSA().P256()
+	leaf, _ := newTestCertificates(t, edPub)
+	ecLeaf, _ := newTestCertificates(t, ecPub)
+
+	_, err := pkcs12.ToPKCS12(edKey, leaf, nil, "secret")
+	assert.Equal(t, c.ErrUnsupportedPrivateKey, err)
+
+	_, err = pkcs12.ToPKCS12(nil, leaf, nil, "secret")
+	assert.Equal(t, c.ErrNilPrivateKey, err)
+
+	_, err = pkcs12.ToPKCS12(ecKey, nil, nil, "secret")
+	assert.Equal(t, c.ErrNilCertificate, err)
+
+	_, err = pkcs12.ToPKCS12(ecKey, ecLeaf, nil, "secret")
+	assert.Nil(t, err)
+
+	_, _, _, err = pkcs12.FromPKCS12([]byte("not a pfx"), "secret")
+	assert.NotNil(t, err)
+}
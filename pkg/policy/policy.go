@@ -0,0 +1,307 @@
+package policy
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+
+	c "github.com/ELares/crypto/pkg"
+	"github.com/cloudflare/circl/sign/ed448"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	// FIPS1403 preset limited to the FIPS 140-3 approved algorithms (FIPS 186-5, SP 800-186)
+	FIPS1403 Preset = iota
+
+	// CNSA2 preset following the NSA CNSA suite: RSA-3072+, P-384 and SHA-384+ for classical
+	// algorithms, ML-KEM-1024 and ML-DSA-87
+	CNSA2
+
+	// Modern preset accepting RSA-3072+, P-256, P-384, Ed25519, ML-KEM-768+, ML-DSA-65+ and the
+	// ML-KEM-768 hybrids with SHA-256+
+	Modern
+)
+
+const (
+	// AlgorithmRSA rsa keys
+	AlgorithmRSA Algorithm = "RSA"
+
+	// AlgorithmECDSA ecdsa keys
+	AlgorithmECDSA Algorithm = "ECDSA"
+
+	// AlgorithmEd25519 ed25519 keys
+	AlgorithmEd25519 Algorithm = "Ed25519"
+
+	// AlgorithmEd448 ed448 keys
+	AlgorithmEd448 Algorithm = "Ed448"
+
+	// AlgorithmX25519 x25519 key agreement keys
+	AlgorithmX25519 Algorithm = "X25519"
+
+	// AlgorithmMLKEM512 ML-KEM-512 keys
+	AlgorithmMLKEM512 Algorithm = "ML-KEM-512"
+
+	// AlgorithmMLKEM768 ML-KEM-768 keys
+	AlgorithmMLKEM768 Algorithm = "ML-KEM-768"
+
+	// AlgorithmMLKEM1024 ML-KEM-1024 keys
+	AlgorithmMLKEM1024 Algorithm = "ML-KEM-1024"
+
+	// AlgorithmMLDSA44 ML-DSA-44 keys
+	AlgorithmMLDSA44 Algorithm = "ML-DSA-44"
+
+	// AlgorithmMLDSA65 ML-DSA-65 keys
+	AlgorithmMLDSA65 Algorithm = "ML-DSA-65"
+
+	// AlgorithmMLDSA87 ML-DSA-87 keys
+	AlgorithmMLDSA87 Algorithm = "ML-DSA-87"
+
+	// AlgorithmX25519MLKEM768 X25519 combined with ML-KEM-768 hybrid keys
+	AlgorithmX25519MLKEM768 Algorithm = "X25519MLKEM768"
+
+	// AlgorithmSecP256r1MLKEM768 P-256 combined with ML-KEM-768 hybrid keys
+	AlgorithmSecP256r1MLKEM768 Algorithm = "SecP256r1MLKEM768"
+
+	// EncodingPEM pem keys
+	EncodingPEM Encoding = "PEM"
+
+	// EncodingJWK json web keys
+	EncodingJWK Encoding = "JWK"
+)
+
+var (
+	// jwkHashes hash function of each jwk signature algorithm
+	jwkHashes = map[jose.SignatureAlgorithm]crypto.Hash{
+		jose.RS256: crypto.SHA256,
+		jose.RS384: crypto.SHA384,
+		jose.RS512: crypto.SHA512,
+		jose.PS256: crypto.SHA256,
+		jose.PS384: crypto.SHA384,
+		jose.PS512: crypto.SHA512,
+		jose.ES256: crypto.SHA256,
+		jose.ES384: crypto.SHA384,
+		jose.ES512: crypto.SHA512,
+
+		jose.SignatureAlgorithm("ES256K"): crypto.SHA256,
+		jose.SignatureAlgorithm("ESB256"): crypto.SHA256,
+		jose.SignatureAlgorithm("ESB384"): crypto.SHA384,
+		jose.SignatureAlgorithm("ESB512"): crypto.SHA512,
+	}
+)
+
+type (
+	// Preset named policy
+	Preset int
+
+	// Algorithm public key algorithm
+	Algorithm string
+
+	// Encoding key encoding
+	Encoding string
+
+	// Policy allowed algorithms, curves, key sizes, hash functions and encodings, a nil policy
+	// allows everything
+	Policy struct {
+		Name       string
+		Algorithms []Algorithm
+		Curves     []string
+		MinRSASize int
+		Hashes     []crypto.Hash
+		Encodings  []Encoding
+	}
+
+	// Violation error returned when a key or operation is not allowed by a policy, matching
+	// c.ErrPolicyViolation with errors.Is
+	Violation struct {
+		Policy string
+		Reason string
+	}
+)
+
+// NewPolicy gets a new Policy pointer of the preset
+func NewPolicy(preset Preset) (*Policy, error) {
+	switch preset {
+	case FIPS1403:
+		return &Policy{
+			Name:       "FIPS 140-3",
+			Algorithms: []Algorithm{AlgorithmRSA, AlgorithmECDSA, AlgorithmEd25519, AlgorithmEd448, AlgorithmMLKEM512, AlgorithmMLKEM768, AlgorithmMLKEM1024, AlgorithmMLDSA44, AlgorithmMLDSA65, AlgorithmMLDSA87, AlgorithmX25519MLKEM768, AlgorithmSecP256r1MLKEM768},
+			Curves:     []string{"P-224", "P-256", "P-384", "P-521"},
+			MinRSASize: 2048,
+			Hashes:     []crypto.Hash{crypto.SHA224, crypto.SHA256, crypto.SHA384, crypto.SHA512, crypto.SHA512_256, crypto.SHA3_256, crypto.SHA3_384, crypto.SHA3_512},
+			Encodings:  []Encoding{EncodingPEM, EncodingJWK},
+		}, nil
+	case CNSA2:
+		return &Policy{
+			Name:       "CNSA 2.0",
+			Algorithms: []Algorithm{AlgorithmRSA, AlgorithmECDSA, AlgorithmMLKEM1024, AlgorithmMLDSA87},
+			Curves:     []string{"P-384"},
+			MinRSASize: 3072,
+			Hashes:     []crypto.Hash{crypto.SHA384, crypto.SHA512},
+			Encodings:  []Encoding{EncodingPEM, EncodingJWK},
+		}, nil
+	case Modern:
+		return &Policy{
+			Name:       "modern",
+			Algorithms: []Algorithm{AlgorithmRSA, AlgorithmECDSA, AlgorithmEd25519, AlgorithmX25519, AlgorithmMLKEM768, AlgorithmMLKEM1024, AlgorithmMLDSA65, AlgorithmMLDSA87, AlgorithmX25519MLKEM768, AlgorithmSecP256r1MLKEM768},
+			Curves:     []string{"P-256", "P-384"},
+			MinRSASize: 3072,
+			Hashes:     []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512},
+			Encodings:  []Encoding{EncodingPEM, EncodingJWK},
+		}, nil
+	}
+
+	return nil, c.ErrUnknownPreset
+}
+
+// CheckAlgorithm checks the algorithm is allowed
+func (p *Policy) CheckAlgorithm(algorithm Algorithm) error {
+	if p == nil || contains(p.Algorithms, algorithm) {
+		return nil
+	}
+
+	return p.violation("algorithm %s is not allowed", algorithm)
+}
+
+// CheckRSA checks rsa keys of the size are allowed
+func (p *Policy) CheckRSA(size int) error {
+	if err := p.CheckAlgorithm(AlgorithmRSA); err != nil {
+		return err
+	}
+
+	if p == nil || size >= p.MinRSASize {
+		return nil
+	}
+
+	return p.violation("rsa key size %d is below the minimum of %d", size, p.MinRSASize)
+}
+
+// CheckCurve checks ecdsa keys on the curve are allowed
+func (p *Policy) CheckCurve(curve elliptic.Curve) error {
+	if err := p.CheckAlgorithm(AlgorithmECDSA); err != nil {
+		return err
+	}
+
+	if p == nil {
+		return nil
+	}
+
+	if curve == nil {
+		return c.ErrNilPublicKeyCurve
+	}
+
+	if contains(p.Curves, curve.Params().Name) {
+		return nil
+	}
+
+	return p.violation("curve %s is not allowed", curve.Params().Name)
+}
+
+// CheckHash checks the hash function is allowed
+func (p *Policy) CheckHash(hash crypto.Hash) error {
+	if p == nil || contains(p.Hashes, hash) {
+		return nil
+	}
+
+	return p.violation("hash %s is not allowed", hash)
+}
+
+// CheckEncoding checks the key encoding is allowed
+func (p *Policy) CheckEncoding(encoding Encoding) error {
+	if p == nil || contains(p.Encodings, encoding) {
+		return nil
+	}
+
+	return p.violation("encoding %s is not allowed", encoding)
+}
+
+// CheckPublicKey checks the algorithm, size or curve of a rsa, ecdsa, ecdh, ed25519 or ed448 public
+// key, ecdh keys on NIST curves being checked as ecdsa keys
+func (p *Policy) CheckPublicKey(publicKey crypto.PublicKey) error {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if key == nil || key.N == nil {
+			return c.ErrNilPublicKey
+		}
+
+		return p.CheckRSA(key.N.BitLen())
+	case *ecdsa.PublicKey:
+		if key == nil {
+			return c.ErrNilPublicKey
+		}
+
+		return p.CheckCurve(key.Curve)
+	case *ecdh.PublicKey:
+		if key == nil {
+			return c.ErrNilPublicKey
+		}
+
+		switch key.Curve() {
+		case ecdh.X25519():
+			return p.CheckAlgorithm(AlgorithmX25519)
+		case ecdh.P256():
+			return p.CheckCurve(elliptic.P256())
+		case ecdh.P384():
+			return p.CheckCurve(elliptic.P384())
+		case ecdh.P521():
+			return p.CheckCurve(elliptic.P521())
+		}
+
+		return c.ErrUnsupportedCurve
+	case ed25519.PublicKey:
+		return p.CheckAlgorithm(AlgorithmEd25519)
+	case ed448.PublicKey:
+		return p.CheckAlgorithm(AlgorithmEd448)
+	case nil:
+		return c.ErrNilPublicKey
+	}
+
+	return c.ErrUnsupportedPublicKey
+}
+
+// CheckJWK checks exporting the public key as a jwk with the signature algorithm is allowed
+func (p *Policy) CheckJWK(publicKey crypto.PublicKey, algo jose.SignatureAlgorithm) error {
+	if err := p.CheckEncoding(EncodingJWK); err != nil {
+		return err
+	}
+
+	if err := p.CheckPublicKey(publicKey); err != nil {
+		return err
+	}
+
+	if hash, ok := jwkHashes[algo]; ok {
+		return p.CheckHash(hash)
+	}
+
+	return nil
+}
+
+// violation builds a Violation of the policy
+func (p *Policy) violation(format string, args ...interface{}) error {
+	return &Violation{Policy: p.Name, Reason: fmt.Sprintf(format, args...)}
+}
+
+// Error gets the violation message
+func (v *Violation) Error() string {
+	return fmt.Sprintf("%s: %s policy: %s", c.ErrPolicyViolation, v.Policy, v.Reason)
+}
+
+// Unwrap gets c.ErrPolicyViolation
+func (v *Violation) Unwrap() error {
+	return c.ErrPolicyViolation
+}
+
+// contains reports whether the value is in the values
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,103 @@
+package policy
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	c "github.com/ELares/crypto/pkg"
+	"github.com/cloudflare/circl/sign/ed448"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestNewPolicy(t *testing.T) {
+	for _, preset := range []Preset{FIPS1403, CNSA2, Modern} {
+		policy, err := NewPolicy(preset)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, policy.Name)
+	}
+
+	policy, err := NewPolicy(Preset(42))
+	assert.Nil(t, policy)
+	assert.Equal(t, c.ErrUnknownPreset, err)
+}
+
+func TestCheckPublicKey(t *testing.T) {
+	fips, _ := NewPolicy(FIPS1403)
+	cnsa, _ := NewPolicy(CNSA2)
+	modern, _ := NewPolicy(Modern)
+
+	rsa2048, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p224, _ := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	ed, _, _ := ed25519.GenerateKey(rand.Reader)
+	ed448Key, _, _ := ed448.GenerateKey(rand.Reader)
+	x25519, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	ecdhP384, _ := ecdh.P384().GenerateKey(rand.Reader)
+
+	testcases := []struct {
+		name string
+
+		policy    *Policy
+		publicKey crypto.PublicKey
+
+		isViolation bool
+	}{
+		{name: "Valid nil policy", policy: nil, publicKey: &p224.PublicKey},
+		{name: "Valid FIPS RSA-2048", policy: fips, publicKey: &rsa2048.PublicKey},
+		{name: "Valid FIPS P-224", policy: fips, publicKey: &p224.PublicKey},
+		{name: "Valid FIPS Ed25519", policy: fips, publicKey: ed},
+		{name: "Valid FIPS Ed448", policy: fips, publicKey: ed448Key},
+		{name: "Valid CNSA P-384", policy: cnsa, publicKey: &p384.PublicKey},
+		{name: "Valid modern P-256", policy: modern, publicKey: &p256.PublicKey},
+		{name: "Valid modern Ed25519", policy: modern, publicKey: ed},
+		{name: "Valid modern X25519", policy: modern, publicKey: x25519.PublicKey()},
+		{name: "Valid CNSA ECDH P-384", policy: cnsa, publicKey: ecdhP384.PublicKey()},
+		{name: "Invalid CNSA RSA-2048", policy: cnsa, publicKey: &rsa2048.PublicKey, isViolation: true},
+		{name: "Invalid CNSA P-256", policy: cnsa, publicKey: &p256.PublicKey, isViolation: true},
+		{name: "Invalid CNSA Ed25519", policy: cnsa, publicKey: ed, isViolation: true},
+		{name: "Invalid FIPS X25519", policy: fips, publicKey: x25519.PublicKey(), isViolation: true},
+		{name: "Invalid modern RSA-2048", policy: modern, publicKey: &rsa2048.PublicKey, isViolation: true},
+		{name: "Invalid modern P-224", policy: modern, publicKey: &p224.PublicKey, isViolation: true},
+		{name: "Invalid modern Ed448", policy: modern, publicKey: ed448Key, isViolation: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.CheckPublicKey(tc.publicKey)
+
+			if tc.isViolation {
+				assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+				var violation *Violation
+				assert.True(t, errors.As(err, &violation))
+				assert.Equal(t, tc.policy.Name, violation.Policy)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+
+	assert.Equal(t, c.ErrNilPublicKey, fips.CheckPublicKey(nil))
+	assert.Equal(t, c.ErrUnsupportedPublicKey, fips.CheckPublicKey("key"))
+}
+
+func TestCheckJWK(t *testing.T) {
+	cnsa, _ := NewPolicy(CNSA2)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+	assert.Nil(t, cnsa.CheckJWK(&p384.PublicKey, jose.ES384))
+	assert.True(t, errors.Is(cnsa.CheckJWK(&p384.PublicKey, jose.ES256), c.ErrPolicyViolation))
+
+	pemOnly := &Policy{Name: "pem only", Algorithms: []Algorithm{AlgorithmECDSA}, Curves: []string{"P-384"}, Encodings: []Encoding{EncodingPEM}}
+	assert.True(t, errors.Is(pemOnly.CheckJWK(&p384.PublicKey, jose.ES384), c.ErrPolicyViolation))
+	assert.Nil(t, pemOnly.CheckEncoding(EncodingPEM))
+}
@@ -0,0 +1,101 @@
+package revocation
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	c "github.com/ELares/crypto/pkg"
+	p "github.com/ELares/crypto/pkg/pem"
+)
+
+type (
+	// ICRL interface for methods to generate certificate revocation lists and conversion to PEM format
+	ICRL interface {
+		CRL(db IDatabase, issuer *x509.Certificate, signer crypto.Signer, number *big.Int, thisUpdate, nextUpdate time.Time) ([]byte, error)
+		CRLPEM(db IDatabase, issuer *x509.Certificate, signer crypto.Signer, number *big.Int, thisUpdate, nextUpdate time.Time) (p.CRLPEM, error)
+
+		FromPEMCRL(p.CRLPEM) (*x509.RevocationList, error)
+		ToPEMCRL(der []byte) (p.CRLPEM, error)
+	}
+
+	// CRL struct to implement the ICRL methods
+	CRL struct{}
+)
+
+// NewCRL gets a new CRL pointer
+func NewCRL() ICRL {
+	return &CRL{}
+}
+
+// CRL generates a DER certificate revocation list of the database entries, signed by the issuer
+// private key (rsa, ecdsa or ed25519)
+func (l *CRL) CRL(db IDatabase, issuer *x509.Certificate, signer crypto.Signer, number *big.Int, thisUpdate, nextUpdate time.Time) ([]byte, error) {
+	if db == nil {
+		return nil, c.ErrNilDatabase
+	}
+
+	if issuer == nil {
+		return nil, c.ErrNilCertificate
+	}
+
+	if signer == nil {
+		return nil, c.ErrNilSigner
+	}
+
+	if number == nil {
+		return nil, c.ErrNilSerialNumber
+	}
+
+	entries := db.Entries()
+	revoked := make([]x509.RevocationListEntry, 0, len(entries))
+	for _, entry := range entries {
+		revoked = append(revoked, x509.RevocationListEntry{
+			SerialNumber:   entry.SerialNumber,
+			RevocationTime: entry.RevokedAt,
+			ReasonCode:     int(entry.Reason),
+		})
+	}
+
+	template := &x509.RevocationList{
+		RevokedCertificateEntries: revoked,
+		Number:                    number,
+		ThisUpdate:                thisUpdate,
+		NextUpdate:                nextUpdate,
+	}
+
+	return x509.CreateRevocationList(rand.Reader, template, issuer, signer)
+}
+
+// CRLPEM generates a PEM certificate revocation list of the database entries
+func (l *CRL) CRLPEM(db IDatabase, issuer *x509.Certificate, signer crypto.Signer, number *big.Int, thisUpdate, nextUpdate time.Time) (p.CRLPEM, error) {
+	der, err := l.CRL(db, issuer, signer, number, thisUpdate, nextUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.ToPEMCRL(der)
+}
+
+// FromPEMCRL takes a crl pem and converts it into a x509 revocation list
+func (l *CRL) FromPEMCRL(crlPEM p.CRLPEM) (*x509.RevocationList, error) {
+	block, _ := pem.Decode(crlPEM)
+
+	if block == nil || block.Type != c.X509CRL {
+		return nil, c.ErrDecodePEMCRL
+	}
+
+	return x509.ParseRevocationList(block.Bytes)
+}
+
+// ToPEMCRL converts a DER certificate revocation list into a crl PEM
+func (l *CRL) ToPEMCRL(der []byte) (p.CRLPEM, error) {
+	if len(der) == 0 {
+		return nil, c.ErrEmptyDER
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: c.X509CRL, Bytes: der}), nil
+}
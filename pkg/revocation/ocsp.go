@@ -0,0 +1,388 @@
+package revocation
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	c "github.com/ELares/crypto/pkg"
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// OCSPRequestContentType content type of an OCSP request body
+	OCSPRequestContentType = "application/ocsp-request"
+
+	// OCSPResponseContentType content type of an OCSP response body
+	OCSPResponseContentType = "application/ocsp-response"
+
+	// maxRequestSize upper bound of an OCSP request body
+	maxRequestSize = 10 * 1024
+)
+
+var (
+	oidBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+
+	oidSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidEd25519         = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+	hashOIDs = map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA1:   {1, 3, 14, 3, 2, 26},
+		crypto.SHA256: {2, 16, 840, 1, 101, 3, 4, 2, 1},
+		crypto.SHA384: {2, 16, 840, 1, 101, 3, 4, 2, 2},
+		crypto.SHA512: {2, 16, 840, 1, 101, 3, 4, 2, 3},
+	}
+)
+
+type (
+	// Responder http.Handler answering OCSP requests (RFC 6960) from a revocation database
+	Responder struct {
+		database      IDatabase
+		issuer        *x509.Certificate
+		responderCert *x509.Certificate
+		signer        crypto.Signer
+		validity      time.Duration
+	}
+
+	certID struct {
+		HashAlgorithm pkix.AlgorithmIdentifier
+		NameHash      []byte
+		IssuerKeyHash []byte
+		SerialNumber  *big.Int
+	}
+
+	revokedInfo struct {
+		RevocationTime time.Time       `asn1:"generalized"`
+		Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+	}
+
+	singleResponse struct {
+		CertID     certID
+		Good       asn1.Flag   `asn1:"tag:0,optional"`
+		Revoked    revokedInfo `asn1:"tag:1,optional"`
+		ThisUpdate time.Time   `asn1:"generalized"`
+		NextUpdate time.Time   `asn1:"generalized,explicit,tag:0,optional"`
+	}
+
+	responseData struct {
+		ResponderID asn1.RawValue
+		ProducedAt  time.Time `asn1:"generalized"`
+		Responses   []singleResponse
+	}
+
+	basicResponse struct {
+		TBSResponseData    asn1.RawValue
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Signature          asn1.BitString
+		Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+	}
+
+	responseBytes struct {
+		ResponseType asn1.ObjectIdentifier
+		Response     []byte
+	}
+
+	responseASN1 struct {
+		Status   asn1.Enumerated
+		Response responseBytes `asn1:"explicit,tag:0"`
+	}
+
+	subjectPublicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+)
+
+// NewResponder gets a new OCSP Responder pointer for certificates of the issuer, responses are
+// signed by the signer which is either the issuer key (responderCert nil) or the key of a
+// delegated responderCert issued by the issuer with the OCSP signing extended key usage, and stay
+// valid for the validity duration
+func NewResponder(db IDatabase, issuer, responderCert *x509.Certificate, signer crypto.Signer, validity time.Duration) (*Responder, error) {
+	if db == nil {
+		return nil, c.ErrNilDatabase
+	}
+
+	if issuer == nil {
+		return nil, c.ErrNilCertificate
+	}
+
+	signingCert := issuer
+	if responderCert != nil && !responderCert.Equal(issuer) {
+		if !slices.Contains(responderCert.ExtKeyUsage, x509.ExtKeyUsageOCSPSigning) {
+			return nil, c.ErrMissingOCSPSigning
+		}
+
+		if err := responderCert.CheckSignatureFrom(issuer); err != nil {
+			return nil, c.ErrResponderNotIssued
+		}
+
+		signingCert = responderCert
+	}
+
+	if signer == nil {
+		return nil, c.ErrNilSigner
+	}
+
+	if _, _, err := signatureAlgorithm(signer.Public()); err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(signingCert.PublicKey) {
+		return nil, c.ErrKeyMismatch
+	}
+
+	return &Responder{
+		database:      db,
+		issuer:        issuer,
+		responderCert: responderCert,
+		signer:        signer,
+		validity:      validity,
+	}, nil
+}
+
+// ServeHTTP answers OCSP requests sent through GET (base64 in the path, after the prefix of the
+// ServeMux pattern the responder is mounted at) or POST
+func (r *Responder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var raw []byte
+	var err error
+
+	switch req.Method {
+	case http.MethodGet:
+		raw, err = decodeGETRequest(req)
+	case http.MethodPost:
+		raw, err = io.ReadAll(io.LimitReader(req.Body, maxRequestSize))
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", OCSPResponseContentType)
+
+	if err != nil {
+		w.Write(ocsp.MalformedRequestErrorResponse)
+		return
+	}
+
+	ocspRequest, err := ocsp.ParseRequest(raw)
+	if err != nil {
+		w.Write(ocsp.MalformedRequestErrorResponse)
+		return
+	}
+
+	if !r.issuedBy(ocspRequest) {
+		w.Write(ocsp.UnauthorizedErrorResponse)
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	response, err := r.Respond(ocspRequest, now)
+	if err != nil {
+		w.Write(ocsp.InternalErrorErrorResponse)
+		return
+	}
+
+	if req.Method == http.MethodGet {
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d, public, no-transform, must-revalidate", int(r.validity.Seconds())))
+	}
+
+	w.Write(response)
+}
+
+// Respond builds a signed OCSP response for the request at the given time, serial numbers absent
+// from the database are reported as good
+func (r *Responder) Respond(ocspRequest *ocsp.Request, now time.Time) ([]byte, error) {
+	hashOID, ok := hashOIDs[ocspRequest.HashAlgorithm]
+	if !ok {
+		return nil, x509.ErrUnsupportedAlgorithm
+	}
+
+	single := singleResponse{
+		CertID: certID{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: hashOID, Parameters: asn1.NullRawValue},
+			NameHash:      ocspRequest.IssuerNameHash,
+			IssuerKeyHash: ocspRequest.IssuerKeyHash,
+			SerialNumber:  ocspRequest.SerialNumber,
+		},
+		ThisUpdate: now,
+		NextUpdate: now.Add(r.validity),
+	}
+
+	if entry, revoked := r.database.Status(ocspRequest.SerialNumber); revoked {
+		single.Revoked = revokedInfo{
+			RevocationTime: entry.RevokedAt,
+			Reason:         asn1.Enumerated(entry.Reason),
+		}
+	} else {
+		single.Good = true
+	}
+
+	responder := r.issuer
+	if r.responderCert != nil {
+		responder = r.responderCert
+	}
+
+	keyHash, err := publicKeyHash(responder, crypto.SHA1)
+	if err != nil {
+		return nil, err
+	}
+
+	keyHashDER, err := asn1.Marshal(keyHash)
+	if err != nil {
+		return nil, err
+	}
+
+	tbs, err := asn1.Marshal(responseData{
+		ResponderID: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: keyHashDER},
+		ProducedAt:  now,
+		Responses:   []singleResponse{single},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hash, algorithm, err := signatureAlgorithm(r.signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	digest := tbs
+	if hash != 0 {
+		h := hash.New()
+		h.Write(tbs)
+		digest = h.Sum(nil)
+	}
+
+	signature, err := r.signer.Sign(rand.Reader, digest, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	basic := basicResponse{
+		TBSResponseData:    asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: algorithm,
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	}
+
+	if r.responderCert != nil {
+		basic.Certificates = []asn1.RawValue{{FullBytes: r.responderCert.Raw}}
+	}
+
+	basicDER, err := asn1.Marshal(basic)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(responseASN1{
+		Status: asn1.Enumerated(ocsp.Success),
+		Response: responseBytes{
+			ResponseType: oidBasicResponse,
+			Response:     basicDER,
+		},
+	})
+}
+
+// issuedBy checks that the request targets a certificate of the responder issuer
+func (r *Responder) issuedBy(ocspRequest *ocsp.Request) bool {
+	if _, ok := hashOIDs[ocspRequest.HashAlgorithm]; !ok || !ocspRequest.HashAlgorithm.Available() {
+		return false
+	}
+
+	h := ocspRequest.HashAlgorithm.New()
+	h.Write(r.issuer.RawSubject)
+	if !bytes.Equal(h.Sum(nil), ocspRequest.IssuerNameHash) {
+		return false
+	}
+
+	keyHash, err := publicKeyHash(r.issuer, ocspRequest.HashAlgorithm)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(keyHash, ocspRequest.IssuerKeyHash)
+}
+
+// decodeGETRequest extracts the DER request from the base64 path of a GET request, stripping the
+// path of the ServeMux pattern that routed it; the escaped path is decoded so that a "/" of the
+// base64, escaped as %2F, is neither taken as a separator nor cleaned away by the ServeMux
+func decodeGETRequest(req *http.Request) ([]byte, error) {
+	encoded := req.URL.EscapedPath()
+	if prefix := patternPath(req.Pattern); strings.HasPrefix(encoded, prefix) {
+		encoded = encoded[len(prefix):]
+	}
+
+	unescaped, err := url.PathUnescape(strings.TrimPrefix(encoded, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(unescaped)
+}
+
+// patternPath gets the path of a "[METHOD ][HOST]/[PATH]" ServeMux pattern, "/" when the request
+// was not routed by a ServeMux
+func patternPath(pattern string) string {
+	fields := strings.Fields(pattern)
+	if len(fields) == 0 {
+		return "/"
+	}
+
+	if i := strings.Index(fields[len(fields)-1], "/"); i >= 0 {
+		return fields[len(fields)-1][i:]
+	}
+
+	return "/"
+}
+
+// publicKeyHash hashes the subject public key bits of the certificate
+func publicKeyHash(cert *x509.Certificate, hash crypto.Hash) ([]byte, error) {
+	var spki subjectPublicKeyInfo
+	if _, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return nil, err
+	}
+
+	h := hash.New()
+	h.Write(spki.PublicKey.RightAlign())
+
+	return h.Sum(nil), nil
+}
+
+// signatureAlgorithm picks the hash and signature algorithm identifier for the signer public key
+func signatureAlgorithm(publicKey crypto.PublicKey) (crypto.Hash, pkix.AlgorithmIdentifier, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return crypto.SHA256, pkix.AlgorithmIdentifier{Algorithm: oidSHA256WithRSA, Parameters: asn1.NullRawValue}, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().BitSize {
+		case 384:
+			return crypto.SHA384, pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA384}, nil
+		case 521:
+			return crypto.SHA512, pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA512}, nil
+		default:
+			return crypto.SHA256, pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256}, nil
+		}
+	case ed25519.PublicKey:
+		return crypto.Hash(0), pkix.AlgorithmIdentifier{Algorithm: oidEd25519}, nil
+	}
+
+	return 0, pkix.AlgorithmIdentifier{}, c.ErrUnsupportedSigner
+}
@@ -0,0 +1,153 @@
+package revocation
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	c "github.com/ELares/crypto/pkg"
+)
+
+const (
+	// Unspecified RFC 5280 reason code unspecified
+	Unspecified Reason = 0
+
+	// KeyCompromise RFC 5280 reason code keyCompromise
+	KeyCompromise Reason = 1
+
+	// CACompromise RFC 5280 reason code cACompromise
+	CACompromise Reason = 2
+
+	// AffiliationChanged RFC 5280 reason code affiliationChanged
+	AffiliationChanged Reason = 3
+
+	// Superseded RFC 5280 reason code superseded
+	Superseded Reason = 4
+
+	// CessationOfOperation RFC 5280 reason code cessationOfOperation
+	CessationOfOperation Reason = 5
+
+	// CertificateHold RFC 5280 reason code certificateHold
+	CertificateHold Reason = 6
+
+	// PrivilegeWithdrawn RFC 5280 reason code privilegeWithdrawn
+	PrivilegeWithdrawn Reason = 9
+
+	// AACompromise RFC 5280 reason code aACompromise
+	AACompromise Reason = 10
+)
+
+type (
+	// Reason CRL reason code as defined in RFC 5280 section 5.3.1
+	Reason int
+
+	// Entry a revoked certificate record
+	Entry struct {
+		SerialNumber *big.Int
+		RevokedAt    time.Time
+		Reason       Reason
+	}
+
+	// IDatabase interface for methods to record and query revoked certificates
+	IDatabase interface {
+		Revoke(serialNumber *big.Int, revokedAt time.Time, reason Reason) error
+		Release(serialNumber *big.Int) error
+		Status(serialNumber *big.Int) (Entry, bool)
+		Entries() []Entry
+	}
+
+	// Database struct to implement the IDatabase methods in memory
+	Database struct {
+		mu      sync.RWMutex
+		entries map[string]Entry
+	}
+)
+
+// NewDatabase gets a new in memory revocation Database pointer
+func NewDatabase() IDatabase {
+	return &Database{entries: map[string]Entry{}}
+}
+
+// Valid reports whether the reason can be used for a revoked certificate entry
+func (r Reason) Valid() bool {
+	switch r {
+	case Unspecified, KeyCompromise, CACompromise, AffiliationChanged, Superseded,
+		CessationOfOperation, CertificateHold, PrivilegeWithdrawn, AACompromise:
+		return true
+	}
+
+	return false
+}
+
+// Revoke records the certificate serial number as revoked, replacing any previous record
+func (d *Database) Revoke(serialNumber *big.Int, revokedAt time.Time, reason Reason) error {
+	if serialNumber == nil {
+		return c.ErrNilSerialNumber
+	}
+
+	if !reason.Valid() {
+		return c.ErrInvalidRevocationReason
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries[serialNumber.String()] = Entry{
+		SerialNumber: new(big.Int).Set(serialNumber),
+		RevokedAt:    revokedAt.UTC(),
+		Reason:       reason,
+	}
+
+	return nil
+}
+
+// Release removes a certificate put on hold, only CertificateHold entries can be released
+func (d *Database) Release(serialNumber *big.Int) error {
+	if serialNumber == nil {
+		return c.ErrNilSerialNumber
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.entries[serialNumber.String()]
+	if !ok || entry.Reason != CertificateHold {
+		return c.ErrInvalidRevocationReason
+	}
+
+	delete(d.entries, serialNumber.String())
+
+	return nil
+}
+
+// Status returns the revocation record of the serial number, if any
+func (d *Database) Status(serialNumber *big.Int) (Entry, bool) {
+	if serialNumber == nil {
+		return Entry{}, false
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok := d.entries[serialNumber.String()]
+
+	return entry, ok
+}
+
+// Entries returns every revocation record ordered by serial number
+func (d *Database) Entries() []Entry {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(d.entries))
+	for _, entry := range d.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].SerialNumber.Cmp(entries[j].SerialNumber) < 0
+	})
+
+	return entries
+}
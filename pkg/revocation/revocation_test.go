@@ -0,0 +1,417 @@
+package revocation
+
+import (
+	"bytes"
+	"crypto"
+	ced25519 "crypto/ed25519"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	c "github.com/ELares/crypto/pkg"
+	"github.com/ELares/crypto/pkg/ecdsa"
+	"github.com/ELares/crypto/pkg/ed25519"
+	"github.com/ELares/crypto/pkg/rsa"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ocsp"
+)
+
+type testCA struct {
+	name   string
+	cert   *x509.Certificate
+	signer crypto.Signer
+	leaf   *x509.Certificate
+}
+
+func newTestCA(t *testing.T, name string, signer crypto.Signer) testCA {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name + " CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(nil, template, template, signer.Public(), signer)
+	assert.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	_, leafKey, _ := ecdsa.NewECDSA().P256()
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(4242),
+		Subject:      pkix.Name{CommonName: name + " leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	leafDER, err := x509.CreateCertificate(nil, leafTemplate, cert, leafKey, signer)
+	assert.Nil(t, err)
+
+	leaf, err := x509.ParseCertificate(leafDER)
+	assert.Nil(t, err)
+
+	return testCA{name: name, cert: cert, signer: signer, leaf: leaf}
+}
+
+func newTestCAs(t *testing.T) []testCA {
+	rsaKey, _, _ := rsa.NewRSA().R2048()
+	ecdsaKey, _, _ := ecdsa.NewECDSA().P384()
+	edKey, _, _ := ed25519.NewED25519().Ed25519()
+
+	return []testCA{
+		newTestCA(t, "RSA", rsaKey),
+		newTestCA(t, "ECDSA", ecdsaKey),
+		newTestCA(t, "Ed25519", edKey),
+	}
+}
+
+func TestDatabase(t *testing.T) {
+	db := NewDatabase()
+	now := time.Now()
+
+	testcases := []struct {
+		name   string
+		serial *big.Int
+		reason Reason
+
+		isError bool
+	}{
+		{
+			name:   "Valid KeyCompromise",
+			serial: big.NewInt(3),
+			reason: KeyCompromise,
+
+			isError: false,
+		},
+		{
+			name:   "Valid CertificateHold",
+			serial: big.NewInt(1),
+			reason: CertificateHold,
+
+			isError: false,
+		},
+		{
+			name:   "Invalid Nil Serial",
+			serial: nil,
+			reason: Superseded,
+
+			isError: true,
+		},
+		{
+			name:   "Invalid Reason 7",
+			serial: big.NewInt(2),
+			reason: Reason(7),
+
+			isError: true,
+		},
+		{
+			name:   "Invalid Reason RemoveFromCRL",
+			serial: big.NewInt(2),
+			reason: Reason(8),
+
+			isError: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := db.Revoke(tc.serial, now, tc.reason)
+
+			if tc.isError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+
+				entry, ok := db.Status(tc.serial)
+				assert.True(t, ok)
+				assert.Equal(t, tc.reason, entry.Reason)
+			}
+		})
+	}
+
+	entries := db.Entries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, int64(1), entries[0].SerialNumber.Int64())
+	assert.Equal(t, int64(3), entries[1].SerialNumber.Int64())
+
+	assert.NotNil(t, db.Release(big.NewInt(3)))
+	assert.Nil(t, db.Release(big.NewInt(1)))
+
+	_, ok := db.Status(big.NewInt(1))
+	assert.False(t, ok)
+}
+
+func TestCRL(t *testing.T) {
+	crl := NewCRL()
+
+	db := NewDatabase()
+	revokedAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	assert.Nil(t, db.Revoke(big.NewInt(4242), revokedAt, KeyCompromise))
+	assert.Nil(t, db.Revoke(big.NewInt(17), revokedAt, Unspecified))
+
+	for _, ca := range newTestCAs(t) {
+		t.Run(ca.name, func(t *testing.T) {
+			thisUpdate := time.Now()
+			nextUpdate := thisUpdate.Add(24 * time.Hour)
+
+			crlPEM, err := crl.CRLPEM(db, ca.cert, ca.signer, big.NewInt(1), thisUpdate, nextUpdate)
+			assert.Nil(t, err)
+			assert.NotEmpty(t, crlPEM)
+
+			list, err := crl.FromPEMCRL(crlPEM)
+			assert.Nil(t, err)
+			assert.Nil(t, list.CheckSignatureFrom(ca.cert))
+			assert.Equal(t, int64(1), list.Number.Int64())
+			assert.Equal(t, nextUpdate.UTC().Truncate(time.Second), list.NextUpdate)
+
+			assert.Len(t, list.RevokedCertificateEntries, 2)
+			assert.Equal(t, int64(17), list.RevokedCertificateEntries[0].SerialNumber.Int64())
+			assert.Equal(t, int64(4242), list.RevokedCertificateEntries[1].SerialNumber.Int64())
+			assert.Equal(t, int(KeyCompromise), list.RevokedCertificateEntries[1].ReasonCode)
+			assert.Equal(t, revokedAt, list.RevokedCertificateEntries[1].RevocationTime)
+		})
+	}
+
+	ca := newTestCAs(t)[0]
+
+	_, err := crl.CRL(db, nil, ca.signer, big.NewInt(1), time.Now(), time.Now())
+	assert.NotNil(t, err)
+
+	_, err = crl.CRL(db, ca.cert, nil, big.NewInt(1), time.Now(), time.Now())
+	assert.NotNil(t, err)
+
+	_, err = crl.CRL(db, ca.cert, ca.signer, nil, time.Now(), time.Now())
+	assert.NotNil(t, err)
+
+	_, err = crl.CRL(nil, ca.cert, ca.signer, big.NewInt(1), time.Now(), time.Now())
+	assert.Equal(t, c.ErrNilDatabase, err)
+
+	_, err = crl.FromPEMCRL([]byte("-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n"))
+	assert.NotNil(t, err)
+
+	_, err = crl.ToPEMCRL(nil)
+	assert.NotNil(t, err)
+}
+
+func TestResponder(t *testing.T) {
+	for _, ca := range newTestCAs(t) {
+		t.Run(ca.name, func(t *testing.T) {
+			db := NewDatabase()
+
+			responder, err := NewResponder(db, ca.cert, nil, ca.signer, time.Hour)
+			assert.Nil(t, err)
+
+			server := httptest.NewServer(responder)
+			defer server.Close()
+
+			request, err := ocsp.CreateRequest(ca.leaf, ca.cert, nil)
+			assert.Nil(t, err)
+
+			response := postOCSP(t, server.URL, request)
+			parsed := parseOCSP(t, response, ca)
+			assert.Equal(t, ocsp.Good, parsed.Status)
+			assert.Equal(t, 0, parsed.SerialNumber.Cmp(ca.leaf.SerialNumber))
+
+			revokedAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+			assert.Nil(t, db.Revoke(ca.leaf.SerialNumber, revokedAt, Superseded))
+
+			response = getOCSP(t, server.URL, request)
+			parsed = parseOCSP(t, response, ca)
+			assert.Equal(t, ocsp.Revoked, parsed.Status)
+			assert.Equal(t, int(Superseded), parsed.RevocationReason)
+			assert.Equal(t, revokedAt, parsed.RevokedAt)
+		})
+	}
+}
+
+func TestResponderErrors(t *testing.T) {
+	cas := newTestCAs(t)
+
+	responder, err := NewResponder(NewDatabase(), cas[0].cert, nil, cas[0].signer, time.Hour)
+	assert.Nil(t, err)
+
+	server := httptest.NewServer(responder)
+	defer server.Close()
+
+	response := postOCSP(t, server.URL, []byte("not an ocsp request"))
+	assert.Equal(t, ocsp.MalformedRequestErrorResponse, response)
+
+	otherRequest, err := ocsp.CreateRequest(cas[1].leaf, cas[1].cert, nil)
+	assert.Nil(t, err)
+
+	response = postOCSP(t, server.URL, otherRequest)
+	assert.Equal(t, ocsp.UnauthorizedErrorResponse, response)
+
+	res, err := http.Head(server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+
+	_, err = NewResponder(NewDatabase(), nil, nil, cas[0].signer, time.Hour)
+	assert.NotNil(t, err)
+
+	_, err = NewResponder(NewDatabase(), cas[0].cert, nil, nil, time.Hour)
+	assert.NotNil(t, err)
+
+	_, err = NewResponder(nil, cas[0].cert, nil, cas[0].signer, time.Hour)
+	assert.Equal(t, c.ErrNilDatabase, err)
+
+	_, err = NewResponder(NewDatabase(), cas[0].cert, cas[0].cert, cas[0].signer, time.Hour)
+	assert.Nil(t, err)
+
+	_, err = NewResponder(NewDatabase(), cas[0].cert, nil, cas[1].signer, time.Hour)
+	assert.Equal(t, c.ErrKeyMismatch, err)
+}
+
+func TestResponderSubPath(t *testing.T) {
+	ca := newTestCAs(t)[1]
+
+	responder, err := NewResponder(NewDatabase(), ca.cert, nil, ca.signer, time.Hour)
+	assert.Nil(t, err)
+
+	mux := http.NewServeMux()
+	mux.Handle("/ocsp/", responder)
+	mux.Handle("GET /status/ocsp/", responder)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	request, err := ocsp.CreateRequest(ca.leaf, ca.cert, nil)
+	assert.Nil(t, err)
+
+	for _, path := range []string{"/ocsp", "/status/ocsp"} {
+		parsed := parseOCSP(t, getOCSP(t, server.URL+path, request), ca)
+		assert.Equal(t, ocsp.Good, parsed.Status)
+	}
+
+	// a base64 "/" escaped as %2F is kept, a "+" may be escaped or not
+	req := httptest.NewRequest(http.MethodGet, "/ocsp/%2F%2F%2F%2F%2B%2B++", nil)
+	req.Pattern = "/ocsp/"
+
+	raw, err := decodeGETRequest(req)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xfb, 0xef, 0xbe}, raw)
+}
+
+func TestDelegatedResponder(t *testing.T) {
+	ca := newTestCAs(t)[1]
+
+	testcases := []struct {
+		name string
+
+		extKeyUsage []x509.ExtKeyUsage
+		otherIssuer bool
+		otherSigner bool
+
+		expectError error
+	}{
+		{name: "Valid OCSP Signing", extKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}},
+		{name: "Invalid No Extended Key Usage", expectError: c.ErrMissingOCSPSigning},
+		{name: "Invalid Server Auth", extKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, expectError: c.ErrMissingOCSPSigning},
+		{name: "Invalid Other Issuer", extKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}, otherIssuer: true, expectError: c.ErrResponderNotIssued},
+		{name: "Invalid Other Signer", extKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}, otherSigner: true, expectError: c.ErrKeyMismatch},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			responderKey, _, _ := ecdsa.NewECDSA().P256()
+			template := &x509.Certificate{
+				SerialNumber: big.NewInt(99),
+				Subject:      pkix.Name{CommonName: "OCSP responder"},
+				NotBefore:    time.Now().Add(-time.Hour),
+				NotAfter:     time.Now().Add(time.Hour),
+				KeyUsage:     x509.KeyUsageDigitalSignature,
+				ExtKeyUsage:  tc.extKeyUsage,
+			}
+
+			parent, parentSigner := ca.cert, ca.signer
+			if tc.otherIssuer {
+				otherKey, _, _ := ecdsa.NewECDSA().P384()
+				other := newTestCA(t, "Other", otherKey)
+				parent, parentSigner = other.cert, other.signer
+			}
+
+			der, err := x509.CreateCertificate(nil, template, parent, responderKey.Public(), parentSigner)
+			assert.Nil(t, err)
+
+			responderCert, err := x509.ParseCertificate(der)
+			assert.Nil(t, err)
+
+			var signer crypto.Signer = responderKey
+			if tc.otherSigner {
+				signer, _, _ = ecdsa.NewECDSA().P256()
+			}
+
+			responder, err := NewResponder(NewDatabase(), ca.cert, responderCert, signer, time.Hour)
+			assert.Equal(t, tc.expectError, err)
+
+			if tc.expectError != nil {
+				assert.Nil(t, responder)
+				return
+			}
+
+			server := httptest.NewServer(responder)
+			defer server.Close()
+
+			request, err := ocsp.CreateRequest(ca.leaf, ca.cert, nil)
+			assert.Nil(t, err)
+
+			parsed, err := ocsp.ParseResponseForCert(postOCSP(t, server.URL, request), ca.leaf, ca.cert)
+			assert.Nil(t, err)
+			assert.Equal(t, ocsp.Good, parsed.Status)
+			assert.True(t, responderCert.Equal(parsed.Certificate))
+		})
+	}
+}
+
+func postOCSP(t *testing.T, serverURL string, request []byte) []byte {
+	res, err := http.Post(serverURL, OCSPRequestContentType, bytes.NewReader(request))
+	assert.Nil(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, OCSPResponseContentType, res.Header.Get("Content-Type"))
+
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(res.Body)
+
+	return buf.Bytes()
+}
+
+func getOCSP(t *testing.T, serverURL string, request []byte) []byte {
+	res, err := http.Get(serverURL + "/" + url.PathEscape(base64.StdEncoding.EncodeToString(request)))
+	assert.Nil(t, err)
+	defer res.Body.Close()
+
+	assert.NotEmpty(t, res.Header.Get("Cache-Control"))
+
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(res.Body)
+
+	return buf.Bytes()
+}
+
+// parseOCSP parses and verifies the response, x/crypto/ocsp cannot verify ed25519 signatures
+// so those are checked against the issuer key directly
+func parseOCSP(t *testing.T, response []byte, ca testCA) *ocsp.Response {
+	if edKey, ok := ca.signer.Public().(ced25519.PublicKey); ok {
+		parsed, err := ocsp.ParseResponse(response, nil)
+		assert.Nil(t, err)
+		assert.True(t, ced25519.Verify(edKey, parsed.TBSResponseData, parsed.Signature))
+
+		return parsed
+	}
+
+	parsed, err := ocsp.ParseResponseForCert(response, ca.leaf, ca.cert)
+	assert.Nil(t, err)
+
+	return parsed
+}
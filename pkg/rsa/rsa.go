@@ -8,6 +8,7 @@ import (
 
 	c "github.com/ELares/crypto/pkg"
 	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/policy"
 	"gopkg.in/square/go-jose.v2"
 )
 
@@ -15,8 +16,26 @@ const (
 	// RSA2048 common rsa byte size 2048
 	RSA2048 = 2048
 
+	// RSA3072 common rsa byte size 3072
+	RSA3072 = 3072
+
 	// RSA4096 common rsa byte size 4096
 	RSA4096 = 4096
+
+	// MinSize minimum rsa key size accepted by the Validate methods
+	MinSize = RSA2048
+)
+
+var (
+	// algorithmSizes minimum key size of each jwk signature algorithm
+	algorithmSizes = map[jose.SignatureAlgorithm]int{
+		jose.RS256: RSA2048,
+		jose.PS256: RSA2048,
+		jose.RS384: RSA3072,
+		jose.PS384: RSA3072,
+		jose.RS512: RSA4096,
+		jose.PS512: RSA4096,
+	}
 )
 
 type (
@@ -37,6 +56,10 @@ type (
 		FromPEMPrivateKey(p.PrivatePEM) (*rsa.PrivateKey, error)
 		FromPEMPublicKey(p.PublicPEM) (*rsa.PublicKey, error)
 		FromPEM(p.PrivatePEM, p.PublicPEM) (*rsa.PrivateKey, *rsa.PublicKey, error)
+		FromPEMValidated(p.PrivatePEM, p.PublicPEM) (*rsa.PrivateKey, *rsa.PublicKey, error)
+
+		ValidatePrivateKey(*rsa.PrivateKey) error
+		ValidatePublicKey(*rsa.PublicKey) error
 
 		ToPEMPrivateKey(*rsa.PrivateKey) (p.PrivatePEM, error)
 		ToPEMPublicKey(*rsa.PublicKey) (p.PublicPEM, error)
@@ -45,10 +68,13 @@ type (
 		ToJWKRS256(publicKey *rsa.PublicKey, id string) ([]byte, error)
 		ToJWKRS512(publicKey *rsa.PublicKey, id string) ([]byte, error)
 		ToJWK(publicKey *rsa.PublicKey, id string, algo jose.SignatureAlgorithm) ([]byte, error)
+		ToJWKAuto(publicKey *rsa.PublicKey, id string) ([]byte, error)
 	}
 
 	// RSA struct to implement the IRSA methods
-	RSA struct{}
+	RSA struct {
+		policy *policy.Policy
+	}
 )
 
 // NewRSA gets a new RSA pointer
@@ -56,6 +82,11 @@ func NewRSA() IRSA {
 	return &RSA{}
 }
 
+// NewRSAWithPolicy gets a new RSA pointer enforcing the policy on generated, parsed and exported keys
+func NewRSAWithPolicy(policy *policy.Policy) IRSA {
+	return &RSA{policy: policy}
+}
+
 // R2048 generates a new RSA-2048 private/public keys
 func (r *RSA) R2048() (*rsa.PrivateKey, *rsa.PublicKey, error) {
 	return r.generateKeys(r.R2048PrivateKey)
@@ -104,7 +135,20 @@ func (r *RSA) FromPEMPrivateKey(privatePEM p.PrivatePEM) (*rsa.PrivateKey, error
 		return nil, c.ErrDecodePEMPrivateKey
 	}
 
-	return x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err := r.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.policy.CheckPublicKey(&privateKey.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return privateKey, nil
 }
 
 // FromPEMPublicKey takes a public pem key and converts it into a rsa public key
@@ -115,11 +159,19 @@ func (r *RSA) FromPEMPublicKey(publicPEM p.PublicPEM) (*rsa.PublicKey, error) {
 		return nil, c.ErrDecodePEMPublicKey
 	}
 
+	if err := r.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
 	genericPublicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := r.policy.CheckPublicKey(genericPublicKey); err != nil {
+		return nil, err
+	}
+
 	return genericPublicKey, nil
 }
 
@@ -138,6 +190,72 @@ func (r *RSA) FromPEM(privatePEM p.PrivatePEM, publicPEM p.PublicPEM) (*rsa.Priv
 	return privateKey, publicKey, nil
 }
 
+// FromPEMValidated takes pem keys and converts them into validated rsa keys, failing when the
+// public key is not the public half of the private key
+func (r *RSA) FromPEMValidated(privatePEM p.PrivatePEM, publicPEM p.PublicPEM) (*rsa.PrivateKey, *rsa.PublicKey, error) {
+	privateKey, publicKey, err := r.FromPEM(privatePEM, publicPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := r.ValidatePrivateKey(privateKey); err != nil {
+		return nil, nil, err
+	}
+
+	if !privateKey.PublicKey.Equal(publicKey) {
+		return nil, nil, c.ErrKeyMismatch
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// ValidatePrivateKey checks the private key primes, exponents and public half
+func (r *RSA) ValidatePrivateKey(privateKey *rsa.PrivateKey) error {
+	if privateKey == nil {
+		return c.ErrNilPrivateKey
+	}
+
+	if privateKey.D == nil {
+		return c.ErrNilPrivateKeyD
+	}
+
+	if privateKey.N == nil {
+		return c.ErrNilPrivateKeyN
+	}
+
+	if err := r.ValidatePublicKey(&privateKey.PublicKey); err != nil {
+		return err
+	}
+
+	return privateKey.Validate()
+}
+
+// ValidatePublicKey checks the public key modulus is odd and at least MinSize bits and the public
+// exponent is odd and at least 3
+func (r *RSA) ValidatePublicKey(publicKey *rsa.PublicKey) error {
+	if publicKey == nil {
+		return c.ErrNilPublicKey
+	}
+
+	if publicKey.N == nil {
+		return c.ErrNilPublicKeyN
+	}
+
+	if publicKey.N.Sign() <= 0 || publicKey.N.Bit(0) == 0 {
+		return c.ErrInvalidModulus
+	}
+
+	if publicKey.N.BitLen() < MinSize {
+		return c.ErrKeyTooSmall
+	}
+
+	if publicKey.E < 3 || publicKey.E%2 == 0 {
+		return c.ErrInvalidPublicExponent
+	}
+
+	return nil
+}
+
 // ToPEMPrivateKey converts a RSA private key into a private PEM key
 func (r *RSA) ToPEMPrivateKey(privateKey *rsa.PrivateKey) (p.PrivatePEM, error) {
 	if privateKey == nil {
@@ -152,6 +270,10 @@ func (r *RSA) ToPEMPrivateKey(privateKey *rsa.PrivateKey) (p.PrivatePEM, error)
 		return nil, c.ErrNilPrivateKeyN
 	}
 
+	if err := r.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
 	x509Encoded := x509.MarshalPKCS1PrivateKey(privateKey)
 
 	return pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: x509Encoded}), nil
@@ -167,6 +289,10 @@ func (r *RSA) ToPEMPublicKey(publicKey *rsa.PublicKey) (p.PublicPEM, error) {
 		return nil, c.ErrNilPublicKeyN
 	}
 
+	if err := r.policy.CheckEncoding(policy.EncodingPEM); err != nil {
+		return nil, err
+	}
+
 	x509EncodedPub := x509.MarshalPKCS1PublicKey(publicKey)
 
 	return pem.EncodeToMemory(&pem.Block{Type: c.PUBLICKEY, Bytes: x509EncodedPub}), nil
@@ -197,8 +323,34 @@ func (r *RSA) ToJWKRS256(publicKey *rsa.PublicKey, id string) ([]byte, error) {
 	return r.ToJWK(publicKey, id, jose.RS256)
 }
 
-// ToJWK converts RSA public key into a jwk
+// ToJWK converts RSA public key into a jwk, the algorithm must be a RS* or PS* algorithm whose hash
+// is not stronger than the key
 func (r *RSA) ToJWK(publicKey *rsa.PublicKey, id string, algo jose.SignatureAlgorithm) ([]byte, error) {
+	if publicKey == nil {
+		return nil, c.ErrNilPublicKey
+	}
+
+	if publicKey.N == nil {
+		return nil, c.ErrNilPublicKeyN
+	}
+
+	minSize, ok := algorithmSizes[algo]
+	if !ok {
+		return nil, c.ErrUnsupportedAlgorithm
+	}
+
+	if publicKey.N.BitLen() < MinSize {
+		return nil, c.ErrKeyTooSmall
+	}
+
+	if publicKey.N.BitLen() < minSize {
+		return nil, c.ErrAlgorithmMismatch
+	}
+
+	if err := r.policy.CheckJWK(publicKey, algo); err != nil {
+		return nil, err
+	}
+
 	jwk := jose.JSONWebKey{
 		Use:       c.SIG,
 		Algorithm: string(algo),
@@ -209,8 +361,33 @@ func (r *RSA) ToJWK(publicKey *rsa.PublicKey, id string, algo jose.SignatureAlgo
 	return jwk.MarshalJSON()
 }
 
-// generateKey wrapper for rsa.GenerateKey() for unit-test mocking purposes
+// ToJWKAuto converts RSA public key into a jwk using the strongest RS* algorithm of the key size
+func (r *RSA) ToJWKAuto(publicKey *rsa.PublicKey, id string) ([]byte, error) {
+	if publicKey == nil {
+		return nil, c.ErrNilPublicKey
+	}
+
+	if publicKey.N == nil {
+		return nil, c.ErrNilPublicKeyN
+	}
+
+	switch size := publicKey.N.BitLen(); {
+	case size >= RSA4096:
+		return r.ToJWK(publicKey, id, jose.RS512)
+	case size >= RSA3072:
+		return r.ToJWK(publicKey, id, jose.RS384)
+	default:
+		return r.ToJWK(publicKey, id, jose.RS256)
+	}
+}
+
+// generateKey wrapper for rsa.GenerateKey() for unit-test mocking purposes, rejecting sizes the
+// policy does not allow
 func (r *RSA) generateKey(size int) (*rsa.PrivateKey, error) {
+	if err := r.policy.CheckRSA(size); err != nil {
+		return nil, err
+	}
+
 	return rsa.GenerateKey(rand.Reader, size)
 }
 
@@ -2,14 +2,18 @@ package rsa
 
 import (
 	"bytes"
+	"crypto/rand"
 	crsa "crypto/rsa"
 	"errors"
 	"math/big"
 	"strings"
 	"testing"
 
+	c "github.com/ELares/crypto/pkg"
 	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/policy"
 	"github.com/stretchr/testify/assert"
+	jose "gopkg.in/square/go-jose.v2"
 )
 
 func TestRSAFromPEMPrivateKey(t *testing.T) {
@@ -517,3 +521,182 @@ func TestRSARPEMPrivateKey(t *testing.T) {
 		})
 	}
 }
+
+func TestRSAValidate(t *testing.T) {
+	rsa := NewRSA()
+
+	prvKey, pubKey, _ := rsa.R2048()
+
+	small, _ := crsa.GenerateKey(rand.Reader, 1024)
+
+	evenExponent := *pubKey
+	evenExponent.E = 65536
+
+	evenModulus := *pubKey
+	evenModulus.N = new(big.Int).Add(pubKey.N, big.NewInt(1))
+
+	tampered := *prvKey
+	tampered.D = new(big.Int).Add(prvKey.D, big.NewInt(2))
+
+	testcases := []struct {
+		name string
+
+		validate func() error
+
+		isError     bool
+		expectError error
+	}{
+		{
+			name:     "Valid Private Key",
+			validate: func() error { return rsa.ValidatePrivateKey(prvKey) },
+		},
+		{
+			name:     "Valid Public Key",
+			validate: func() error { return rsa.ValidatePublicKey(pubKey) },
+		},
+		{
+			name:        "Invalid Private Key: Too Small",
+			validate:    func() error { return rsa.ValidatePrivateKey(small) },
+			isError:     true,
+			expectError: c.ErrKeyTooSmall,
+		},
+		{
+			name:     "Invalid Private Key: Tampered Private Exponent",
+			validate: func() error { return rsa.ValidatePrivateKey(&tampered) },
+			isError:  true,
+		},
+		{
+			name:        "Invalid Private Key: Nil",
+			validate:    func() error { return rsa.ValidatePrivateKey(nil) },
+			isError:     true,
+			expectError: c.ErrNilPrivateKey,
+		},
+		{
+			name:        "Invalid Public Key: Even Exponent",
+			validate:    func() error { return rsa.ValidatePublicKey(&evenExponent) },
+			isError:     true,
+			expectError: c.ErrInvalidPublicExponent,
+		},
+		{
+			name:        "Invalid Public Key: Even Modulus",
+			validate:    func() error { return rsa.ValidatePublicKey(&evenModulus) },
+			isError:     true,
+			expectError: c.ErrInvalidModulus,
+		},
+		{
+			name:        "Invalid Public Key: Nil",
+			validate:    func() error { return rsa.ValidatePublicKey(nil) },
+			isError:     true,
+			expectError: c.ErrNilPublicKey,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.validate()
+
+			if !tc.isError {
+				assert.Nil(t, err)
+				return
+			}
+
+			assert.NotNil(t, err)
+			if tc.expectError != nil {
+				assert.Equal(t, tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestRSAFromPEMValidated(t *testing.T) {
+	rsa := NewRSA()
+
+	_, prvPEM, _, pubPEM, _ := rsa.R2048PEM()
+	_, _, _, otherPubPEM, _ := rsa.R2048PEM()
+
+	prvKey, pubKey, err := rsa.FromPEMValidated(prvPEM, pubPEM)
+	assert.Nil(t, err)
+	assert.NotNil(t, prvKey)
+	assert.NotNil(t, pubKey)
+
+	prvKey, pubKey, err = rsa.FromPEMValidated(prvPEM, otherPubPEM)
+	assert.Equal(t, c.ErrKeyMismatch, err)
+	assert.Nil(t, prvKey)
+	assert.Nil(t, pubKey)
+
+	_, _, err = rsa.FromPEMValidated(prvPEM, nil)
+	assert.NotNil(t, err)
+}
+
+func TestRSAWithPolicy(t *testing.T) {
+	cnsa, _ := policy.NewPolicy(policy.CNSA2)
+	rsa := NewRSAWithPolicy(cnsa)
+
+	prvKey, pubKey, err := rsa.R2048()
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+	assert.Nil(t, prvKey)
+	assert.Nil(t, pubKey)
+
+	_, prvPEM, _, pubPEM, _ := NewRSA().R2048PEM()
+
+	_, err = rsa.FromPEMPrivateKey(prvPEM)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, err = rsa.FromPEMPublicKey(pubPEM)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	prvKey, prvPEM, pubKey, pubPEM, err = rsa.R4096PEM()
+	assert.Nil(t, err)
+
+	_, _, err = rsa.FromPEM(prvPEM, pubPEM)
+	assert.Nil(t, err)
+
+	_, err = rsa.ToJWKRS512(pubKey, "id")
+	assert.Nil(t, err)
+
+	_, err = rsa.ToJWKRS256(pubKey, "id")
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+}
+
+func TestRSAToJWKAlgorithm(t *testing.T) {
+	rsa := NewRSA()
+
+	_, r2048, _ := rsa.R2048()
+	_, r4096, _ := rsa.R4096()
+	small, _ := crsa.GenerateKey(rand.Reader, 1024)
+
+	testcases := []struct {
+		name string
+
+		publicKey *crsa.PublicKey
+		algo      jose.SignatureAlgorithm
+
+		expectError error
+	}{
+		{name: "Valid RSA-2048 RS256", publicKey: r2048, algo: jose.RS256},
+		{name: "Valid RSA-2048 PS256", publicKey: r2048, algo: jose.PS256},
+		{name: "Valid RSA-4096 RS512", publicKey: r4096, algo: jose.RS512},
+		{name: "Valid RSA-4096 PS384", publicKey: r4096, algo: jose.PS384},
+		{name: "Invalid RSA-2048 RS512", publicKey: r2048, algo: jose.RS512, expectError: c.ErrAlgorithmMismatch},
+		{name: "Invalid RSA-2048 PS384", publicKey: r2048, algo: jose.PS384, expectError: c.ErrAlgorithmMismatch},
+		{name: "Invalid RSA-2048 ES256", publicKey: r2048, algo: jose.ES256, expectError: c.ErrUnsupportedAlgorithm},
+		{name: "Invalid RSA-1024 RS256", publicKey: &small.PublicKey, algo: jose.RS256, expectError: c.ErrKeyTooSmall},
+		{name: "Invalid nil key", publicKey: nil, algo: jose.RS256, expectError: c.ErrNilPublicKey},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := rsa.ToJWK(tc.publicKey, "id", tc.algo)
+			assert.Equal(t, tc.expectError, err)
+		})
+	}
+
+	for publicKey, algo := range map[*crsa.PublicKey]jose.SignatureAlgorithm{r2048: jose.RS256, r4096: jose.RS512} {
+		jwk, err := rsa.ToJWKAuto(publicKey, "id")
+		assert.Nil(t, err)
+
+		var parsed jose.JSONWebKey
+		assert.Nil(t, parsed.UnmarshalJSON(jwk))
+		assert.Equal(t, string(algo), parsed.Algorithm)
+	}
+}
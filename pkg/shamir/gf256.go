@@ -0,0 +1,67 @@
+package shamir
+
+// GF(2^8) arithmetic with the AES reduction polynomial x^8 + x^4 + x^3 + x + 1, in constant time
+// so that no table lookup is indexed by secret bytes; addition and subtraction are xor
+
+// mul multiplies two field elements
+func mul(a, b byte) byte {
+	var product byte
+
+	for i := 0; i < 8; i++ {
+		product ^= -(b & 1) & a
+
+		carry := -(a >> 7)
+		a = (a << 1) ^ (carry & 0x1b)
+		b >>= 1
+	}
+
+	return product
+}
+
+// inverse gets the multiplicative inverse of a non zero field element, a^254
+func inverse(a byte) byte {
+	result := byte(1)
+
+	for exponent := byte(254); exponent > 0; exponent >>= 1 {
+		if exponent&1 == 1 {
+			result = mul(result, a)
+		}
+
+		a = mul(a, a)
+	}
+
+	return result
+}
+
+// evaluate evaluates the polynomial of the coefficients, constant term first, at x
+func evaluate(coefficients []byte, x byte) byte {
+	var y byte
+
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coefficients[i]
+	}
+
+	return y
+}
+
+// interpolate gets the Lagrange basis coefficients at 0 of the distinct non zero x coordinates
+func interpolate(xs []byte) []byte {
+	basis := make([]byte, len(xs))
+
+	for i, xi := range xs {
+		numerator, denominator := byte(1), byte(1)
+
+		for j, xj := range xs {
+			if i == j {
+				continue
+			}
+
+			numerator = mul(numerator, xj)
+			denominator = mul(denominator, xj^xi)
+		}
+
+		basis[i] = mul(numerator, inverse(denominator))
+	}
+
+	return basis
+}
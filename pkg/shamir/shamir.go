@@ -0,0 +1,431 @@
+package shamir
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"strconv"
+	"strings"
+
+	c "github.com/ELares/crypto/pkg"
+	ec "github.com/ELares/crypto/pkg/ecdsa"
+	ed "github.com/ELares/crypto/pkg/ed25519"
+	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/policy"
+	r "github.com/ELares/crypto/pkg/rsa"
+)
+
+const (
+	// TypeSecret share of an arbitrary secret
+	TypeSecret Type = iota
+
+	// TypeRSA share of a rsa private key
+	TypeRSA
+
+	// TypeECDSA share of an ecdsa private key
+	TypeECDSA
+
+	// TypeEd25519 share of an ed25519 private key
+	TypeEd25519
+)
+
+const (
+	// MaxShares maximum number of shares of a secret, the x coordinates being the non zero elements
+	// of GF(256)
+	MaxShares = 255
+
+	// version version of the share encoding
+	version = 1
+
+	// headerSize size of the version, type, id, threshold, total and index of an encoded share
+	headerSize = 9
+
+	// checksumSize size of the truncated SHA-256 checksum of an encoded share
+	checksumSize = 4
+
+	// digestSize size of the truncated SHA-256 digest of the secret, split along with it to check
+	// the recombined secret
+	digestSize = 8
+
+	// textGroupSize characters per group of the text encoding
+	textGroupSize = 4
+
+	// textLineSize groups per line of the text encoding
+	textLineSize = 8
+)
+
+var (
+	// textEncoding unpadded RFC 4648 base32, without the easily confused 0, 1 and 8
+	textEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	// textReplacer normalizes hand typed text, dropping separators and reading digits the alphabet
+	// lacks as the letters they resemble
+	textReplacer = strings.NewReplacer(" ", "", "-", "", "\t", "", "\r", "", "\n", "", "0", "O", "1", "I", "8", "B")
+
+	// typeNames name of each share type
+	typeNames = map[Type]string{
+		TypeSecret:  "secret",
+		TypeRSA:     "RSA",
+		TypeECDSA:   "ECDSA",
+		TypeEd25519: "Ed25519",
+	}
+)
+
+type (
+	// Type type of the secret of a share
+	Type byte
+
+	// Share share of a secret split with a threshold, ID identifying the split it comes from and
+	// Index being its non zero x coordinate
+	Share struct {
+		Type      Type
+		ID        uint32
+		Threshold int
+		Total     int
+		Index     int
+		Value     []byte
+	}
+
+	// IShamir interface for methods to split secrets and private keys into shares over GF(256),
+	// recombine them, and encode shares as PEM or text
+	IShamir interface {
+		Split(secret []byte, total, threshold int) ([]Share, error)
+		Combine(shares []Share) ([]byte, error)
+
+		SplitPrivateKey(privateKey crypto.PrivateKey, total, threshold int) ([]Share, error)
+		CombinePrivateKey(shares []Share) (crypto.PrivateKey, error)
+
+		ToPEM(share Share) (p.SharePEM, error)
+		FromPEM(sharePEM p.SharePEM) (Share, error)
+
+		ToText(share Share) (string, error)
+		FromText(text string) (Share, error)
+	}
+
+	// Shamir struct to implement the IShamir methods
+	Shamir struct {
+		rsa     r.IRSA
+		ecdsa   ec.IECDSA
+		ed25519 ed.IED25519
+	}
+)
+
+// NewShamir gets a new Shamir pointer
+func NewShamir() IShamir {
+	return NewShamirWithPolicy(nil)
+}
+
+// NewShamirWithPolicy gets a new Shamir pointer enforcing the policy on split and recombined
+// private keys
+func NewShamirWithPolicy(policy *policy.Policy) IShamir {
+	return &Shamir{
+		rsa:     r.NewRSAWithPolicy(policy),
+		ecdsa:   ec.NewECDSAWithPolicy(policy),
+		ed25519: ed.NewED25519WithPolicy(policy),
+	}
+}
+
+// String gets the name of the share type
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// Split splits the secret into total shares, any threshold of them recombining it and fewer
+// revealing nothing about it
+func (s *Shamir) Split(secret []byte, total, threshold int) ([]Share, error) {
+	return split(TypeSecret, secret, total, threshold)
+}
+
+// Combine recombines the secret from at least threshold shares of the same split, checking it
+// against the digest split along with it
+func (s *Shamir) Combine(shares []Share) ([]byte, error) {
+	_, secret, err := combine(shares)
+
+	return secret, err
+}
+
+// SplitPrivateKey splits a *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey, encoded as
+// its ToPEM methods do, into total shares
+func (s *Shamir) SplitPrivateKey(privateKey crypto.PrivateKey, total, threshold int) ([]Share, error) {
+	var (
+		shareType  Type
+		privatePEM p.PrivatePEM
+		err        error
+	)
+
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		shareType = TypeRSA
+		privatePEM, err = s.rsa.ToPEMPrivateKey(privateKey)
+	case *ecdsa.PrivateKey:
+		shareType = TypeECDSA
+		privatePEM, err = s.ecdsa.ToPEMPrivateKey(privateKey)
+	case ed25519.PrivateKey:
+		shareType = TypeEd25519
+		privatePEM, err = s.ed25519.ToPEMPrivateKey(privateKey)
+	case nil:
+		return nil, c.ErrNilPrivateKey
+	default:
+		return nil, c.ErrUnsupportedPrivateKey
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(privatePEM)
+	if block == nil {
+		return nil, c.ErrDecodePEMPrivateKey
+	}
+
+	return split(shareType, block.Bytes, total, threshold)
+}
+
+// CombinePrivateKey recombines a private key from at least threshold shares of the same split,
+// into a *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey
+func (s *Shamir) CombinePrivateKey(shares []Share) (crypto.PrivateKey, error) {
+	shareType, der, err := combine(shares)
+	if err != nil {
+		return nil, err
+	}
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: c.PRIVATEKEY, Bytes: der})
+
+	switch shareType {
+	case TypeRSA:
+		return s.rsa.FromPEMPrivateKey(privatePEM)
+	case TypeECDSA:
+		return s.ecdsa.FromPEMPrivateKey(privatePEM)
+	case TypeEd25519:
+		return s.ed25519.FromPEMPrivateKey(privatePEM)
+	}
+
+	return nil, c.ErrUnsupportedPrivateKey
+}
+
+// ToPEM encodes a share as a SHAMIR SHARE PEM block, its headers describing the share for its
+// custodian
+func (s *Shamir) ToPEM(share Share) (p.SharePEM, error) {
+	data, err := encode(share)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type: c.SHAMIRSHARE,
+		Headers: map[string]string{
+			"Type":      share.Type.String(),
+			"ID":        strconv.FormatUint(uint64(share.ID), 16),
+			"Share":     strconv.Itoa(share.Index) + " of " + strconv.Itoa(share.Total),
+			"Threshold": strconv.Itoa(share.Threshold),
+		},
+		Bytes: data,
+	}), nil
+}
+
+// FromPEM decodes a SHAMIR SHARE PEM block, checking its checksum
+func (s *Shamir) FromPEM(sharePEM p.SharePEM) (Share, error) {
+	block, _ := pem.Decode(sharePEM)
+	if block == nil || block.Type != c.SHAMIRSHARE {
+		return Share{}, c.ErrInvalidShare
+	}
+
+	return decode(block.Bytes)
+}
+
+// ToText encodes a share as lines of base32 groups, to write down or read aloud
+func (s *Shamir) ToText(share Share) (string, error) {
+	data, err := encode(share)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := textEncoding.EncodeToString(data)
+
+	var text strings.Builder
+	for i := 0; i < len(encoded); i += textGroupSize {
+		if i > 0 {
+			if i%(textGroupSize*textLineSize) == 0 {
+				text.WriteByte('\n')
+			} else {
+				text.WriteByte(' ')
+			}
+		}
+
+		text.WriteString(encoded[i:min(i+textGroupSize, len(encoded))])
+	}
+
+	return text.String(), nil
+}
+
+// FromText decodes a text share, ignoring case, spaces, dashes and line breaks, checking its
+// checksum
+func (s *Shamir) FromText(text string) (Share, error) {
+	data, err := textEncoding.DecodeString(textReplacer.Replace(strings.ToUpper(text)))
+	if err != nil {
+		return Share{}, c.ErrInvalidShare
+	}
+
+	return decode(data)
+}
+
+// split splits the secret and its digest into shares with random polynomials of degree threshold-1
+func split(shareType Type, secret []byte, total, threshold int) ([]Share, error) {
+	if len(secret) == 0 {
+		return nil, c.ErrEmptySecret
+	}
+
+	if threshold < 2 || threshold > total || total > MaxShares {
+		return nil, c.ErrInvalidThreshold
+	}
+
+	digest := sha256.Sum256(secret)
+	payload := append(append([]byte{}, secret...), digest[:digestSize]...)
+
+	random := make([]byte, 4+len(payload)*(threshold-1))
+	if _, err := io.ReadFull(rand.Reader, random); err != nil {
+		return nil, err
+	}
+
+	id := binary.BigEndian.Uint32(random)
+	random = random[4:]
+
+	shares := make([]Share, total)
+	for i := range shares {
+		shares[i] = Share{Type: shareType, ID: id, Threshold: threshold, Total: total, Index: i + 1, Value: make([]byte, len(payload))}
+	}
+
+	coefficients := make([]byte, threshold)
+	for b, secretByte := range payload {
+		coefficients[0] = secretByte
+		copy(coefficients[1:], random[b*(threshold-1):(b+1)*(threshold-1)])
+
+		for i := range shares {
+			shares[i].Value[b] = evaluate(coefficients, byte(i+1))
+		}
+	}
+
+	return shares, nil
+}
+
+// combine recombines the payload of the first threshold shares and checks its digest
+func combine(shares []Share) (Type, []byte, error) {
+	if len(shares) == 0 {
+		return 0, nil, c.ErrNotEnoughShares
+	}
+
+	first := shares[0]
+	if first.Threshold < 2 || first.Threshold > MaxShares || len(first.Value) <= digestSize {
+		return 0, nil, c.ErrInvalidShare
+	}
+
+	if len(shares) < first.Threshold {
+		return 0, nil, c.ErrNotEnoughShares
+	}
+
+	shares = shares[:first.Threshold]
+	xs := make([]byte, len(shares))
+	seen := map[int]bool{}
+
+	for i, share := range shares {
+		if share.Index < 1 || share.Index > MaxShares {
+			return 0, nil, c.ErrInvalidShare
+		}
+
+		if share.Type != first.Type || share.ID != first.ID || share.Threshold != first.Threshold || len(share.Value) != len(first.Value) || seen[share.Index] {
+			return 0, nil, c.ErrShareMismatch
+		}
+
+		seen[share.Index] = true
+		xs[i] = byte(share.Index)
+	}
+
+	basis := interpolate(xs)
+	payload := make([]byte, len(first.Value))
+
+	for b := range payload {
+		for i, share := range shares {
+			payload[b] ^= mul(share.Value[b], basis[i])
+		}
+	}
+
+	secret, expected := payload[:len(payload)-digestSize], payload[len(payload)-digestSize:]
+
+	digest := sha256.Sum256(secret)
+	if subtle.ConstantTimeCompare(digest[:digestSize], expected) != 1 {
+		return 0, nil, c.ErrShareMismatch
+	}
+
+	return first.Type, secret, nil
+}
+
+// encode encodes a share as its version, type, id, threshold, total, index and value, followed by
+// a checksum
+func encode(share Share) ([]byte, error) {
+	if _, ok := typeNames[share.Type]; !ok || len(share.Value) == 0 {
+		return nil, c.ErrInvalidShare
+	}
+
+	if share.Threshold < 2 || share.Threshold > share.Total || share.Total > MaxShares || share.Index < 1 || share.Index > share.Total {
+		return nil, c.ErrInvalidShare
+	}
+
+	data := make([]byte, headerSize, headerSize+len(share.Value)+checksumSize)
+	data[0] = version
+	data[1] = byte(share.Type)
+	binary.BigEndian.PutUint32(data[2:6], share.ID)
+	data[6] = byte(share.Threshold)
+	data[7] = byte(share.Total)
+	data[8] = byte(share.Index)
+	data = append(data, share.Value...)
+
+	checksum := sha256.Sum256(data)
+
+	return append(data, checksum[:checksumSize]...), nil
+}
+
+// decode decodes and checks an encoded share
+func decode(data []byte) (Share, error) {
+	if len(data) <= headerSize+checksumSize || data[0] != version {
+		return Share{}, c.ErrInvalidShare
+	}
+
+	body, checksum := data[:len(data)-checksumSize], data[len(data)-checksumSize:]
+
+	expected := sha256.Sum256(body)
+	if !bytes.Equal(expected[:checksumSize], checksum) {
+		return Share{}, c.ErrInvalidShare
+	}
+
+	share := Share{
+		Type:      Type(body[1]),
+		ID:        binary.BigEndian.Uint32(body[2:6]),
+		Threshold: int(body[6]),
+		Total:     int(body[7]),
+		Index:     int(body[8]),
+		Value:     append([]byte{}, body[headerSize:]...),
+	}
+
+	if _, ok := typeNames[share.Type]; !ok {
+		return Share{}, c.ErrInvalidShare
+	}
+
+	if share.Threshold < 2 || share.Threshold > share.Total || share.Index < 1 || share.Index > share.Total {
+		return Share{}, c.ErrInvalidShare
+	}
+
+	return share, nil
+}
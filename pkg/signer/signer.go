@@ -0,0 +1,322 @@
+package signer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+
+	c "github.com/ELares/crypto/pkg"
+	ec "github.com/ELares/crypto/pkg/ecdsa"
+	ed "github.com/ELares/crypto/pkg/ed25519"
+	"github.com/ELares/crypto/pkg/kms"
+	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/policy"
+	r "github.com/ELares/crypto/pkg/rsa"
+)
+
+type (
+	// SignFunc signs a digest, or the message for ed25519 keys, as crypto.Signer.Sign does, e.g. a
+	// call to a remote signing service
+	SignFunc func(digest []byte, opts crypto.SignerOpts) ([]byte, error)
+
+	// DecryptFunc decrypts a ciphertext as crypto.Decrypter.Decrypt does, e.g. a call to a remote
+	// decryption service
+	DecryptFunc func(ciphertext []byte, opts crypto.DecrypterOpts) ([]byte, error)
+
+	// IAdapter interface for methods to expose local keys, key manager keys and remote callbacks as
+	// crypto.Signer and crypto.Decrypter, for tls.Certificate, x509.CreateCertificate or
+	// ssh.NewSignerFromSigner
+	IAdapter interface {
+		FromPrivateKey(privateKey crypto.PrivateKey) (*Signer, error)
+		FromPEM(privatePEM p.PrivatePEM) (*Signer, error)
+		FromKeyManager(ctx context.Context, km kms.KeyManager, id string) (*Signer, error)
+		FromCallback(publicKey crypto.PublicKey, sign SignFunc) (*Signer, error)
+
+		DecrypterFromPrivateKey(privateKey *rsa.PrivateKey) (*Decrypter, error)
+		DecrypterFromKeyManager(ctx context.Context, km kms.KeyManager, id string) (*Decrypter, error)
+		DecrypterFromCallback(publicKey *rsa.PublicKey, decrypt DecryptFunc) (*Decrypter, error)
+	}
+
+	// Adapter struct to implement the IAdapter methods
+	Adapter struct {
+		policy *policy.Policy
+	}
+
+	// Signer crypto.Signer of a rsa, ecdsa or ed25519 key, checking the SignerOpts against the key
+	// type before signing
+	Signer struct {
+		publicKey crypto.PublicKey
+		sign      SignFunc
+		policy    *policy.Policy
+	}
+
+	// Decrypter crypto.Decrypter of a rsa key
+	Decrypter struct {
+		publicKey *rsa.PublicKey
+		decrypt   DecryptFunc
+	}
+)
+
+// NewAdapter gets a new Adapter pointer
+func NewAdapter() IAdapter {
+	return &Adapter{}
+}
+
+// NewAdapterWithPolicy gets a new Adapter pointer enforcing the policy on the adapted public keys
+// and on the hash of every signature
+func NewAdapterWithPolicy(policy *policy.Policy) IAdapter {
+	return &Adapter{policy: policy}
+}
+
+// FromPrivateKey adapts a *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey
+func (a *Adapter) FromPrivateKey(privateKey crypto.PrivateKey) (*Signer, error) {
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if privateKey == nil {
+			return nil, c.ErrNilPrivateKey
+		}
+
+		return a.FromCallback(&privateKey.PublicKey, signWith(privateKey))
+	case *ecdsa.PrivateKey:
+		if privateKey == nil {
+			return nil, c.ErrNilPrivateKey
+		}
+
+		return a.FromCallback(&privateKey.PublicKey, signECDSA(privateKey))
+	case ed25519.PrivateKey:
+		if len(privateKey) != ed25519.PrivateKeySize {
+			return nil, c.ErrInvalidKeyLength
+		}
+
+		return a.FromCallback(privateKey.Public(), signWith(privateKey))
+	case nil:
+		return nil, c.ErrNilPrivateKey
+	}
+
+	return nil, c.ErrUnsupportedPrivateKey
+}
+
+// FromPEM adapts a rsa, ecdsa or ed25519 private PEM key, as written by the ToPEM methods
+func (a *Adapter) FromPEM(privatePEM p.PrivatePEM) (*Signer, error) {
+	parsers := []func(p.PrivatePEM) (crypto.PrivateKey, error){
+		func(privatePEM p.PrivatePEM) (crypto.PrivateKey, error) {
+			return r.NewRSAWithPolicy(a.policy).FromPEMPrivateKey(privatePEM)
+		},
+		func(privatePEM p.PrivatePEM) (crypto.PrivateKey, error) {
+			return ec.NewECDSAWithPolicy(a.policy).FromPEMPrivateKey(privatePEM)
+		},
+		func(privatePEM p.PrivatePEM) (crypto.PrivateKey, error) {
+			return ed.NewED25519WithPolicy(a.policy).FromPEMPrivateKey(privatePEM)
+		},
+	}
+
+	for _, parse := range parsers {
+		privateKey, err := parse(privatePEM)
+		if err == nil {
+			return a.FromPrivateKey(privateKey)
+		}
+
+		if errors.Is(err, c.ErrPolicyViolation) {
+			return nil, err
+		}
+	}
+
+	return nil, c.ErrDecodePEMPrivateKey
+}
+
+// FromKeyManager adapts a signing key of a key manager, signing with its current version, the
+// context being used for every call to the key manager
+func (a *Adapter) FromKeyManager(ctx context.Context, km kms.KeyManager, id string) (*Signer, error) {
+	if km == nil {
+		return nil, c.ErrNilSigner
+	}
+
+	publicKey, err := km.GetPublicKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.FromCallback(publicKey, func(digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+		return km.Sign(ctx, id, digest, opts)
+	})
+}
+
+// FromCallback adapts a remote signing callback for the *rsa.PublicKey, *ecdsa.PublicKey or
+// ed25519.PublicKey
+func (a *Adapter) FromCallback(publicKey crypto.PublicKey, sign SignFunc) (*Signer, error) {
+	if sign == nil {
+		return nil, c.ErrNilSigner
+	}
+
+	publicKey, err := normalize(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.policy.CheckPublicKey(publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Signer{publicKey: publicKey, sign: sign, policy: a.policy}, nil
+}
+
+// DecrypterFromPrivateKey adapts a rsa private key
+func (a *Adapter) DecrypterFromPrivateKey(privateKey *rsa.PrivateKey) (*Decrypter, error) {
+	if privateKey == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	return a.DecrypterFromCallback(&privateKey.PublicKey, func(ciphertext []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+		return privateKey.Decrypt(rand.Reader, ciphertext, opts)
+	})
+}
+
+// DecrypterFromKeyManager adapts a rsa decryption key of a key manager, the context being used for
+// every call to the key manager
+func (a *Adapter) DecrypterFromKeyManager(ctx context.Context, km kms.KeyManager, id string) (*Decrypter, error) {
+	if km == nil {
+		return nil, c.ErrNilSigner
+	}
+
+	publicKey, err := km.GetPublicKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaPublicKey, ok := publicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, c.ErrUnsupportedPublicKey
+	}
+
+	return a.DecrypterFromCallback(rsaPublicKey, func(ciphertext []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+		return km.Decrypt(ctx, id, ciphertext, opts)
+	})
+}
+
+// DecrypterFromCallback adapts a remote decryption callback for the rsa public key
+func (a *Adapter) DecrypterFromCallback(publicKey *rsa.PublicKey, decrypt DecryptFunc) (*Decrypter, error) {
+	if decrypt == nil {
+		return nil, c.ErrNilSigner
+	}
+
+	if publicKey == nil {
+		return nil, c.ErrNilPublicKey
+	}
+
+	if err := a.policy.CheckPublicKey(publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Decrypter{publicKey: publicKey, decrypt: decrypt}, nil
+}
+
+// Public gets the *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey of the signer
+func (s *Signer) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+// Sign signs the digest, or the message for ed25519 keys, the random source being left to the
+// signer: rsa and ecdsa keys need opts hashing to a digest of the same size, rsa keys sign with PSS
+// when opts is a *rsa.PSSOptions, ed25519 keys need crypto.Hash(0), or a nil opts, or an
+// *ed25519.Options
+func (s *Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if _, ok := s.publicKey.(ed25519.PublicKey); ok {
+		if opts == nil {
+			opts = crypto.Hash(0)
+		}
+
+		if _, ok := opts.(*ed25519.Options); !ok && opts.HashFunc() != 0 {
+			return nil, c.ErrUnsupportedAlgorithm
+		}
+
+		if opts.HashFunc() != 0 {
+			if err := s.policy.CheckHash(opts.HashFunc()); err != nil {
+				return nil, err
+			}
+		}
+
+		return s.sign(digest, opts)
+	}
+
+	if opts == nil || opts.HashFunc() == 0 || !opts.HashFunc().Available() {
+		return nil, c.ErrUnsupportedAlgorithm
+	}
+
+	if _, ok := opts.(*rsa.PSSOptions); ok {
+		if _, ok := s.publicKey.(*rsa.PublicKey); !ok {
+			return nil, c.ErrUnsupportedAlgorithm
+		}
+	}
+
+	if len(digest) != opts.HashFunc().Size() {
+		return nil, c.ErrInvalidDigest
+	}
+
+	if err := s.policy.CheckHash(opts.HashFunc()); err != nil {
+		return nil, err
+	}
+
+	return s.sign(digest, opts)
+}
+
+// Public gets the *rsa.PublicKey of the decrypter
+func (d *Decrypter) Public() crypto.PublicKey {
+	return d.publicKey
+}
+
+// Decrypt decrypts the ciphertext, with PKCS#1 v1.5 when opts is nil or a
+// *rsa.PKCS1v15DecryptOptions and with OAEP when opts is a *rsa.OAEPOptions, as *rsa.PrivateKey
+// does, the random source being left to the decrypter
+func (d *Decrypter) Decrypt(_ io.Reader, ciphertext []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	return d.decrypt(ciphertext, opts)
+}
+
+// signWith signs with a local private key
+func signWith(signer crypto.Signer) SignFunc {
+	return func(digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+		return signer.Sign(rand.Reader, digest, opts)
+	}
+}
+
+// signECDSA signs with a local ecdsa private key through the ecdsa package, which signs the
+// secp256k1 and brainpool curves the standard library does not implement
+func signECDSA(privateKey *ecdsa.PrivateKey) SignFunc {
+	return func(digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+		return ec.NewECDSA().Sign(privateKey, digest)
+	}
+}
+
+// normalize checks the public key type, dereferencing a *ed25519.PublicKey
+func normalize(publicKey crypto.PublicKey) (crypto.PublicKey, error) {
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		if publicKey == nil {
+			return nil, c.ErrNilPublicKey
+		}
+	case *ecdsa.PublicKey:
+		if publicKey == nil {
+			return nil, c.ErrNilPublicKey
+		}
+	case ed25519.PublicKey:
+		if len(publicKey) != ed25519.PublicKeySize {
+			return nil, c.ErrInvalidKeyLength
+		}
+	case *ed25519.PublicKey:
+		if publicKey == nil {
+			return nil, c.ErrNilPublicKey
+		}
+
+		return normalize(*publicKey)
+	case nil:
+		return nil, c.ErrNilPublicKey
+	default:
+		return nil, c.ErrUnsupportedPublicKey
+	}
+
+	return publicKey, nil
+}
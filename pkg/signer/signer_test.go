@@ -0,0 +1,237 @@
+package signer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"testing"
+
+	c "github.com/ELares/crypto/pkg"
+	ec "github.com/ELares/crypto/pkg/ecdsa"
+	ed "github.com/ELares/crypto/pkg/ed25519"
+	"github.com/ELares/crypto/pkg/kms"
+	"github.com/ELares/crypto/pkg/policy"
+	r "github.com/ELares/crypto/pkg/rsa"
+	"github.com/ELares/crypto/pkg/signer/signertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	a := NewAdapter()
+	ctx := context.Background()
+	km := kms.NewLocalKeyManager()
+
+	rsaKey, rsaPEM, _, _, _ := r.NewRSA().R2048PEM()
+	ecdsaKey, ecdsaPEM, _, _, _ := ec.NewECDSA().P256PEM()
+	ed25519Key, ed25519PEM, _, _, _ := ed.NewED25519().Ed25519PEM()
+
+	managed := map[kms.KeySpec]string{}
+	for _, spec := range []kms.KeySpec{kms.RSA2048, kms.ECDSAP384, kms.Ed25519} {
+		key, err := km.CreateKey(ctx, spec, kms.UsageSign)
+		require.Nil(t, err)
+
+		managed[spec] = key.ID
+	}
+
+	fromKeyManager := func(spec kms.KeySpec) func() (*Signer, error) {
+		return func() (*Signer, error) { return a.FromKeyManager(ctx, km, managed[spec]) }
+	}
+
+	managedPublicKey := func(spec kms.KeySpec) crypto.PublicKey {
+		publicKey, _ := km.GetPublicKey(ctx, managed[spec])
+		return publicKey
+	}
+
+	testcases := []struct {
+		name string
+
+		adapt func() (*Signer, error)
+
+		expectPublicKey crypto.PublicKey
+	}{
+		{name: "RSA private key", adapt: func() (*Signer, error) { return a.FromPrivateKey(rsaKey) }, expectPublicKey: &rsaKey.PublicKey},
+		{name: "ECDSA private key", adapt: func() (*Signer, error) { return a.FromPrivateKey(ecdsaKey) }, expectPublicKey: &ecdsaKey.PublicKey},
+		{name: "ED25519 private key", adapt: func() (*Signer, error) { return a.FromPrivateKey(ed25519Key) }, expectPublicKey: ed25519Key.Public()},
+		{name: "RSA PEM", adapt: func() (*Signer, error) { return a.FromPEM(rsaPEM) }, expectPublicKey: &rsaKey.PublicKey},
+		{name: "ECDSA PEM", adapt: func() (*Signer, error) { return a.FromPEM(ecdsaPEM) }, expectPublicKey: &ecdsaKey.PublicKey},
+		{name: "ED25519 PEM", adapt: func() (*Signer, error) { return a.FromPEM(ed25519PEM) }, expectPublicKey: ed25519Key.Public()},
+		{name: "ECDSA callback", adapt: func() (*Signer, error) {
+			// e.g. a remote signing service, only the public key is local
+			return a.FromCallback(&ecdsaKey.PublicKey, func(digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+				return ecdsaKey.Sign(rand.Reader, digest, opts)
+			})
+		}, expectPublicKey: &ecdsaKey.PublicKey},
+		{name: "ED25519 callback: pointer public key", adapt: func() (*Signer, error) {
+			publicKey := ed25519Key.Public().(ed25519.PublicKey)
+			return a.FromCallback(&publicKey, func(message []byte, opts crypto.SignerOpts) ([]byte, error) {
+				return ed25519Key.Sign(rand.Reader, message, opts)
+			})
+		}, expectPublicKey: ed25519Key.Public()},
+		{name: "KMS RSA-2048", adapt: fromKeyManager(kms.RSA2048), expectPublicKey: managedPublicKey(kms.RSA2048)},
+		{name: "KMS ECDSA-P384", adapt: fromKeyManager(kms.ECDSAP384), expectPublicKey: managedPublicKey(kms.ECDSAP384)},
+		{name: "KMS Ed25519", adapt: fromKeyManager(kms.Ed25519), expectPublicKey: managedPublicKey(kms.Ed25519)},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			signer, err := tc.adapt()
+			require.Nil(t, err)
+			assert.Equal(t, tc.expectPublicKey, signer.Public())
+
+			signertest.TestSigner(t, signer)
+		})
+	}
+}
+
+func TestDecrypter(t *testing.T) {
+	a := NewAdapter()
+	ctx := context.Background()
+
+	rsaKey, _, _ := r.NewRSA().R2048()
+
+	decrypter, err := a.DecrypterFromPrivateKey(rsaKey)
+	require.Nil(t, err)
+	assert.Equal(t, &rsaKey.PublicKey, decrypter.Public())
+
+	signertest.TestDecrypter(t, decrypter)
+	signertest.TestTLSDecrypter(t, decrypter)
+
+	callback, err := a.DecrypterFromCallback(&rsaKey.PublicKey, func(ciphertext []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+		return rsaKey.Decrypt(rand.Reader, ciphertext, opts)
+	})
+	require.Nil(t, err)
+
+	signertest.TestTLSDecrypter(t, callback)
+
+	// the local key manager only decrypts RSA-OAEP
+	km := kms.NewLocalKeyManager()
+	key, _ := km.CreateKey(ctx, kms.RSA2048, kms.UsageDecrypt)
+
+	managed, err := a.DecrypterFromKeyManager(ctx, km, key.ID)
+	require.Nil(t, err)
+
+	signertest.TestDecrypter(t, managed)
+
+	signing, _ := km.CreateKey(ctx, kms.ECDSAP256, kms.UsageSign)
+	_, err = a.DecrypterFromKeyManager(ctx, km, signing.ID)
+	assert.Equal(t, c.ErrUnsupportedPublicKey, err)
+}
+
+func TestSignerOpts(t *testing.T) {
+	a := NewAdapter()
+
+	rsaKey, _, _ := r.NewRSA().R2048()
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, ed25519Key, _ := ed25519.GenerateKey(rand.Reader)
+
+	rsaSigner, _ := a.FromPrivateKey(rsaKey)
+	ecdsaSigner, _ := a.FromPrivateKey(ecdsaKey)
+	ed25519Signer, _ := a.FromPrivateKey(ed25519Key)
+
+	digest := sha256.Sum256([]byte("message"))
+	prehashed := sha512.Sum512([]byte("message"))
+	pss := &rsa.PSSOptions{Hash: crypto.SHA256, SaltLength: rsa.PSSSaltLengthEqualsHash}
+
+	testcases := []struct {
+		name string
+
+		signer *Signer
+		digest []byte
+		opts   crypto.SignerOpts
+
+		expectError error
+	}{
+		{name: "Valid RSA: PKCS#1 v1.5", signer: rsaSigner, digest: digest[:], opts: crypto.SHA256},
+		{name: "Valid RSA: PSS", signer: rsaSigner, digest: digest[:], opts: pss},
+		{name: "Valid ECDSA", signer: ecdsaSigner, digest: digest[:], opts: crypto.SHA256},
+		{name: "Valid ED25519", signer: ed25519Signer, digest: []byte("message"), opts: crypto.Hash(0)},
+		{name: "Valid ED25519: nil opts", signer: ed25519Signer, digest: []byte("message")},
+		{name: "Valid ED25519ph", signer: ed25519Signer, digest: prehashed[:], opts: &ed25519.Options{Hash: crypto.SHA512}},
+		{name: "Invalid RSA: nil opts", signer: rsaSigner, digest: digest[:], expectError: c.ErrUnsupportedAlgorithm},
+		{name: "Invalid RSA: digest size", signer: rsaSigner, digest: prehashed[:], opts: crypto.SHA256, expectError: c.ErrInvalidDigest},
+		{name: "Invalid ECDSA: no hash", signer: ecdsaSigner, digest: digest[:], opts: crypto.Hash(0), expectError: c.ErrUnsupportedAlgorithm},
+		{name: "Invalid ECDSA: PSS", signer: ecdsaSigner, digest: digest[:], opts: pss, expectError: c.ErrUnsupportedAlgorithm},
+		{name: "Invalid ED25519: hash", signer: ed25519Signer, digest: digest[:], opts: crypto.SHA256, expectError: c.ErrUnsupportedAlgorithm},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			signature, err := tc.signer.Sign(rand.Reader, tc.digest, tc.opts)
+			assert.Equal(t, tc.expectError, err)
+
+			if tc.expectError == nil {
+				assert.NotEmpty(t, signature)
+			}
+		})
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	a := NewAdapter()
+
+	sign := func([]byte, crypto.SignerOpts) ([]byte, error) { return nil, nil }
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var nilRSA *rsa.PrivateKey
+
+	testcases := []struct {
+		name string
+
+		run func() error
+
+		expectError error
+	}{
+		{name: "Invalid private key: nil", run: func() error { _, err := a.FromPrivateKey(nil); return err }, expectError: c.ErrNilPrivateKey},
+		{name: "Invalid private key: nil rsa", run: func() error { _, err := a.FromPrivateKey(nilRSA); return err }, expectError: c.ErrNilPrivateKey},
+		{name: "Invalid private key: type", run: func() error { _, err := a.FromPrivateKey("key"); return err }, expectError: c.ErrUnsupportedPrivateKey},
+		{name: "Invalid PEM", run: func() error { _, err := a.FromPEM([]byte("key")); return err }, expectError: c.ErrDecodePEMPrivateKey},
+		{name: "Invalid callback: nil", run: func() error { _, err := a.FromCallback(&ecdsaKey.PublicKey, nil); return err }, expectError: c.ErrNilSigner},
+		{name: "Invalid callback: public key", run: func() error { _, err := a.FromCallback(ecdsaKey, sign); return err }, expectError: c.ErrUnsupportedPublicKey},
+		{name: "Invalid callback: ed25519 size", run: func() error { _, err := a.FromCallback(ed25519.PublicKey{1}, sign); return err }, expectError: c.ErrInvalidKeyLength},
+		{name: "Invalid key manager: nil", run: func() error { _, err := a.FromKeyManager(context.Background(), nil, "id"); return err }, expectError: c.ErrNilSigner},
+		{name: "Invalid key manager: unknown", run: func() error {
+			_, err := a.FromKeyManager(context.Background(), kms.NewLocalKeyManager(), "id")
+			return err
+		}, expectError: c.ErrKeyNotFound},
+		{name: "Invalid decrypter: nil", run: func() error { _, err := a.DecrypterFromPrivateKey(nil); return err }, expectError: c.ErrNilPrivateKey},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectError, tc.run())
+		})
+	}
+}
+
+func TestWithPolicy(t *testing.T) {
+	cnsa, _ := policy.NewPolicy(policy.CNSA2)
+	a := NewAdapterWithPolicy(cnsa)
+
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, err := a.FromPrivateKey(p256Key)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	_, p256PEM, _, _, _ := ec.NewECDSA().P256PEM()
+	_, err = a.FromPEM(p256PEM)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	signer, err := a.FromPrivateKey(p384Key)
+	require.Nil(t, err)
+
+	// CNSA requires SHA-384 or stronger
+	digest := sha256.Sum256([]byte("message"))
+	_, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.True(t, errors.Is(err, c.ErrPolicyViolation))
+
+	strong := sha512.Sum384([]byte("message"))
+	_, err = signer.Sign(rand.Reader, strong[:], crypto.SHA384)
+	assert.Nil(t, err)
+}
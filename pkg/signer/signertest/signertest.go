@@ -0,0 +1,190 @@
+// Package signertest test harness checking crypto.Signer and crypto.Decrypter implementations
+// against the x509 and tls packages of the standard library
+//
+//	func TestSigner(t *testing.T) {
+//		signertest.TestSigner(t, newRemoteSigner(t))
+//	}
+package signertest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSigner checks the signer signs certificates and CSRs with x509 and authenticates TLS 1.2 and
+// TLS 1.3 servers and clients, its Public being a *rsa.PublicKey, *ecdsa.PublicKey or
+// ed25519.PublicKey
+func TestSigner(t *testing.T, signer crypto.Signer) {
+	switch signer.Public().(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+	default:
+		t.Fatalf("unsupported public key type %T", signer.Public())
+	}
+
+	t.Run("Certificate", func(t *testing.T) {
+		certificate := selfSigned(t, signer)
+		assert.Nil(t, certificate.CheckSignatureFrom(certificate))
+		assert.True(t, certificate.PublicKey.(interface{ Equal(crypto.PublicKey) bool }).Equal(signer.Public()))
+	})
+
+	t.Run("CertificateRequest", func(t *testing.T) {
+		der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "signertest"}}, signer)
+		require.Nil(t, err)
+
+		request, err := x509.ParseCertificateRequest(der)
+		require.Nil(t, err)
+		assert.Nil(t, request.CheckSignature())
+	})
+
+	certificate := selfSigned(t, signer)
+	tlsCertificate := tls.Certificate{Certificate: [][]byte{certificate.Raw}, PrivateKey: signer, Leaf: certificate}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(certificate)
+
+	for _, version := range []uint16{tls.VersionTLS12, tls.VersionTLS13} {
+		t.Run(tls.VersionName(version), func(t *testing.T) {
+			// the signer authenticates both the server and the client
+			server := &tls.Config{
+				Certificates: []tls.Certificate{tlsCertificate},
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				ClientCAs:    roots,
+				MinVersion:   version,
+				MaxVersion:   version,
+			}
+
+			client := &tls.Config{
+				Certificates: []tls.Certificate{tlsCertificate},
+				RootCAs:      roots,
+				ServerName:   "localhost",
+				MinVersion:   version,
+				MaxVersion:   version,
+			}
+
+			handshake(t, server, client)
+		})
+	}
+}
+
+// TestDecrypter checks the decrypter decrypts RSA-OAEP ciphertexts, its Public being a
+// *rsa.PublicKey
+func TestDecrypter(t *testing.T, decrypter crypto.Decrypter) {
+	publicKey, ok := decrypter.Public().(*rsa.PublicKey)
+	require.True(t, ok, "unsupported public key type %T", decrypter.Public())
+
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, []byte("signertest"), []byte("label"))
+	require.Nil(t, err)
+
+	plaintext, err := decrypter.Decrypt(rand.Reader, ciphertext, &rsa.OAEPOptions{Hash: crypto.SHA256, Label: []byte("label")})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("signertest"), plaintext)
+}
+
+// TestTLSDecrypter checks the decrypter decrypts the premaster secret of a TLS 1.2 RSA key
+// exchange, with PKCS#1 v1.5 and a *rsa.PKCS1v15DecryptOptions session key length
+func TestTLSDecrypter(t *testing.T, decrypter crypto.Decrypter) {
+	publicKey, ok := decrypter.Public().(*rsa.PublicKey)
+	require.True(t, ok, "unsupported public key type %T", decrypter.Public())
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	ca := selfSigned(t, caKey)
+
+	template := newTemplate(false)
+	template.KeyUsage = x509.KeyUsageKeyEncipherment
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, publicKey, caKey)
+	require.Nil(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	suites := []uint16{tls.TLS_RSA_WITH_AES_128_GCM_SHA256}
+
+	server := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: decrypter}},
+		CipherSuites: suites,
+		MaxVersion:   tls.VersionTLS12,
+	}
+
+	client := &tls.Config{RootCAs: roots, ServerName: "localhost", CipherSuites: suites, MaxVersion: tls.VersionTLS12}
+
+	state := handshake(t, server, client)
+	assert.Equal(t, tls.TLS_RSA_WITH_AES_128_GCM_SHA256, state.CipherSuite)
+}
+
+// selfSigned creates a self-signed CA certificate for localhost
+func selfSigned(t *testing.T, signer crypto.Signer) *x509.Certificate {
+	template := newTemplate(true)
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	require.Nil(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+
+	return certificate
+}
+
+// newTemplate certificate template for localhost, valid for an hour
+func newTemplate(isCA bool) *x509.Certificate {
+	serialNumber, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	return template
+}
+
+// handshake runs a TLS handshake between the server and client configurations over an in-memory
+// connection, session tickets being disabled as nothing reads them from the synchronous pipe
+func handshake(t *testing.T, server, client *tls.Config) tls.ConnectionState {
+	server = server.Clone()
+	server.SessionTicketsDisabled = true
+
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	errs := make(chan error, 1)
+	go func() {
+		tlsServer := tls.Server(serverConn, server)
+		errs <- tlsServer.Handshake()
+		tlsServer.Close()
+	}()
+
+	tlsClient := tls.Client(clientConn, client)
+	require.Nil(t, tlsClient.Handshake())
+	require.Nil(t, <-errs)
+
+	return tlsClient.ConnectionState()
+}
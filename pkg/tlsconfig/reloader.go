@@ -0,0 +1,146 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+	// Reloader Source of a certificate and private key PEM files, reloaded when they change on disk
+	Reloader struct {
+		certificatePath string
+		privateKeyPath  string
+
+		mu          sync.RWMutex
+		certificate *tls.Certificate
+		stamp       [2]fileStamp
+		err         error
+	}
+
+	// fileStamp modification time and size of a file
+	fileStamp struct {
+		modTime time.Time
+		size    int64
+	}
+)
+
+// NewReloader gets a new Reloader pointer with the files loaded
+func NewReloader(certificatePath, privateKeyPath string) (*Reloader, error) {
+	r := &Reloader{certificatePath: certificatePath, privateKeyPath: privateKeyPath}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Certificate returns the last successfully loaded certificate
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.certificate
+}
+
+// Err returns the error of the last reload, the previous certificate stays in use on error
+func (r *Reloader) Err() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.err
+}
+
+// Reload reads the certificate and private key files
+func (r *Reloader) Reload() error {
+	stamp, err := r.stat()
+	if err != nil {
+		return r.fail(err)
+	}
+
+	certificatePEM, err := os.ReadFile(r.certificatePath)
+	if err != nil {
+		return r.fail(err)
+	}
+
+	privatePEM, err := os.ReadFile(r.privateKeyPath)
+	if err != nil {
+		return r.fail(err)
+	}
+
+	certificate, err := NewTLS().FromPEM(privatePEM, certificatePEM)
+	if err != nil {
+		return r.fail(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.certificate = certificate
+	r.stamp = stamp
+	r.err = nil
+
+	return nil
+}
+
+// Watch polls the files every interval and reloads them on change, until the context is done
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reloadIfChanged()
+		}
+	}
+}
+
+// reloadIfChanged reloads the files when their modification time or size changed
+func (r *Reloader) reloadIfChanged() (bool, error) {
+	stamp, err := r.stat()
+	if err != nil {
+		return false, r.fail(err)
+	}
+
+	r.mu.RLock()
+	changed := stamp != r.stamp
+	r.mu.RUnlock()
+
+	if !changed {
+		return false, nil
+	}
+
+	return true, r.Reload()
+}
+
+// stat gets the stamps of the certificate and private key files
+func (r *Reloader) stat() ([2]fileStamp, error) {
+	var stamp [2]fileStamp
+
+	for i, path := range []string{r.certificatePath, r.privateKeyPath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return stamp, err
+		}
+
+		stamp[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	return stamp, nil
+}
+
+// fail records the reload error
+func (r *Reloader) fail(err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.err = err
+
+	return err
+}
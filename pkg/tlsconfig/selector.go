@@ -0,0 +1,54 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+
+	c "github.com/ELares/crypto/pkg"
+)
+
+// selector picks the certificate of a server name among the sources
+type selector struct {
+	sources []Source
+}
+
+// newSelector gets a new selector pointer
+func newSelector(sources []Source) *selector {
+	return &selector{sources: sources}
+}
+
+// GetCertificate returns the first certificate valid for the SNI server name and supported by the
+// client, or the first available certificate
+func (s *selector) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	var fallback *tls.Certificate
+
+	for _, source := range s.sources {
+		certificate := source.Certificate()
+		if certificate == nil {
+			continue
+		}
+
+		if fallback == nil {
+			fallback = certificate
+		}
+
+		if hello.ServerName == "" || certificate.Leaf == nil {
+			continue
+		}
+
+		if certificate.Leaf.VerifyHostname(hello.ServerName) != nil {
+			continue
+		}
+
+		if hello.SupportsCertificate(certificate) != nil {
+			continue
+		}
+
+		return certificate, nil
+	}
+
+	if fallback == nil {
+		return nil, c.ErrNilCertificate
+	}
+
+	return fallback, nil
+}
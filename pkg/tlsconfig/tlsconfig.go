@@ -0,0 +1,187 @@
+package tlsconfig
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+
+	c "github.com/ELares/crypto/pkg"
+	p "github.com/ELares/crypto/pkg/pem"
+)
+
+const (
+	// Modern preset accepting TLS 1.3 only
+	Modern Preset = iota
+
+	// Intermediate preset accepting TLS 1.2 with forward secret AEAD cipher suites and TLS 1.3
+	Intermediate
+)
+
+type (
+	// Preset set of protocol versions, cipher suites and curves
+	Preset int
+
+	// Source provides the current certificate of a config, allowing it to change over time
+	Source interface {
+		Certificate() *tls.Certificate
+	}
+
+	// ITLS interface for methods to build tls configs from library keys and certificates
+	ITLS interface {
+		Certificate(privateKey crypto.PrivateKey, certificates ...*x509.Certificate) (*tls.Certificate, error)
+		FromPEM(privatePEM p.PrivatePEM, certificatePEM p.CertificatePEM) (*tls.Certificate, error)
+
+		ServerConfig(preset Preset, clientCAs *x509.CertPool, sources ...Source) (*tls.Config, error)
+		ClientConfig(preset Preset, roots *x509.CertPool, source Source) (*tls.Config, error)
+	}
+
+	// TLS struct to implement the ITLS methods
+	TLS struct{}
+
+	// Static Source of a certificate that never changes
+	Static struct {
+		certificate *tls.Certificate
+	}
+)
+
+// NewTLS gets a new TLS pointer
+func NewTLS() ITLS {
+	return &TLS{}
+}
+
+// NewStatic gets a new Static source pointer
+func NewStatic(certificate *tls.Certificate) *Static {
+	return &Static{certificate: certificate}
+}
+
+// Certificate returns the static certificate
+func (s *Static) Certificate() *tls.Certificate {
+	return s.certificate
+}
+
+// Certificate assembles a tls certificate from a rsa, ecdsa or ed25519 private key and its
+// certificate chain, leaf first, checking the key belongs to the leaf
+func (t *TLS) Certificate(privateKey crypto.PrivateKey, certificates ...*x509.Certificate) (*tls.Certificate, error) {
+	if privateKey == nil {
+		return nil, c.ErrNilPrivateKey
+	}
+
+	if len(certificates) == 0 || certificates[0] == nil {
+		return nil, c.ErrNilCertificate
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, c.ErrUnsupportedSigner
+	}
+
+	publicKey, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(certificates[0].PublicKey) {
+		return nil, c.ErrKeyMismatch
+	}
+
+	certificate := &tls.Certificate{
+		PrivateKey: privateKey,
+		Leaf:       certificates[0],
+	}
+
+	for _, cert := range certificates {
+		if cert == nil {
+			return nil, c.ErrNilCertificate
+		}
+
+		certificate.Certificate = append(certificate.Certificate, cert.Raw)
+	}
+
+	return certificate, nil
+}
+
+// FromPEM takes a private pem key, as written by the rsa, ecdsa or ed25519 packages, and a
+// certificate chain pem and converts them into a tls certificate
+func (t *TLS) FromPEM(privatePEM p.PrivatePEM, certificatePEM p.CertificatePEM) (*tls.Certificate, error) {
+	certificate, err := tls.X509KeyPair(certificatePEM, privatePEM)
+	if err != nil {
+		return nil, err
+	}
+
+	if certificate.Leaf == nil {
+		certificate.Leaf, err = x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &certificate, nil
+}
+
+// ServerConfig builds a server tls config of the preset serving the sources by SNI, the first
+// source being the default, and requiring client certificates issued by clientCAs when not nil
+func (t *TLS) ServerConfig(preset Preset, clientCAs *x509.CertPool, sources ...Source) (*tls.Config, error) {
+	if len(sources) == 0 {
+		return nil, c.ErrNilCertificate
+	}
+
+	config, err := t.preset(preset)
+	if err != nil {
+		return nil, err
+	}
+
+	config.GetCertificate = newSelector(sources).GetCertificate
+
+	if clientCAs != nil {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = clientCAs
+	}
+
+	return config, nil
+}
+
+// ClientConfig builds a client tls config of the preset trusting roots (system roots when nil)
+// and presenting the source certificate when not nil
+func (t *TLS) ClientConfig(preset Preset, roots *x509.CertPool, source Source) (*tls.Config, error) {
+	config, err := t.preset(preset)
+	if err != nil {
+		return nil, err
+	}
+
+	config.RootCAs = roots
+
+	if source != nil {
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			certificate := source.Certificate()
+			if certificate == nil {
+				return &tls.Certificate{}, nil
+			}
+
+			return certificate, nil
+		}
+	}
+
+	return config, nil
+}
+
+// preset gets a new tls config of the preset
+func (t *TLS) preset(preset Preset) (*tls.Config, error) {
+	switch preset {
+	case Modern:
+		return &tls.Config{
+			MinVersion:       tls.VersionTLS13,
+			CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+		}, nil
+	case Intermediate:
+		return &tls.Config{
+			MinVersion:       tls.VersionTLS12,
+			CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+			CipherSuites: []uint16{
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			},
+		}, nil
+	}
+
+	return nil, c.ErrUnknownPreset
+}
@@ -0,0 +1,327 @@
+package tlsconfig
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ELares/crypto/pkg/ecdsa"
+	"github.com/ELares/crypto/pkg/ed25519"
+	p "github.com/ELares/crypto/pkg/pem"
+	"github.com/ELares/crypto/pkg/rsa"
+	"github.com/stretchr/testify/assert"
+)
+
+type testPKI struct {
+	ca     *x509.Certificate
+	caKey  crypto.Signer
+	pool   *x509.CertPool
+	serial int64
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	caKey, _, _ := ecdsa.NewECDSA().P256()
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(nil, template, template, caKey.Public(), caKey)
+	assert.Nil(t, err)
+
+	ca, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	return &testPKI{ca: ca, caKey: caKey, pool: pool, serial: 1}
+}
+
+func (pki *testPKI) issue(t *testing.T, publicKey crypto.PublicKey, usage x509.ExtKeyUsage, names ...string) *x509.Certificate {
+	pki.serial++
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(pki.serial),
+		Subject:      pkix.Name{CommonName: "test"},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	der, err := x509.CreateCertificate(nil, template, pki.ca, publicKey, pki.caKey)
+	assert.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	return cert
+}
+
+func certificatePEM(cert *x509.Certificate) p.CertificatePEM {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+func serve(t *testing.T, config *tls.Config) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	server.TLS = config
+	server.StartTLS()
+
+	return server
+}
+
+func get(config *tls.Config, url string) error {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+
+	res, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+
+	return res.Body.Close()
+}
+
+func TestCertificate(t *testing.T) {
+	itls := NewTLS()
+	pki := newTestPKI(t)
+
+	rsaKey, rsaPub, _ := rsa.NewRSA().R2048()
+	ecKey, ecPub, _ := ecdsa.NewECDSA().P384()
+	edKey, edPub, _ := ed25519.NewED25519().Ed25519()
+
+	testcases := []struct {
+		name string
+		key  crypto.PrivateKey
+		cert *x509.Certificate
+
+		isValid bool
+	}{
+		{
+			name: "Valid RSA",
+			key:  rsaKey,
+			cert: pki.issue(t, rsaPub, x509.ExtKeyUsageServerAuth, "localhost"),
+
+			isValid: true,
+		},
+		{
+			name: "Valid ECDSA",
+			key:  ecKey,
+			cert: pki.issue(t, ecPub, x509.ExtKeyUsageServerAuth, "localhost"),
+
+			isValid: true,
+		},
+		{
+			name: "Valid Ed25519",
+			key:  edKey,
+			cert: pki.issue(t, edPub, x509.ExtKeyUsageServerAuth, "localhost"),
+
+			isValid: true,
+		},
+		{
+			name: "Invalid key mismatch",
+			key:  ecKey,
+			cert: pki.issue(t, rsaPub, x509.ExtKeyUsageServerAuth, "localhost"),
+
+			isValid: false,
+		},
+		{
+			name: "Invalid nil key",
+			key:  nil,
+			cert: pki.issue(t, rsaPub, x509.ExtKeyUsageServerAuth, "localhost"),
+
+			isValid: false,
+		},
+		{
+			name: "Invalid nil certificate",
+			key:  ecKey,
+			cert: nil,
+
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			certificate, err := itls.Certificate(tc.key, tc.cert, pki.ca)
+
+			if tc.isValid {
+				assert.Nil(t, err)
+				assert.Len(t, certificate.Certificate, 2)
+				assert.Equal(t, tc.cert, certificate.Leaf)
+			} else {
+				assert.Nil(t, certificate)
+				assert.NotNil(t, err)
+			}
+		})
+	}
+}
+
+func TestFromPEM(t *testing.T) {
+	itls := NewTLS()
+	pki := newTestPKI(t)
+
+	_, rsaPrvPEM, rsaPub, _, _ := rsa.NewRSA().R2048PEM()
+	_, ecPrvPEM, ecPub, _, _ := ecdsa.NewECDSA().P256PEM()
+	_, edPrvPEM, edPub, _, _ := ed25519.NewED25519().Ed25519PEM()
+
+	for name, tc := range map[string]struct {
+		privatePEM p.PrivatePEM
+		publicKey  crypto.PublicKey
+	}{
+		"RSA":     {rsaPrvPEM, rsaPub},
+		"ECDSA":   {ecPrvPEM, ecPub},
+		"Ed25519": {edPrvPEM, edPub},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cert := pki.issue(t, tc.publicKey, x509.ExtKeyUsageServerAuth, "localhost")
+
+			certificate, err := itls.FromPEM(tc.privatePEM, certificatePEM(cert))
+			assert.Nil(t, err)
+			assert.NotNil(t, certificate.Leaf)
+
+			_, err = itls.FromPEM(rsaPrvPEM, certificatePEM(pki.issue(t, edPub, x509.ExtKeyUsageServerAuth)))
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestServerClientConfig(t *testing.T) {
+	itls := NewTLS()
+	pki := newTestPKI(t)
+
+	serverKey, serverPub, _ := ecdsa.NewECDSA().P256()
+	serverCert, err := itls.Certificate(serverKey, pki.issue(t, serverPub, x509.ExtKeyUsageServerAuth, "localhost"))
+	assert.Nil(t, err)
+
+	clientKey, clientPub, _ := ed25519.NewED25519().Ed25519()
+	clientCert, err := itls.Certificate(clientKey, pki.issue(t, clientPub, x509.ExtKeyUsageClientAuth))
+	assert.Nil(t, err)
+
+	for _, preset := range []Preset{Modern, Intermediate} {
+		serverConfig, err := itls.ServerConfig(preset, pki.pool, NewStatic(serverCert))
+		assert.Nil(t, err)
+		assert.Equal(t, tls.RequireAndVerifyClientCert, serverConfig.ClientAuth)
+
+		server := serve(t, serverConfig)
+
+		clientConfig, err := itls.ClientConfig(preset, pki.pool, NewStatic(clientCert))
+		assert.Nil(t, err)
+		clientConfig.ServerName = "localhost"
+		assert.Nil(t, get(clientConfig, server.URL))
+
+		anonymousConfig, err := itls.ClientConfig(preset, pki.pool, nil)
+		assert.Nil(t, err)
+		anonymousConfig.ServerName = "localhost"
+		assert.NotNil(t, get(anonymousConfig, server.URL))
+
+		server.Close()
+	}
+
+	_, err = itls.ServerConfig(Preset(42), nil, NewStatic(serverCert))
+	assert.NotNil(t, err)
+
+	_, err = itls.ServerConfig(Modern, nil)
+	assert.NotNil(t, err)
+}
+
+func TestSNI(t *testing.T) {
+	itls := NewTLS()
+	pki := newTestPKI(t)
+
+	aKey, aPub, _ := ecdsa.NewECDSA().P256()
+	a, _ := itls.Certificate(aKey, pki.issue(t, aPub, x509.ExtKeyUsageServerAuth, "a.example.com"))
+
+	bKey, bPub, _ := rsa.NewRSA().R2048()
+	b, _ := itls.Certificate(bKey, pki.issue(t, bPub, x509.ExtKeyUsageServerAuth, "*.b.example.com"))
+
+	serverConfig, err := itls.ServerConfig(Intermediate, nil, NewStatic(a), NewStatic(b))
+	assert.Nil(t, err)
+
+	server := serve(t, serverConfig)
+	defer server.Close()
+
+	for serverName, expected := range map[string]*tls.Certificate{
+		"a.example.com":     a,
+		"www.b.example.com": b,
+		"unknown.com":       a,
+	} {
+		t.Run(serverName, func(t *testing.T) {
+			clientConfig, _ := itls.ClientConfig(Intermediate, pki.pool, nil)
+			clientConfig.ServerName = serverName
+			clientConfig.InsecureSkipVerify = true
+
+			conn, err := tls.Dial("tcp", server.Listener.Addr().String(), clientConfig)
+			assert.Nil(t, err)
+			defer conn.Close()
+
+			assert.Equal(t, expected.Leaf.Raw, conn.ConnectionState().PeerCertificates[0].Raw)
+		})
+	}
+}
+
+func TestReloader(t *testing.T) {
+	pki := newTestPKI(t)
+	dir := t.TempDir()
+	certificatePath := filepath.Join(dir, "cert.pem")
+	privateKeyPath := filepath.Join(dir, "key.pem")
+
+	write := func(modTime time.Time) *x509.Certificate {
+		_, privatePEM, publicKey, _, _ := ecdsa.NewECDSA().P256PEM()
+		cert := pki.issue(t, publicKey, x509.ExtKeyUsageServerAuth, "localhost")
+
+		assert.Nil(t, os.WriteFile(certificatePath, certificatePEM(cert), 0600))
+		assert.Nil(t, os.WriteFile(privateKeyPath, privatePEM, 0600))
+		assert.Nil(t, os.Chtimes(certificatePath, modTime, modTime))
+		assert.Nil(t, os.Chtimes(privateKeyPath, modTime, modTime))
+
+		return cert
+	}
+
+	first := write(time.Now().Add(-time.Minute))
+
+	reloader, err := NewReloader(certificatePath, privateKeyPath)
+	assert.Nil(t, err)
+	assert.Equal(t, first.Raw, reloader.Certificate().Leaf.Raw)
+
+	changed, err := reloader.reloadIfChanged()
+	assert.Nil(t, err)
+	assert.False(t, changed)
+
+	second := write(time.Now())
+
+	changed, err = reloader.reloadIfChanged()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, second.Raw, reloader.Certificate().Leaf.Raw)
+
+	assert.Nil(t, os.WriteFile(privateKeyPath, []byte("garbage"), 0600))
+
+	changed, err = reloader.reloadIfChanged()
+	assert.True(t, changed)
+	assert.NotNil(t, err)
+	assert.NotNil(t, reloader.Err())
+	assert.Equal(t, second.Raw, reloader.Certificate().Leaf.Raw)
+
+	_, err = NewReloader(filepath.Join(dir, "missing.pem"), privateKeyPath)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,323 @@
+package weakkey
+
+import (
+	"crypto/rsa"
+	"math/big"
+
+	c "github.com/ELares/crypto/pkg"
+	p "github.com/ELares/crypto/pkg/pem"
+	r "github.com/ELares/crypto/pkg/rsa"
+)
+
+const (
+	// FindingTooSmall modulus is below the policy size
+	FindingTooSmall Finding = "too-small"
+
+	// FindingInvalidModulus modulus is not a positive odd number
+	FindingInvalidModulus Finding = "invalid-modulus"
+
+	// FindingSmallExponent public exponent is below 65537
+	FindingSmallExponent Finding = "small-exponent"
+
+	// FindingEvenExponent public exponent is even
+	FindingEvenExponent Finding = "even-exponent"
+
+	// FindingROCA modulus has the fingerprint of the Infineon RSALib keys (CVE-2017-15361)
+	FindingROCA Finding = "roca"
+
+	// FindingFermat modulus primes are close enough to be factored with Fermat's method
+	FindingFermat Finding = "fermat"
+
+	// FindingSharedFactor modulus shares a prime with another modulus of the batch
+	FindingSharedFactor Finding = "shared-factor"
+
+	// FindingDuplicateModulus modulus is used by another key of the batch
+	FindingDuplicateModulus Finding = "duplicate-modulus"
+
+	// MinExponent smallest public exponent accepted, as required by NIST SP 800-56B
+	MinExponent = 65537
+
+	// FermatRounds number of Fermat iterations run on each modulus
+	FermatRounds = 100
+)
+
+var (
+	// rocaGenerator generator of the ROCA primes, p = k * M + (65537^a mod M)
+	rocaGenerator = big.NewInt(65537)
+
+	// rocaPrimes small primes of M for which 65537 does not generate the whole multiplicative group
+	rocaPrimes = []int64{11, 13, 17, 19, 37, 53, 61, 71, 73, 79, 97, 103, 107, 109, 127, 151, 157}
+
+	// rocaResidues residues modulo each of the rocaPrimes reachable as a power of rocaGenerator
+	rocaResidues = newROCAResidues()
+)
+
+type (
+	// Finding a weakness found in a rsa public key
+	Finding string
+
+	// Report the findings of a rsa public key, Index is its position in the checked batch and
+	// Factor a recovered prime of the modulus when one was found
+	Report struct {
+		Index     int
+		Size      int
+		PublicKey *rsa.PublicKey
+		Findings  []Finding
+		Factor    *big.Int
+	}
+
+	// IWeakKey interface for methods to screen rsa public keys for known weaknesses
+	IWeakKey interface {
+		Check(*rsa.PublicKey) (*Report, error)
+		CheckBatch([]*rsa.PublicKey) ([]*Report, error)
+		CheckPEM(...p.PublicPEM) ([]*Report, error)
+	}
+
+	// WeakKey struct to implement the IWeakKey methods
+	WeakKey struct {
+		minSize int
+	}
+)
+
+// NewWeakKey gets a new WeakKey pointer flagging keys below minSize bits
+func NewWeakKey(minSize int) IWeakKey {
+	return &WeakKey{minSize: minSize}
+}
+
+// Check screens a single rsa public key
+func (w *WeakKey) Check(publicKey *rsa.PublicKey) (*Report, error) {
+	reports, err := w.CheckBatch([]*rsa.PublicKey{publicKey})
+	if err != nil {
+		return nil, err
+	}
+
+	return reports[0], nil
+}
+
+// CheckBatch screens rsa public keys, each one on its own and all of them together for shared
+// primes with batch-GCD, returning a report per key in the same order
+func (w *WeakKey) CheckBatch(publicKeys []*rsa.PublicKey) ([]*Report, error) {
+	reports := make([]*Report, len(publicKeys))
+	var moduli []*big.Int
+	var indexes []int
+
+	for i, publicKey := range publicKeys {
+		if publicKey == nil {
+			return nil, c.ErrNilPublicKey
+		}
+
+		if publicKey.N == nil {
+			return nil, c.ErrNilPublicKeyN
+		}
+
+		reports[i] = w.check(publicKey)
+		reports[i].Index = i
+
+		if !reports[i].has(FindingInvalidModulus) {
+			moduli = append(moduli, publicKey.N)
+			indexes = append(indexes, i)
+		}
+	}
+
+	for i, gcd := range batchGCD(moduli) {
+		report := reports[indexes[i]]
+
+		switch {
+		case gcd.Cmp(big.NewInt(1)) == 0:
+		case gcd.Cmp(moduli[i]) != 0:
+			report.add(FindingSharedFactor, gcd)
+		default:
+			// both primes are shared, find out with whom one by one
+			for j, other := range moduli {
+				if j == i {
+					continue
+				}
+
+				if other.Cmp(moduli[i]) == 0 {
+					report.add(FindingDuplicateModulus, nil)
+					break
+				}
+
+				if factor := new(big.Int).GCD(nil, nil, moduli[i], other); factor.Cmp(big.NewInt(1)) != 0 {
+					report.add(FindingSharedFactor, factor)
+					break
+				}
+			}
+		}
+	}
+
+	return reports, nil
+}
+
+// CheckPEM converts the pem public keys with rsa.FromPEMPublicKey and screens them as a batch
+func (w *WeakKey) CheckPEM(publicPEMs ...p.PublicPEM) ([]*Report, error) {
+	irsa := r.NewRSA()
+
+	publicKeys := make([]*rsa.PublicKey, len(publicPEMs))
+	for i, publicPEM := range publicPEMs {
+		publicKey, err := irsa.FromPEMPublicKey(publicPEM)
+		if err != nil {
+			return nil, err
+		}
+
+		publicKeys[i] = publicKey
+	}
+
+	return w.CheckBatch(publicKeys)
+}
+
+// Weak reports whether any weakness was found
+func (rep *Report) Weak() bool {
+	return len(rep.Findings) > 0
+}
+
+// check runs the checks of a single key
+func (w *WeakKey) check(publicKey *rsa.PublicKey) *Report {
+	report := &Report{PublicKey: publicKey, Size: publicKey.N.BitLen()}
+
+	if report.Size < w.minSize {
+		report.add(FindingTooSmall, nil)
+	}
+
+	if publicKey.E < MinExponent {
+		report.add(FindingSmallExponent, nil)
+	}
+
+	if publicKey.E%2 == 0 {
+		report.add(FindingEvenExponent, nil)
+	}
+
+	if publicKey.N.Sign() <= 0 || publicKey.N.Bit(0) == 0 {
+		report.add(FindingInvalidModulus, nil)
+		return report
+	}
+
+	if isROCA(publicKey.N) {
+		report.add(FindingROCA, nil)
+	}
+
+	if factor := fermat(publicKey.N, FermatRounds); factor != nil {
+		report.add(FindingFermat, factor)
+	}
+
+	return report
+}
+
+// add appends the finding, keeping the first recovered factor
+func (rep *Report) add(finding Finding, factor *big.Int) {
+	rep.Findings = append(rep.Findings, finding)
+
+	if rep.Factor == nil && factor != nil {
+		rep.Factor = factor
+	}
+}
+
+// has reports whether the finding was already added
+func (rep *Report) has(finding Finding) bool {
+	for _, f := range rep.Findings {
+		if f == finding {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newROCAResidues computes the subgroup generated by 65537 modulo each of the rocaPrimes
+func newROCAResidues() []map[int64]bool {
+	residues := make([]map[int64]bool, len(rocaPrimes))
+	for i, prime := range rocaPrimes {
+		residues[i] = map[int64]bool{}
+
+		g := rocaGenerator.Int64() % prime
+		for x := int64(1); !residues[i][x]; x = x * g % prime {
+			residues[i][x] = true
+		}
+	}
+
+	return residues
+}
+
+// isROCA reports whether n is a power of 65537 modulo every one of the rocaPrimes, which holds for
+// the keys of the vulnerable Infineon library and almost never for others
+func isROCA(n *big.Int) bool {
+	m := new(big.Int)
+	for i, prime := range rocaPrimes {
+		if !rocaResidues[i][m.Mod(n, big.NewInt(prime)).Int64()] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fermat tries to factor n as a^2 - b^2 starting from ceil(sqrt(n)), returning a prime factor when
+// the primes are close enough to be found within rounds iterations
+func fermat(n *big.Int, rounds int) *big.Int {
+	a := new(big.Int).Sqrt(n)
+	if new(big.Int).Mul(a, a).Cmp(n) < 0 {
+		a.Add(a, big.NewInt(1))
+	}
+
+	b2 := new(big.Int)
+	b := new(big.Int)
+	for i := 0; i < rounds; i++ {
+		b2.Mul(a, a).Sub(b2, n)
+		b.Sqrt(b2)
+
+		if new(big.Int).Mul(b, b).Cmp(b2) == 0 {
+			factor := new(big.Int).Sub(a, b)
+			if factor.Cmp(big.NewInt(1)) > 0 {
+				return factor
+			}
+
+			return nil
+		}
+
+		a.Add(a, big.NewInt(1))
+	}
+
+	return nil
+}
+
+// batchGCD computes gcd(n, product of the other moduli) for every modulus with Bernstein's product
+// and remainder trees
+func batchGCD(moduli []*big.Int) []*big.Int {
+	if len(moduli) == 0 {
+		return nil
+	}
+
+	tree := [][]*big.Int{moduli}
+	for level := moduli; len(level) > 1; {
+		next := make([]*big.Int, (len(level)+1)/2)
+		for i := range next {
+			if 2*i+1 < len(level) {
+				next[i] = new(big.Int).Mul(level[2*i], level[2*i+1])
+			} else {
+				next[i] = level[2*i]
+			}
+		}
+
+		tree = append(tree, next)
+		level = next
+	}
+
+	remainders := tree[len(tree)-1]
+	for level := len(tree) - 2; level >= 0; level-- {
+		next := make([]*big.Int, len(tree[level]))
+		for i, node := range tree[level] {
+			square := new(big.Int).Mul(node, node)
+			next[i] = new(big.Int).Mod(remainders[i/2], square)
+		}
+
+		remainders = next
+	}
+
+	gcds := make([]*big.Int, len(moduli))
+	for i, n := range moduli {
+		quotient := new(big.Int).Quo(remainders[i], n)
+		gcds[i] = new(big.Int).GCD(nil, nil, quotient, n)
+	}
+
+	return gcds
+}
@@ -0,0 +1,214 @@
+package weakkey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+	"testing"
+
+	c "github.com/ELares/crypto/pkg"
+	p "github.com/ELares/crypto/pkg/pem"
+	r "github.com/ELares/crypto/pkg/rsa"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPrime(t *testing.T, bits int) *big.Int {
+	prime, err := rand.Prime(rand.Reader, bits)
+	assert.Nil(t, err)
+
+	return prime
+}
+
+func newTestPublicKey(primes ...*big.Int) *rsa.PublicKey {
+	n := big.NewInt(1)
+	for _, prime := range primes {
+		n.Mul(n, prime)
+	}
+
+	return &rsa.PublicKey{N: n, E: 65537}
+}
+
+// newTestROCAPrime builds a prime with the structure of the Infineon RSALib primes
+func newTestROCAPrime(t *testing.T) *big.Int {
+	m := big.NewInt(1)
+	for prime := int64(2); prime <= 167; prime++ {
+		if big.NewInt(prime).ProbablyPrime(0) {
+			m.Mul(m, big.NewInt(prime))
+		}
+	}
+
+	for {
+		k, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+		assert.Nil(t, err)
+
+		a, err := rand.Int(rand.Reader, m)
+		assert.Nil(t, err)
+
+		candidate := new(big.Int).Mul(k, m)
+		candidate.Add(candidate, new(big.Int).Exp(rocaGenerator, a, m))
+		if candidate.ProbablyPrime(20) {
+			return candidate
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	weakKey := NewWeakKey(r.MinSize)
+
+	_, publicKey, _ := r.NewRSA().R2048()
+
+	small, _ := rsa.GenerateKey(rand.Reader, 1024)
+
+	closeP := newTestPrime(t, 1024)
+	closeQ := new(big.Int).Add(closeP, big.NewInt(2))
+	for !closeQ.ProbablyPrime(20) {
+		closeQ.Add(closeQ, big.NewInt(2))
+	}
+
+	testcases := []struct {
+		name string
+
+		publicKey *rsa.PublicKey
+
+		expectFindings []Finding
+		expectFactor   bool
+	}{
+		{
+			name: "Valid Key",
+
+			publicKey: publicKey,
+		},
+		{
+			name: "Invalid Key: Too Small",
+
+			publicKey: &small.PublicKey,
+
+			expectFindings: []Finding{FindingTooSmall},
+		},
+		{
+			name: "Invalid Key: Small Exponent",
+
+			publicKey: &rsa.PublicKey{N: publicKey.N, E: 3},
+
+			expectFindings: []Finding{FindingSmallExponent},
+		},
+		{
+			name: "Invalid Key: Even Exponent",
+
+			publicKey: &rsa.PublicKey{N: publicKey.N, E: 65538},
+
+			expectFindings: []Finding{FindingEvenExponent},
+		},
+		{
+			name: "Invalid Key: Even Modulus",
+
+			publicKey: &rsa.PublicKey{N: new(big.Int).Lsh(publicKey.N, 1), E: 65537},
+
+			expectFindings: []Finding{FindingInvalidModulus},
+		},
+		{
+			name: "Invalid Key: ROCA",
+
+			publicKey: newTestPublicKey(newTestROCAPrime(t), newTestROCAPrime(t)),
+
+			expectFindings: []Finding{FindingTooSmall, FindingROCA},
+		},
+		{
+			name: "Invalid Key: Fermat",
+
+			publicKey: newTestPublicKey(closeP, closeQ),
+
+			expectFindings: []Finding{FindingFermat},
+			expectFactor:   true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			report, err := weakKey.Check(tc.publicKey)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectFindings, report.Findings)
+			assert.Equal(t, len(tc.expectFindings) > 0, report.Weak())
+
+			if tc.expectFactor {
+				assert.NotNil(t, report.Factor)
+				assert.Equal(t, 0, new(big.Int).Mod(tc.publicKey.N, report.Factor).Sign())
+			} else {
+				assert.Nil(t, report.Factor)
+			}
+		})
+	}
+
+	_, err := weakKey.Check(nil)
+	assert.Equal(t, c.ErrNilPublicKey, err)
+
+	_, err = weakKey.Check(&rsa.PublicKey{E: 65537})
+	assert.Equal(t, c.ErrNilPublicKeyN, err)
+}
+
+func TestCheckBatch(t *testing.T) {
+	weakKey := NewWeakKey(1024)
+
+	primes := make([]*big.Int, 8)
+	for i := range primes {
+		primes[i] = newTestPrime(t, 512)
+	}
+
+	publicKeys := []*rsa.PublicKey{
+		newTestPublicKey(primes[0], primes[1]),
+		newTestPublicKey(primes[0], primes[2]),
+		newTestPublicKey(primes[3], primes[4]),
+		newTestPublicKey(primes[5], primes[6]),
+		newTestPublicKey(primes[5], primes[6]),
+		newTestPublicKey(primes[1], primes[7]),
+	}
+
+	reports, err := weakKey.CheckBatch(publicKeys)
+	assert.Nil(t, err)
+	assert.Len(t, reports, len(publicKeys))
+
+	expected := []struct {
+		findings []Finding
+		factor   *big.Int
+	}{
+		{[]Finding{FindingSharedFactor}, nil},
+		{[]Finding{FindingSharedFactor}, primes[0]},
+		{nil, nil},
+		{[]Finding{FindingDuplicateModulus}, nil},
+		{[]Finding{FindingDuplicateModulus}, nil},
+		{[]Finding{FindingSharedFactor}, primes[1]},
+	}
+
+	for i, report := range reports {
+		assert.Equal(t, i, report.Index)
+		assert.Equal(t, expected[i].findings, report.Findings, i)
+
+		if expected[i].factor != nil {
+			assert.Equal(t, expected[i].factor, report.Factor, i)
+		}
+	}
+
+	// both primes of the first modulus are shared, one of them is recovered pairwise
+	assert.True(t, reports[0].Factor.Cmp(primes[0]) == 0 || reports[0].Factor.Cmp(primes[1]) == 0)
+
+	reports, err = weakKey.CheckBatch(nil)
+	assert.Nil(t, err)
+	assert.Empty(t, reports)
+
+	_, err = weakKey.CheckBatch([]*rsa.PublicKey{publicKeys[0], nil})
+	assert.Equal(t, c.ErrNilPublicKey, err)
+}
+
+func TestCheckPEM(t *testing.T) {
+	weakKey := NewWeakKey(r.MinSize)
+
+	_, _, _, publicPEM, _ := r.NewRSA().R2048PEM()
+
+	reports, err := weakKey.CheckPEM(publicPEM, publicPEM)
+	assert.Nil(t, err)
+	assert.Len(t, reports, 2)
+	assert.Equal(t, []Finding{FindingDuplicateModulus}, reports[0].Findings)
+
+	_, err = weakKey.CheckPEM(publicPEM, p.PublicPEM("not a pem"))
+	assert.NotNil(t, err)
+}